@@ -0,0 +1,395 @@
+package adaptive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+)
+
+var (
+	// Policy algorithm is unknown.
+	ErrAlgorithmValidation = errors.New("unknown adaptive limit algorithm")
+
+	// Policy min limit is less than minimum required.
+	ErrMinLimitValidation = fmt.Errorf("min limit must be >= %d", MinMinLimit)
+
+	// Policy max limit is less than min limit.
+	ErrMaxLimitValidation = errors.New("max limit must be >= min limit")
+
+	// Policy initial limit is out of min and max limits.
+	ErrInitialLimitValidation = errors.New("initial limit must be >= min limit and <= max limit")
+
+	// Policy slow call duration is less than minimum required.
+	ErrSlowCallDurationValidation = fmt.Errorf("slow call duration must be >= %d", MinSlowCallDuration)
+
+	// Policy backoff ratio is out of range.
+	ErrBackoffRatioValidation = errors.New("backoff ratio must be > 0 and < 1")
+
+	// Policy tolerance is less than minimum required.
+	ErrToleranceValidation = fmt.Errorf("tolerance must be >= %g", MinTolerance)
+
+	// Policy smoothing is out of range.
+	ErrSmoothingValidation = errors.New("smoothing must be > 0 and <= 1")
+
+	// Policy long window is less than minimum required.
+	ErrLongWindowValidation = fmt.Errorf("long window must be >= %d", MinLongWindow)
+
+	// No command nor wrapped policy is set.
+	ErrCommandRequired = errors.New("command nor wrapped policy provided")
+
+	// As many calls as the limit are in progress.
+	ErrLimitExceeded = errors.New("concurrency limit exceeded")
+
+	// The limiter of the service was made with other settings (every field but Errors and the functions).
+	ErrSettingsConflict = errors.New("adaptive limit settings conflict with those of the service")
+)
+
+// Policy defines the adaptive concurrency limit algorithm execution policy.
+type Policy struct {
+	// The registered service id. Calls of the same service share the limit.
+	ServiceID string
+
+	// How the limit is adjusted (AIMDAlgorithm if not set).
+	Algorithm Algorithm
+
+	// Limit which a service starts from.
+	InitialLimit int
+
+	// Least limit.
+	MinLimit int
+
+	// Greatest limit.
+	MaxLimit int
+
+	// Calls slower than this count as dropped (zero means none). Ignored by Gradient2.
+	SlowCallDuration time.Duration
+
+	// Errors which count as dropped calls (any error if empty). Ignored by Gradient2.
+	Errors []error
+
+	// Ratio which AIMD cuts the limit by after a dropped call.
+	BackoffRatio float64
+
+	// How much slower than the long term average latency calls may be before Gradient2 cuts the limit
+	// (e.g. 1.5 means 50% slower).
+	Tolerance float64
+
+	// How much of each change Gradient2 takes to the limit, from near zero (smooth) to one (all of it).
+	Smoothing float64
+
+	// Number of calls which the long term average latency approximately spans.
+	LongWindow int
+
+	// Clock used to tell time and to measure latencies (real time if not set).
+	Clock core.Clock
+
+	// Registry which keeps the limiter of the service (the default registry if not set).
+	Registry *Registry
+
+	// Whether the policy returns ErrLimitExceeded as it is, and nil when the command supplier or the
+	// wrapped policy fails, whose error is then only recorded in the metric.
+	SwallowErrors bool
+
+	// Function called before execution.
+	BeforeLimiter func(p Policy)
+
+	// Function called after execution.
+	AfterLimiter func(p Policy, err error)
+
+	// Function called when a call is rejected.
+	OnReject func(p Policy, err error)
+
+	// Function called when the limit changes.
+	OnLimitChange func(p Policy, from, to int)
+
+	// The command supplier.
+	Command core.Command
+
+	// The context-aware command supplier. It takes precedence over Command.
+	CommandContext core.CommandContext
+
+	// Any policy that will be wrapped by this one.
+	Policy core.PolicySupplier
+}
+
+// Metric keeps the running state of the adaptive limiter.
+type Metric struct {
+	// The registered service id.
+	ID string
+
+	// The execution status (success is non zero).
+	Status int
+
+	// When execution started.
+	StartedAt time.Time
+
+	// When execution finished.
+	FinishedAt time.Time
+
+	// The error (if execution wasn't succeeded)
+	Error error
+
+	// Whether the call was rejected.
+	Rejected bool
+
+	// The limit once the call adjusted it (or when it was rejected).
+	Limit int
+
+	// Number of calls in progress when this one was admitted (itself included) or rejected.
+	InFlight int
+
+	// Latency of the call.
+	RTT time.Duration
+
+	// The no-load latency estimate (see Snapshot).
+	MinRTT time.Duration
+
+	// The long term average latency estimate.
+	LongRTT time.Duration
+}
+
+const (
+	// Minimum expected to be set on MinLimit field of an adaptive limit policy.
+	MinMinLimit = 1
+
+	// Minimum expected to be set on SlowCallDuration field of an adaptive limit policy.
+	MinSlowCallDuration = 0
+
+	// Minimum expected to be set on Tolerance field of an adaptive limit policy.
+	MinTolerance = 1.0
+
+	// Minimum expected to be set on LongWindow field of an adaptive limit policy.
+	MinLongWindow = 1
+
+	// InitialLimit set by New.
+	DefaultInitialLimit = 20
+
+	// MaxLimit set by New.
+	DefaultMaxLimit = 200
+
+	// BackoffRatio set by New.
+	DefaultBackoffRatio = 0.9
+
+	// Tolerance set by New.
+	DefaultTolerance = 1.5
+
+	// Smoothing set by New.
+	DefaultSmoothing = 0.2
+
+	// LongWindow set by New.
+	DefaultLongWindow = 600
+)
+
+// New creates an adaptive limit policy with default values set.
+func New(serviceID string) Policy {
+	return Policy{
+		ServiceID:    serviceID,
+		Algorithm:    AIMDAlgorithm,
+		InitialLimit: DefaultInitialLimit,
+		MinLimit:     MinMinLimit,
+		MaxLimit:     DefaultMaxLimit,
+		BackoffRatio: DefaultBackoffRatio,
+		Tolerance:    DefaultTolerance,
+		Smoothing:    DefaultSmoothing,
+		LongWindow:   DefaultLongWindow,
+	}
+}
+
+// Run executes a command supplier or a wrapped policy in an adaptive limiter.
+//
+// Possible error(s): ErrAlgorithmValidation, ErrMinLimitValidation, ErrMaxLimitValidation,
+// ErrInitialLimitValidation, ErrSlowCallDurationValidation, ErrBackoffRatioValidation, ErrToleranceValidation,
+// ErrSmoothingValidation, ErrLongWindowValidation, ErrCommandRequired, ErrSettingsConflict, ErrLimitExceeded
+// (see RunContext).
+func (p Policy) Run(metric core.Metric) error {
+	return p.RunContext(context.Background(), metric)
+}
+
+// RunContext executes a command supplier or a wrapped policy in an adaptive limiter bound to a context.
+// Calls of the same service (and registry) share the limit: a call is rejected when as many calls as the limit are in
+// progress, and the latency and outcome of every admitted call adjust the limit. A call whose ctx is done
+// before it ends tells nothing about the service, so it doesn't adjust the limit.
+//
+// Validation errors and ErrSettingsConflict are returned as they are. Any other error is a
+// *core.PolicyError wrapping ErrLimitExceeded or the error of the command supplier or of the wrapped
+// policy, unless SwallowErrors is set.
+//
+// Possible error(s): ErrAlgorithmValidation, ErrMinLimitValidation, ErrMaxLimitValidation,
+// ErrInitialLimitValidation, ErrSlowCallDurationValidation, ErrBackoffRatioValidation, ErrToleranceValidation,
+// ErrSmoothingValidation, ErrLongWindowValidation, ErrCommandRequired, ErrSettingsConflict, ErrLimitExceeded.
+func (p Policy) RunContext(ctx context.Context, metric core.Metric) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+
+	l, err := registryOf(p).get(p)
+	if err != nil {
+		return err
+	}
+
+	clock := core.ClockOrDefault(p.Clock)
+	m := Metric{ID: p.ServiceID, StartedAt: clock.Now()}
+	if p.BeforeLimiter != nil {
+		p.BeforeLimiter(p)
+	}
+
+	if !l.acquire(&m) {
+		m.Status = 1
+		m.Error = ErrLimitExceeded
+		m.Rejected = true
+		if p.OnReject != nil {
+			p.OnReject(p, m.Error)
+		}
+		if p.AfterLimiter != nil {
+			p.AfterLimiter(p, m.Error)
+		}
+		m.FinishedAt = clock.Now()
+		metric[reflect.TypeOf(m).String()] = m
+
+		return failure(p, m.Error, nil)
+	}
+
+	err = call(ctx, p, clock, l, metric, &m)
+	if err != nil {
+		m.Status = 1
+		m.Error = err
+	}
+
+	if p.AfterLimiter != nil {
+		p.AfterLimiter(p, err)
+	}
+	m.FinishedAt = clock.Now()
+	metric[reflect.TypeOf(m).String()] = m
+
+	return failure(p, nil, err)
+}
+
+// WithCommand encapsulates this policy in a new policy with given command supplier.
+func (p Policy) WithCommand(command core.Command) core.PolicySupplier {
+	p.Command = command
+	return p
+}
+
+// WithCommandContext encapsulates this policy in a new policy with given context-aware command supplier.
+func (p Policy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.CommandContext = command
+	return p
+}
+
+// WithPolicy encapsulates this policy in a new policy with wrapped policy.
+func (p Policy) WithPolicy(policy core.PolicySupplier) core.PolicySupplier {
+	p.Policy = policy
+	return p
+}
+
+// call executes the command supplier or the wrapped policy, ending the call on the limiter even if it
+// panics. A panic counts as a dropped call.
+func call(ctx context.Context, p Policy, clock core.Clock, l *limiter, metric core.Metric, m *Metric) error {
+	started := clock.Now()
+	panicked := true
+	var err error
+
+	defer func() {
+		m.RTT = clock.Now().Sub(started)
+		dropped := panicked || (err != nil && droppedError(p, err))
+
+		from, to := l.release(m.RTT, dropped, ctx.Err() != nil, m)
+		if from != to && p.OnLimitChange != nil {
+			p.OnLimitChange(p, from, to)
+		}
+	}()
+
+	err = pickError(execute(ctx, p, metric), metric)
+	panicked = false
+
+	return err
+}
+
+func execute(ctx context.Context, p Policy, metric core.Metric) error {
+	if p.Policy == nil {
+		if p.CommandContext != nil {
+			return p.CommandContext(ctx)
+		}
+
+		return p.Command()
+	}
+
+	return core.RunPolicy(ctx, p.Policy, metric)
+}
+
+func pickError(err error, metric core.MetricRecorder) error {
+	if err != nil {
+		return err
+	}
+
+	return metric.MetricError()
+}
+
+// failure wraps the rejection error or the error of the execution, unless errors are swallowed.
+func failure(p Policy, err, cause error) error {
+	switch {
+	case err == nil && cause == nil:
+		return nil
+	case p.SwallowErrors:
+		return err
+	default:
+		return &core.PolicyError{Policy: "adaptive", ServiceID: p.ServiceID, Err: err, Cause: cause}
+	}
+}
+
+func droppedError(p Policy, err error) bool {
+	return len(p.Errors) == 0 || core.ErrorInErrors(p.Errors, err)
+}
+
+func validate(p Policy) error {
+	switch {
+	case p.Algorithm < AIMDAlgorithm || p.Algorithm > Gradient2Algorithm:
+		return ErrAlgorithmValidation
+	case p.MinLimit < MinMinLimit:
+		return ErrMinLimitValidation
+	case p.MaxLimit < p.MinLimit:
+		return ErrMaxLimitValidation
+	case p.InitialLimit < p.MinLimit || p.InitialLimit > p.MaxLimit:
+		return ErrInitialLimitValidation
+	case p.SlowCallDuration < MinSlowCallDuration:
+		return ErrSlowCallDurationValidation
+	case p.BackoffRatio <= 0 || p.BackoffRatio >= 1:
+		return ErrBackoffRatioValidation
+	case p.Tolerance < MinTolerance:
+		return ErrToleranceValidation
+	case p.Smoothing <= 0 || p.Smoothing > 1:
+		return ErrSmoothingValidation
+	case p.LongWindow < MinLongWindow:
+		return ErrLongWindowValidation
+	case p.Command == nil && p.CommandContext == nil && p.Policy == nil:
+		return ErrCommandRequired
+	default:
+		return nil
+	}
+}
+
+// ServiceID returns the service id registered to the policy binded to this metric.
+func (m Metric) ServiceID() string {
+	return m.ID
+}
+
+// PolicyDuration returns the policy execution duration.
+// In short, finished at less (-) started at.
+func (m Metric) PolicyDuration() time.Duration {
+	return m.FinishedAt.Sub(m.StartedAt)
+}
+
+// Success returns whether the policy execution succeeded or not.
+// In short, status is zero and error is nil.
+func (m Metric) Success() bool {
+	return (m.Status == 0) && (m.Error == nil)
+}
+
+// MetricError returns the error that a command supplier or a wrapped policy raised.
+func (m Metric) MetricError() error {
+	return m.Error
+}
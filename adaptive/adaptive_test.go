@@ -0,0 +1,292 @@
+package adaptive_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/adaptive"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPolicy struct{ mock.Mock }
+
+func (p *mockPolicy) Run(_ core.Metric) error {
+	args := p.Called()
+	return args.Error(0)
+}
+
+func (p *mockPolicy) WithCommand(_ core.Command) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+func (p *mockPolicy) WithPolicy(_ core.PolicySupplier) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+// hold starts n calls which stay in progress until the returned function is called. Their context is
+// canceled by then, so they don't adjust the limit.
+func hold(t *testing.T, p adaptive.Policy, n int) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	p.CommandContext = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = p.RunContext(ctx, core.NewMetric())
+		}()
+	}
+
+	assert.Eventually(t, func() bool {
+		s, _ := adaptive.Stats(p.ServiceID)
+		return s.InFlight == n
+	}, time.Second, time.Millisecond)
+
+	return func() {
+		cancel()
+		wg.Wait()
+	}
+}
+
+// call runs a command which takes rtt on the fake clock and fails with err.
+func call(p adaptive.Policy, clock *core.FakeClock, rtt time.Duration, err error) adaptive.Metric {
+	p.Clock = clock
+	p.Command = func() error {
+		clock.Advance(rtt)
+		return err
+	}
+
+	metric := core.NewMetric()
+	_ = p.Run(metric)
+	m, _ := metric[reflect.TypeOf(adaptive.Metric{}).String()].(adaptive.Metric)
+
+	return m
+}
+
+func TestPolicyImplementsPolicySupplier(t *testing.T) {
+	p := adaptive.New("remote-service")
+	i := reflect.TypeOf((*core.PolicySupplier)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(p).Implements(i))
+}
+
+func TestMetricImplementsMetricRecorder(t *testing.T) {
+	m := adaptive.Metric{}
+	i := reflect.TypeOf((*core.MetricRecorder)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(m).Implements(i))
+}
+
+func TestNew(t *testing.T) {
+	p := adaptive.New("remote-service")
+
+	assert.Equal(t, "remote-service", p.ServiceID)
+	assert.Equal(t, adaptive.AIMDAlgorithm, p.Algorithm)
+	assert.Equal(t, adaptive.DefaultInitialLimit, p.InitialLimit)
+	assert.Equal(t, adaptive.MinMinLimit, p.MinLimit)
+	assert.Equal(t, adaptive.DefaultMaxLimit, p.MaxLimit)
+	assert.Equal(t, adaptive.DefaultBackoffRatio, p.BackoffRatio)
+	assert.Equal(t, adaptive.DefaultTolerance, p.Tolerance)
+	assert.Equal(t, adaptive.DefaultSmoothing, p.Smoothing)
+	assert.Equal(t, adaptive.DefaultLongWindow, p.LongWindow)
+}
+
+func TestRunValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(p *adaptive.Policy)
+		err    error
+	}{
+		{"algorithm", func(p *adaptive.Policy) { p.Algorithm = 9 }, adaptive.ErrAlgorithmValidation},
+		{"min limit", func(p *adaptive.Policy) { p.MinLimit = 0 }, adaptive.ErrMinLimitValidation},
+		{"max limit", func(p *adaptive.Policy) { p.MinLimit, p.MaxLimit = 2, 1 }, adaptive.ErrMaxLimitValidation},
+		{"initial limit", func(p *adaptive.Policy) { p.InitialLimit = 201 }, adaptive.ErrInitialLimitValidation},
+		{"slow call duration", func(p *adaptive.Policy) { p.SlowCallDuration = -1 }, adaptive.ErrSlowCallDurationValidation},
+		{"backoff ratio", func(p *adaptive.Policy) { p.BackoffRatio = 1 }, adaptive.ErrBackoffRatioValidation},
+		{"tolerance", func(p *adaptive.Policy) { p.Tolerance = 0.9 }, adaptive.ErrToleranceValidation},
+		{"smoothing", func(p *adaptive.Policy) { p.Smoothing = 0 }, adaptive.ErrSmoothingValidation},
+		{"long window", func(p *adaptive.Policy) { p.LongWindow = 0 }, adaptive.ErrLongWindowValidation},
+		{"command", func(p *adaptive.Policy) { p.Command = nil }, adaptive.ErrCommandRequired},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := adaptive.New("remote-service")
+			p.Command = func() error { return nil }
+			c.change(&p)
+
+			assert.ErrorIs(t, p.Run(core.NewMetric()), c.err)
+		})
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	before, after := false, false
+	clock := core.NewFakeClock(time.Now())
+	adaptive.Reset("adaptive-service-1")
+
+	p := adaptive.New("adaptive-service-1")
+	p.BeforeLimiter = func(p adaptive.Policy) { before = true }
+	p.AfterLimiter = func(p adaptive.Policy, err error) { after = true }
+	m := call(p, clock, time.Millisecond*10, nil)
+
+	assert.True(t, before)
+	assert.True(t, after)
+	assert.True(t, m.Success())
+	assert.Equal(t, "adaptive-service-1", m.ServiceID())
+	assert.False(t, m.Rejected)
+	assert.Equal(t, 1, m.InFlight)
+	assert.Equal(t, adaptive.DefaultInitialLimit, m.Limit)
+	assert.Equal(t, time.Millisecond*10, m.RTT)
+	assert.Equal(t, time.Millisecond*10, m.MinRTT)
+	assert.Equal(t, time.Millisecond*10, m.LongRTT)
+
+	s, ok := adaptive.Stats("adaptive-service-1")
+	assert.True(t, ok)
+	assert.Equal(t, adaptive.Snapshot{
+		Limit:   adaptive.DefaultInitialLimit,
+		RTT:     time.Millisecond * 10,
+		MinRTT:  time.Millisecond * 10,
+		LongRTT: time.Millisecond * 10,
+	}, s)
+}
+
+func TestRunCommandError(t *testing.T) {
+	errTest := errors.New("err test")
+	from, to := 0, 0
+	adaptive.Reset("adaptive-service-2")
+
+	p := adaptive.New("adaptive-service-2")
+	p.OnLimitChange = func(p adaptive.Policy, f, t int) { from, to = f, t }
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(adaptive.Metric{}).String()].(adaptive.Metric)
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "adaptive", perr.Policy)
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, errTest, m.MetricError())
+	assert.Equal(t, 20, from)
+	assert.Equal(t, 18, to)
+	assert.Equal(t, 18, m.Limit)
+}
+
+func TestRunRejected(t *testing.T) {
+	var rejected error
+	adaptive.Reset("adaptive-service-3")
+
+	p := adaptive.New("adaptive-service-3")
+	p.InitialLimit, p.MaxLimit = 1, 1
+	p.OnReject = func(p adaptive.Policy, err error) { rejected = err }
+	release := hold(t, p, 1)
+	defer release()
+
+	p.Command = func() error { return nil }
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(adaptive.Metric{}).String()].(adaptive.Metric)
+
+	assert.ErrorIs(t, err, adaptive.ErrLimitExceeded)
+	assert.ErrorIs(t, rejected, adaptive.ErrLimitExceeded)
+	assert.True(t, m.Rejected)
+	assert.Equal(t, 1, m.Status)
+	assert.Equal(t, 1, m.Limit)
+	assert.Equal(t, 1, m.InFlight)
+}
+
+func TestRunContextCanceled(t *testing.T) {
+	adaptive.Reset("adaptive-service-4")
+
+	p := adaptive.New("adaptive-service-4")
+	hold(t, p, 2)()
+
+	// Calls given up by the caller don't adjust the limit.
+	s, _ := adaptive.Stats("adaptive-service-4")
+	assert.Equal(t, adaptive.Snapshot{Limit: adaptive.DefaultInitialLimit}, s)
+}
+
+func TestRunPanicReleasesCall(t *testing.T) {
+	adaptive.Reset("adaptive-service-7")
+
+	p := adaptive.New("adaptive-service-7")
+	p.InitialLimit, p.MaxLimit = 1, 1
+	p.Command = func() error { panic("command panic") }
+
+	assert.Panics(t, func() { _ = p.Run(core.NewMetric()) })
+
+	s, _ := adaptive.Stats("adaptive-service-7")
+	assert.Equal(t, 0, s.InFlight)
+
+	p.Command = func() error { return nil }
+	assert.Nil(t, p.Run(core.NewMetric()))
+}
+
+func TestRunPolicy(t *testing.T) {
+	errTest := errors.New("err test")
+	policy := new(mockPolicy)
+	policy.On("Run").Return(errTest)
+	adaptive.Reset("adaptive-service-5")
+
+	p := adaptive.New("adaptive-service-5")
+	p.Policy = policy
+
+	assert.ErrorIs(t, p.Run(core.NewMetric()), errTest)
+}
+
+func TestRunSwallowErrors(t *testing.T) {
+	errTest := errors.New("err test")
+	adaptive.Reset("adaptive-service-6")
+
+	p := adaptive.New("adaptive-service-6")
+	p.InitialLimit, p.MaxLimit = 1, 1
+	p.SwallowErrors = true
+	p.Command = func() error { return errTest }
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	release := hold(t, p, 1)
+	defer release()
+
+	assert.Equal(t, adaptive.ErrLimitExceeded, p.Run(core.NewMetric()))
+}
+
+func TestWithCommand(t *testing.T) {
+	p := adaptive.New("remote-service")
+	c := func() error { return nil }
+	s := p.WithCommand(c)
+	p, _ = s.(adaptive.Policy)
+
+	assert.NotNil(t, p.Command)
+}
+
+func TestWithCommandContext(t *testing.T) {
+	p := adaptive.New("remote-service")
+	c := func(ctx context.Context) error { return nil }
+	s := p.WithCommandContext(c)
+	p, _ = s.(adaptive.Policy)
+
+	assert.NotNil(t, p.CommandContext)
+}
+
+func TestWithPolicy(t *testing.T) {
+	p := adaptive.New("remote-service")
+	s := p.WithPolicy(adaptive.New("any"))
+	p, _ = s.(adaptive.Policy)
+
+	assert.NotNil(t, p.Policy)
+}
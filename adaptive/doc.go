@@ -0,0 +1,125 @@
+/*
+The adaptive concurrency limit pattern limits the calls of a service in progress at the same time, as a
+bulkhead does, but a fixed size is always wrong for someone: the right size changes with the service,
+its load and the network. An adaptive limiter measures the latency of each call and adjusts its limit,
+growing it while latency holds and cutting it when latency grows (a queue is building up) or calls fail.
+Calls beyond the limit are rejected straight away.
+
+# Usage
+
+There are two ways to run a command under a policy. Using a Command supplier
+(anonymous function) or a wrapped policy.
+
+# Command supplier
+
+	p := adaptive.New("service-id")
+	p.Algorithm = adaptive.Gradient2Algorithm
+	p.Command = func() error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+
+	if errors.Is(err, adaptive.ErrLimitExceeded) {
+		// Rejection handling.
+		...
+	}
+
+	// Prints Adaptive metric.
+	fmt.Println(metric)
+
+# Wrapped policy
+
+	policy := new(AnyPolicy)
+	policy.Command = func() error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	al := adaptive.New("service-id")
+
+	// Instead of a command supplier, it is passed a policy.
+	al.Policy = policy
+
+	metric := core.NewMetric()
+	err := al.Run(metric)
+
+	mr := metric["adaptive.Metric"] // or metric[reflect.TypeOf(adaptive.Metric{}).String()]
+	alMetric, _ := mr.(adaptive.Metric)
+
+	// Prints the limit, the calls in progress and the latency estimates.
+	fmt.Println(alMetric.Limit, alMetric.InFlight, alMetric.RTT, alMetric.MinRTT, alMetric.LongRTT)
+
+# Algorithms
+
+The limit starts from InitialLimit and is kept within MinLimit and MaxLimit. It is adjusted by every
+call which ends, except those whose context is done by then, as they tell nothing about the service.
+Calls made while less than half of the limit is in use don't grow it either.
+
+AIMDAlgorithm grows the limit by one after each call, and cuts it by BackoffRatio after a dropped call:
+one which failed with an error in Errors (any error if empty) or took longer than SlowCallDuration.
+
+VegasAlgorithm estimates how many calls are queued by the service, telling the least latency observed
+(the no-load latency) from the latency of each call. The limit grows fast while there is no queue, grows
+slowly while the queue is short, and shrinks while it is long or calls are dropped. As a service may get
+slower for good, the no-load latency is probed again every now and then.
+
+Gradient2Algorithm compares the latency of each call with the long term average latency (over about
+LongWindow calls). The limit is cut as latency grows beyond Tolerance times the average, and it grows by
+the square root of the limit otherwise, taking Smoothing of each change. It ignores dropped calls.
+
+# Sharing
+
+Limiters are kept by a Registry. Calls of the same service sharing a registry share the limiter, so
+unrelated components (or tests) should use registries of their own. Policies without a Registry use the
+default one (see DefaultRegistry). A limiter keeps the settings of the policy which made it: a policy of the
+same service with other settings fails with ErrSettingsConflict, until Reset drops the limiter. Stats tells
+the current limit, calls in progress and latency estimates of a service.
+
+	registry := adaptive.NewRegistry()
+
+	p := adaptive.New("service-id")
+	p.Registry = registry
+	...
+
+	snapshot, ok := registry.Stats("service-id")
+	registry.Reset("service-id")
+
+# Errors
+
+The policy returns a *core.PolicyError wrapping ErrLimitExceeded or the error of the command supplier
+(or of the wrapped policy). SwallowErrors makes the policy return ErrLimitExceeded as it is, and nil when
+the execution fails (see core.PolicyError).
+
+# Listener
+
+In order to keep tracking of what is happening on the execution, you may use listeners to generate some events.
+adaptive supports listeners to track events before and after policy execution, when a call is rejected
+and when the limit changes.
+
+	p := adaptive.New("service-id")
+	...
+	p.BeforeLimiter = func(p adaptive.Policy) {
+		fmt.Println("Before limiter.")
+	}
+	p.AfterLimiter = func(p adaptive.Policy, err error) {
+		fmt.Println("After limiter.")
+	}
+	p.OnReject = func(p adaptive.Policy, err error) {
+		fmt.Println("Rejected:", err)
+	}
+	p.OnLimitChange = func(p adaptive.Policy, from, to int) {
+		fmt.Println("Limit changed from", from, "to", to)
+	}
+
+	_ = p.Run(core.Metric())
+*/
+package adaptive
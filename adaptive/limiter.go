@@ -0,0 +1,250 @@
+package adaptive
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Algorithm is the way an adaptive limiter adjusts its limit.
+type Algorithm int
+
+const (
+	// Indicates additive increase, multiplicative decrease: the limit grows by one after a call made
+	// while the limit was in use, and it is cut by BackoffRatio after a dropped call.
+	AIMDAlgorithm = Algorithm(0)
+
+	// Indicates TCP Vegas: the limit follows how many calls are estimated to be queued by the service, which
+	// is told by comparing the latency of each call to the least latency observed (the no-load latency).
+	VegasAlgorithm = Algorithm(1)
+
+	// Indicates Gradient2: the limit follows the ratio of the long term average latency to the latency of
+	// each call, allowing a queue of the square root of the limit.
+	Gradient2Algorithm = Algorithm(2)
+)
+
+const (
+	// Every vegasProbeMultiplier times the limit calls, Vegas takes the latency of a call as the no-load
+	// latency again, so that it follows a service which got slower for good.
+	vegasProbeMultiplier = 30
+
+	// Number of calls whose latencies are simply averaged before the long term average becomes exponential.
+	longRTTWarmup = 10
+)
+
+// Snapshot is the state of the adaptive limiter of a service.
+type Snapshot struct {
+	// The concurrency limit.
+	Limit int
+
+	// Number of calls in progress.
+	InFlight int
+
+	// Latency of the latest call.
+	RTT time.Duration
+
+	// The no-load latency: the least latency observed (since the latest probe, for Vegas).
+	MinRTT time.Duration
+
+	// The long term average latency.
+	LongRTT time.Duration
+}
+
+// limiter keeps the calls in progress of a service and adjusts its limit with the sample of each call.
+type limiter struct {
+	mu       sync.Mutex
+	settings settings
+	limit    float64
+	inFlight int
+	rtt      time.Duration
+	minRTT   time.Duration
+	longRTT  float64
+	samples  int
+	probe    int
+}
+
+type settings struct {
+	algorithm        Algorithm
+	initialLimit     int
+	minLimit         int
+	maxLimit         int
+	slowCallDuration time.Duration
+	backoffRatio     float64
+	tolerance        float64
+	smoothing        float64
+	longWindow       int
+}
+
+// sample is what a call tells about the service.
+type sample struct {
+	rtt time.Duration
+
+	// Calls in progress when the call was admitted, itself included.
+	inFlight int
+
+	// Whether the call failed or was too slow.
+	dropped bool
+}
+
+func settingsOf(p Policy) settings {
+	return settings{
+		algorithm:        p.Algorithm,
+		initialLimit:     p.InitialLimit,
+		minLimit:         p.MinLimit,
+		maxLimit:         p.MaxLimit,
+		slowCallDuration: p.SlowCallDuration,
+		backoffRatio:     p.BackoffRatio,
+		tolerance:        p.Tolerance,
+		smoothing:        p.Smoothing,
+		longWindow:       p.LongWindow,
+	}
+}
+
+// acquire admits a call if fewer calls than the limit are in progress, recording the state on the metric.
+func (l *limiter) acquire(m *Metric) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	admitted := l.inFlight < int(l.limit)
+	if admitted {
+		l.inFlight++
+	}
+	m.InFlight = l.inFlight
+	l.record(m)
+
+	return admitted
+}
+
+// release ends a call, adjusting the limit with its sample unless it tells nothing about the service,
+// and records the state on the metric. It returns the limit before and after the call.
+func (l *limiter) release(rtt time.Duration, dropped, ignored bool, m *Metric) (int, int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	from := int(l.limit)
+	s := sample{rtt: rtt, inFlight: m.InFlight, dropped: dropped}
+	l.inFlight--
+
+	if !ignored {
+		if l.settings.slowCallDuration > 0 && rtt > l.settings.slowCallDuration {
+			s.dropped = true
+		}
+		l.observe(s)
+		l.limit = math.Max(float64(l.settings.minLimit), math.Min(float64(l.settings.maxLimit), l.next(s)))
+	}
+
+	l.record(m)
+
+	return from, int(l.limit)
+}
+
+// observe updates the latency estimates with the sample.
+func (l *limiter) observe(s sample) {
+	rtt := float64(s.rtt)
+	l.rtt = s.rtt
+	l.samples++
+
+	if l.minRTT == 0 || s.rtt < l.minRTT {
+		l.minRTT = s.rtt
+	}
+
+	if l.samples <= longRTTWarmup {
+		l.longRTT += (rtt - l.longRTT) / float64(l.samples)
+	} else {
+		factor := 2 / float64(l.settings.longWindow+1)
+		l.longRTT = l.longRTT*(1-factor) + rtt*factor
+	}
+
+	// A long term average far above the latency means the service recovered, so it drifts down faster.
+	if rtt > 0 && l.longRTT/rtt > 2 {
+		l.longRTT *= 0.95
+	}
+}
+
+// next returns the limit the sample leads to, before it is kept within the minimum and maximum limits.
+func (l *limiter) next(s sample) float64 {
+	switch l.settings.algorithm {
+	case VegasAlgorithm:
+		return l.vegas(s)
+	case Gradient2Algorithm:
+		return l.gradient2(s)
+	default:
+		return l.aimd(s)
+	}
+}
+
+func (l *limiter) aimd(s sample) float64 {
+	switch {
+	case s.dropped:
+		return l.limit * l.settings.backoffRatio
+	case s.inFlight*2 >= int(l.limit):
+		return l.limit + 1
+	default:
+		return l.limit
+	}
+}
+
+func (l *limiter) vegas(s sample) float64 {
+	l.probe++
+	if l.probe >= vegasProbeMultiplier*int(l.limit) {
+		l.probe = 0
+		l.minRTT = s.rtt
+
+		return l.limit
+	}
+
+	// Calls made while most of the limit is unused tell nothing about it, unless they failed.
+	if !s.dropped && s.inFlight*2 < int(l.limit) {
+		return l.limit
+	}
+
+	lg := log10(l.limit)
+	if s.dropped || s.rtt == 0 {
+		return l.limit - lg
+	}
+
+	queue := math.Ceil(l.limit * (1 - float64(l.minRTT)/float64(s.rtt)))
+	switch {
+	case queue <= lg:
+		return l.limit + 6*lg
+	case queue < 3*lg:
+		return l.limit + lg
+	case queue > 6*lg:
+		return l.limit - lg
+	default:
+		return l.limit
+	}
+}
+
+func (l *limiter) gradient2(s sample) float64 {
+	// Calls made while most of the limit is unused tell nothing about it.
+	if float64(s.inFlight) < l.limit/2 || s.rtt == 0 {
+		return l.limit
+	}
+
+	gradient := math.Max(0.5, math.Min(1, l.settings.tolerance*l.longRTT/float64(s.rtt)))
+	next := l.limit*gradient + math.Sqrt(l.limit)
+
+	return l.limit*(1-l.settings.smoothing) + next*l.settings.smoothing
+}
+
+func (l *limiter) snapshot() Snapshot {
+	return Snapshot{
+		Limit:    int(l.limit),
+		InFlight: l.inFlight,
+		RTT:      l.rtt,
+		MinRTT:   l.minRTT,
+		LongRTT:  time.Duration(l.longRTT),
+	}
+}
+
+func (l *limiter) record(m *Metric) {
+	m.Limit = int(l.limit)
+	m.MinRTT = l.minRTT
+	m.LongRTT = time.Duration(l.longRTT)
+}
+
+// log10 is the order of magnitude of the limit, being at least one so that small limits may still change.
+func log10(limit float64) float64 {
+	return math.Max(1, math.Floor(math.Log10(limit)))
+}
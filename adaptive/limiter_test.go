@@ -0,0 +1,140 @@
+package adaptive_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/adaptive"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMD(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+	adaptive.Reset("aimd-1")
+
+	p := adaptive.New("aimd-1")
+	p.InitialLimit, p.MaxLimit = 2, 4
+	p.BackoffRatio = 0.5
+	p.SlowCallDuration = time.Millisecond * 100
+
+	// The limit grows while at least half of it is in use.
+	assert.Equal(t, 3, call(p, clock, time.Millisecond, nil).Limit)
+	assert.Equal(t, 3, call(p, clock, time.Millisecond, nil).Limit)
+
+	release := hold(t, p, 1)
+	assert.Equal(t, 4, call(p, clock, time.Millisecond, nil).Limit)
+	assert.Equal(t, 4, call(p, clock, time.Millisecond, nil).Limit)
+
+	// Failed and slow calls cut it.
+	assert.Equal(t, 2, call(p, clock, time.Millisecond, errTest).Limit)
+	assert.Equal(t, 1, call(p, clock, time.Millisecond*101, nil).Limit)
+	release()
+
+	assert.Equal(t, 1, call(p, clock, time.Millisecond, errTest).Limit)
+
+	// Errors which don't count as dropped calls don't cut it.
+	p.Errors = []error{errors.New("err other")}
+	assert.Equal(t, 2, call(p, clock, time.Millisecond, errTest).Limit)
+}
+
+func TestVegas(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+	adaptive.Reset("vegas-1")
+
+	p := adaptive.New("vegas-1")
+	p.Algorithm = adaptive.VegasAlgorithm
+	p.InitialLimit = 10
+
+	// Calls made while most of the limit is unused don't adjust it.
+	assert.Equal(t, 10, call(p, clock, time.Millisecond*10, nil).Limit)
+
+	release := hold(t, p, 9)
+	defer release()
+
+	// No queue: the limit grows by six times its order of magnitude.
+	m := call(p, clock, time.Millisecond*10, nil)
+	assert.Equal(t, 16, m.Limit)
+	assert.Equal(t, time.Millisecond*10, m.MinRTT)
+
+	// A queue of 8 (beyond 6): it shrinks by its order of magnitude.
+	assert.Equal(t, 15, call(p, clock, time.Millisecond*20, nil).Limit)
+
+	// A queue of 3 (between 3 and 6): it holds.
+	assert.Equal(t, 15, call(p, clock, time.Millisecond*12, nil).Limit)
+
+	// A dropped call shrinks it.
+	assert.Equal(t, 14, call(p, clock, time.Millisecond*10, errTest).Limit)
+}
+
+func TestGradient2(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	adaptive.Reset("gradient2-1")
+
+	p := adaptive.New("gradient2-1")
+	p.Algorithm = adaptive.Gradient2Algorithm
+	p.InitialLimit = 10
+
+	// Calls made while most of the limit is unused don't adjust it.
+	assert.Equal(t, 10, call(p, clock, time.Millisecond*100, nil).Limit)
+
+	release := hold(t, p, 9)
+	defer release()
+
+	// Steady latencies let the limit grow.
+	limit := 10
+	for i := 0; i < 9; i++ {
+		m := call(p, clock, time.Millisecond*100, nil)
+		assert.GreaterOrEqual(t, m.Limit, limit)
+		limit = m.Limit
+	}
+	assert.Greater(t, limit, 10)
+
+	// A latency far above the long term average cuts it.
+	m := call(p, clock, time.Second, nil)
+	assert.Less(t, m.Limit, limit)
+	assert.Equal(t, time.Millisecond*100, m.MinRTT)
+	assert.InDelta(t, time.Millisecond*103, m.LongRTT, float64(time.Millisecond))
+}
+
+func TestLimitBounds(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+	adaptive.Reset("bounds-1")
+
+	p := adaptive.New("bounds-1")
+	p.InitialLimit, p.MinLimit, p.MaxLimit = 2, 2, 2
+
+	assert.Equal(t, 2, call(p, clock, time.Millisecond, nil).Limit)
+	assert.Equal(t, 2, call(p, clock, time.Millisecond, errTest).Limit)
+}
+
+func TestSettingsChange(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	r := adaptive.NewRegistry()
+
+	_, ok := r.Stats("settings-1")
+	assert.False(t, ok)
+
+	p := adaptive.New("settings-1")
+	p.Registry = r
+	p.InitialLimit = 2
+	assert.Equal(t, 3, call(p, clock, time.Millisecond, nil).Limit)
+
+	// Policies of a service must agree on its settings.
+	other := p
+	other.Algorithm = adaptive.VegasAlgorithm
+	other.Command = func() error { return nil }
+	metric := core.NewMetric()
+	assert.ErrorIs(t, other.Run(metric), adaptive.ErrSettingsConflict)
+	assert.Empty(t, metric)
+
+	// A reset service starts afresh, with new settings.
+	r.Reset("settings-1")
+	_, ok = r.Stats("settings-1")
+	assert.False(t, ok)
+	assert.Equal(t, 8, call(other, clock, time.Millisecond, nil).Limit)
+}
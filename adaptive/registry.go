@@ -0,0 +1,91 @@
+package adaptive
+
+import "sync"
+
+// Registry keeps the adaptive limiters of services. Policies sharing a registry and a ServiceID share a
+// limiter, while separate registries are isolated from each other. Policies without a Registry use the
+// default one.
+type Registry struct {
+	mu       sync.Mutex
+	limiters map[string]*limiter
+}
+
+var defaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{limiters: make(map[string]*limiter)}
+}
+
+// DefaultRegistry returns the registry used by policies without a Registry.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Stats queries for the state of the adaptive limiter of the service in the default registry
+// (see Registry.Stats).
+func Stats(serviceID string) (Snapshot, bool) {
+	return defaultRegistry.Stats(serviceID)
+}
+
+// Reset drops the adaptive limiter of the service in the default registry (see Registry.Reset).
+func Reset(serviceID string) {
+	defaultRegistry.Reset(serviceID)
+}
+
+// Stats queries for the state of the adaptive limiter of the service.
+//
+// Returns the state and whether the service has a limiter, which it has once it is called.
+func (r *Registry) Stats(serviceID string) (Snapshot, bool) {
+	r.mu.Lock()
+	l := r.limiters[serviceID]
+	r.mu.Unlock()
+
+	if l == nil {
+		return Snapshot{}, false
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.snapshot(), true
+}
+
+// Reset drops the adaptive limiter of the service, so that its next call starts afresh from InitialLimit,
+// with the settings of the policy making it. Calls in progress end on the limiter which admitted them.
+func (r *Registry) Reset(serviceID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.limiters, serviceID)
+}
+
+// get returns the limiter of the service, creating it from the settings of the policy if there is none.
+//
+// Possible error(s): ErrSettingsConflict.
+func (r *Registry) get(p Policy) (*limiter, error) {
+	s := settingsOf(p)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l := r.limiters[p.ServiceID]
+	if l == nil {
+		l = &limiter{settings: s, limit: float64(s.initialLimit)}
+		r.limiters[p.ServiceID] = l
+	}
+
+	if l.settings != s {
+		return nil, ErrSettingsConflict
+	}
+
+	return l, nil
+}
+
+func registryOf(p Policy) *Registry {
+	if p.Registry == nil {
+		return defaultRegistry
+	}
+
+	return p.Registry
+}
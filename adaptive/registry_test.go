@@ -0,0 +1,47 @@
+package adaptive_test
+
+import (
+	"testing"
+
+	"github.com/aureliano/resiliencia/adaptive"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	assert.NotNil(t, adaptive.DefaultRegistry())
+	assert.Same(t, adaptive.DefaultRegistry(), adaptive.DefaultRegistry())
+}
+
+func TestRegistryIsolation(t *testing.T) {
+	first, second := adaptive.NewRegistry(), adaptive.NewRegistry()
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	p := adaptive.New("service")
+	p.InitialLimit, p.MinLimit, p.MaxLimit = 1, 1, 1
+	p.Registry = first
+	p.Command = func() error {
+		close(started)
+		<-release
+		return nil
+	}
+	done := make(chan error)
+	go func() { done <- p.Run(core.NewMetric()) }()
+	<-started
+
+	// The limit of the first registry is reached, the one of the second registry isn't.
+	p.Command = func() error { return nil }
+	assert.ErrorIs(t, p.Run(core.NewMetric()), adaptive.ErrLimitExceeded)
+	p.Registry = second
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	s, ok := first.Stats("service")
+	assert.True(t, ok)
+	assert.Equal(t, 1, s.InFlight)
+	s, _ = second.Stats("service")
+	assert.Equal(t, 0, s.InFlight)
+
+	close(release)
+	assert.Nil(t, <-done)
+}
@@ -0,0 +1,285 @@
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+)
+
+var (
+	// Policy max concurrent is less than minimum required.
+	ErrMaxConcurrentValidation = fmt.Errorf("max concurrent must be >= %d", MinMaxConcurrent)
+
+	// Policy max queue is less than minimum required.
+	ErrMaxQueueValidation = fmt.Errorf("max queue must be >= %d", MinMaxQueue)
+
+	// Policy max queue wait is less than minimum required.
+	ErrMaxQueueWaitValidation = fmt.Errorf("max queue wait must be >= %d", MinMaxQueueWait)
+
+	// No command nor wrapped policy is set.
+	ErrCommandRequired = errors.New("command nor wrapped policy provided")
+
+	// All executions are in progress and the queue is full.
+	ErrBulkheadFull = errors.New("bulkhead is full")
+
+	// The execution waited in the queue for longer than allowed.
+	ErrMaxQueueWaitExceeded = fmt.Errorf("%w: max queue wait exceeded", ErrBulkheadFull)
+
+	// The bulkhead of the service was created with other MaxConcurrent or MaxQueue settings.
+	ErrSettingsConflict = errors.New("bulkhead settings conflict with those of the service")
+)
+
+// Policy defines the bulkhead algorithm execution policy.
+type Policy struct {
+	// The registered service id.
+	ServiceID string
+
+	// Number of executions permitted to run at the same time.
+	MaxConcurrent int
+
+	// Number of executions permitted to wait for their turn (zero means executions in excess are
+	// rejected straight away).
+	MaxQueue int
+
+	// How long an execution waits in the queue until it is rejected (zero means no limit).
+	MaxQueueWait time.Duration
+
+	// Clock used to tell time and to time the queue wait out (real time if not set).
+	Clock core.Clock
+
+	// Registry which keeps the bulkhead of the service (the default registry if not set).
+	Registry *Registry
+
+	// Whether the policy returns rejection errors (e.g. ErrBulkheadFull) as they are, and nil when the
+	// command supplier or the wrapped policy fails, whose error is then only recorded in the metric.
+	SwallowErrors bool
+
+	// Function called before execution.
+	BeforeBulkhead func(p Policy)
+
+	// Function called after execution.
+	AfterBulkhead func(p Policy, err error)
+
+	// Function called when an execution is rejected.
+	OnReject func(p Policy, err error)
+
+	// The command supplier.
+	Command core.Command
+
+	// The context-aware command supplier. It takes precedence over Command.
+	CommandContext core.CommandContext
+
+	// Any policy that will be wrapped by this one.
+	Policy core.PolicySupplier
+}
+
+// Metric keeps the running state of the bulkhead.
+type Metric struct {
+	// The registered service id.
+	ID string
+
+	// The execution status (success is non zero).
+	Status int
+
+	// When execution started.
+	StartedAt time.Time
+
+	// When execution finished.
+	FinishedAt time.Time
+
+	// The error (if execution wasn't succeeded)
+	Error error
+
+	// Whether the execution was rejected.
+	Rejected bool
+
+	// How long the execution waited in the queue.
+	QueueWait time.Duration
+
+	// Number of executions in progress when this one was admitted, itself included.
+	InFlight int
+
+	// Number of executions waiting in the queue when this one arrived.
+	Queued int
+}
+
+const (
+	// Minimum expected to be set on MaxConcurrent field of a bulkhead policy.
+	MinMaxConcurrent = 1
+
+	// Minimum expected to be set on MaxQueue field of a bulkhead policy.
+	MinMaxQueue = 0
+
+	// Minimum expected to be set on MaxQueueWait field of a bulkhead policy.
+	MinMaxQueueWait = 0
+)
+
+// New creates a bulkhead policy with default values set.
+func New(serviceID string) Policy {
+	return Policy{
+		ServiceID:     serviceID,
+		MaxConcurrent: MinMaxConcurrent,
+		MaxQueue:      MinMaxQueue,
+	}
+}
+
+// Run executes a command supplier or a wrapped policy in a bulkhead.
+//
+// Possible error(s): ErrMaxConcurrentValidation, ErrMaxQueueValidation, ErrMaxQueueWaitValidation,
+// ErrCommandRequired, ErrSettingsConflict, ErrBulkheadFull, ErrMaxQueueWaitExceeded (see RunContext).
+func (p Policy) Run(metric core.Metric) error {
+	return p.RunContext(context.Background(), metric)
+}
+
+// RunContext executes a command supplier or a wrapped policy in a bulkhead bound to a context.
+// Executions of the same service (and registry) share the bulkhead: at most MaxConcurrent of them run at
+// the same time, at most MaxQueue wait for their turn in arrival order and any other is rejected. An
+// execution leaves the queue as soon as ctx is done.
+//
+// Validation errors and ErrSettingsConflict are returned as they are. Any other error is a *core.PolicyError wrapping
+// ErrBulkheadFull, ErrMaxQueueWaitExceeded, the error of ctx or the error of the command supplier or
+// of the wrapped policy, unless SwallowErrors is set.
+//
+// Possible error(s): ErrMaxConcurrentValidation, ErrMaxQueueValidation, ErrMaxQueueWaitValidation,
+// ErrCommandRequired, ErrSettingsConflict, ErrBulkheadFull, ErrMaxQueueWaitExceeded, context.Canceled,
+// context.DeadlineExceeded.
+func (p Policy) RunContext(ctx context.Context, metric core.Metric) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+
+	b, err := registryOf(p).get(p)
+	if err != nil {
+		return err
+	}
+
+	clock := core.ClockOrDefault(p.Clock)
+	m := Metric{ID: p.ServiceID, StartedAt: clock.Now()}
+	if p.BeforeBulkhead != nil {
+		p.BeforeBulkhead(p)
+	}
+
+	if err = b.acquire(ctx, p, clock, &m); err != nil {
+		m.Status = 1
+		m.Error = err
+		m.Rejected = true
+		if p.OnReject != nil {
+			p.OnReject(p, err)
+		}
+		if p.AfterBulkhead != nil {
+			p.AfterBulkhead(p, err)
+		}
+		m.FinishedAt = clock.Now()
+		metric[reflect.TypeOf(m).String()] = m
+
+		return failure(p, err, nil)
+	}
+	defer b.release()
+
+	err = execute(ctx, p, metric)
+	err = pickError(err, metric)
+
+	if err != nil {
+		m.Status = 1
+		m.Error = err
+	}
+
+	if p.AfterBulkhead != nil {
+		p.AfterBulkhead(p, err)
+	}
+	m.FinishedAt = clock.Now()
+	metric[reflect.TypeOf(m).String()] = m
+
+	return failure(p, nil, err)
+}
+
+// WithCommand encapsulates this policy in a new policy with given command supplier.
+func (p Policy) WithCommand(command core.Command) core.PolicySupplier {
+	p.Command = command
+	return p
+}
+
+// WithCommandContext encapsulates this policy in a new policy with given context-aware command supplier.
+func (p Policy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.CommandContext = command
+	return p
+}
+
+// WithPolicy encapsulates this policy in a new policy with wrapped policy.
+func (p Policy) WithPolicy(policy core.PolicySupplier) core.PolicySupplier {
+	p.Policy = policy
+	return p
+}
+
+func execute(ctx context.Context, p Policy, metric core.Metric) error {
+	if p.Policy == nil {
+		if p.CommandContext != nil {
+			return p.CommandContext(ctx)
+		}
+
+		return p.Command()
+	}
+
+	return core.RunPolicy(ctx, p.Policy, metric)
+}
+
+func pickError(err error, metric core.MetricRecorder) error {
+	if err != nil {
+		return err
+	}
+
+	return metric.MetricError()
+}
+
+// failure wraps the rejection error or the error of the execution, unless errors are swallowed.
+func failure(p Policy, err, cause error) error {
+	switch {
+	case err == nil && cause == nil:
+		return nil
+	case p.SwallowErrors:
+		return err
+	default:
+		return &core.PolicyError{Policy: "bulkhead", ServiceID: p.ServiceID, Err: err, Cause: cause}
+	}
+}
+
+func validate(p Policy) error {
+	switch {
+	case p.MaxConcurrent < MinMaxConcurrent:
+		return ErrMaxConcurrentValidation
+	case p.MaxQueue < MinMaxQueue:
+		return ErrMaxQueueValidation
+	case p.MaxQueueWait < MinMaxQueueWait:
+		return ErrMaxQueueWaitValidation
+	case p.Command == nil && p.CommandContext == nil && p.Policy == nil:
+		return ErrCommandRequired
+	default:
+		return nil
+	}
+}
+
+// ServiceID returns the service id registered to the policy binded to this metric.
+func (m Metric) ServiceID() string {
+	return m.ID
+}
+
+// PolicyDuration returns the policy execution duration.
+// In short, finished at less (-) started at.
+func (m Metric) PolicyDuration() time.Duration {
+	return m.FinishedAt.Sub(m.StartedAt)
+}
+
+// Success returns whether the policy execution succeeded or not.
+// In short, status is zero and error is nil.
+func (m Metric) Success() bool {
+	return (m.Status == 0) && (m.Error == nil)
+}
+
+// MetricError returns the error that a command supplier or a wrapped policy raised.
+func (m Metric) MetricError() error {
+	return m.Error
+}
@@ -0,0 +1,379 @@
+package bulkhead_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/bulkhead"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPolicy struct{ mock.Mock }
+
+func (p *mockPolicy) Run(_ core.Metric) error {
+	args := p.Called()
+	return args.Error(0)
+}
+
+func (p *mockPolicy) WithCommand(_ core.Command) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+func (p *mockPolicy) WithPolicy(_ core.PolicySupplier) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+func TestPolicyImplementsPolicySupplier(t *testing.T) {
+	p := bulkhead.New("remote-service")
+	i := reflect.TypeOf((*core.PolicySupplier)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(p).Implements(i))
+}
+
+func TestMetricImplementsMetricRecorder(t *testing.T) {
+	m := bulkhead.Metric{}
+	i := reflect.TypeOf((*core.MetricRecorder)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(m).Implements(i))
+}
+
+func TestNew(t *testing.T) {
+	p := bulkhead.New("remote-service")
+
+	assert.Equal(t, "remote-service", p.ServiceID)
+	assert.Equal(t, bulkhead.MinMaxConcurrent, p.MaxConcurrent)
+	assert.Equal(t, bulkhead.MinMaxQueue, p.MaxQueue)
+	assert.Equal(t, time.Duration(0), p.MaxQueueWait)
+}
+
+func TestRunValidationMaxConcurrent(t *testing.T) {
+	p := bulkhead.New("remote-service")
+	p.MaxConcurrent = 0
+	p.Command = func() error { return nil }
+
+	assert.ErrorIs(t, p.Run(core.NewMetric()), bulkhead.ErrMaxConcurrentValidation)
+}
+
+func TestRunValidationMaxQueue(t *testing.T) {
+	p := bulkhead.New("remote-service")
+	p.MaxQueue = -1
+	p.Command = func() error { return nil }
+
+	assert.ErrorIs(t, p.Run(core.NewMetric()), bulkhead.ErrMaxQueueValidation)
+}
+
+func TestRunValidationMaxQueueWait(t *testing.T) {
+	p := bulkhead.New("remote-service")
+	p.MaxQueueWait = -1
+	p.Command = func() error { return nil }
+
+	assert.ErrorIs(t, p.Run(core.NewMetric()), bulkhead.ErrMaxQueueWaitValidation)
+}
+
+func TestRunValidationCommandRequired(t *testing.T) {
+	p := bulkhead.New("remote-service")
+
+	assert.ErrorIs(t, p.Run(core.NewMetric()), bulkhead.ErrCommandRequired)
+}
+
+func TestRunCommand(t *testing.T) {
+	before, after := false, false
+	p := bulkhead.New("bulkhead-service-1")
+	p.MaxConcurrent = 2
+	p.BeforeBulkhead = func(p bulkhead.Policy) { before = true }
+	p.AfterBulkhead = func(p bulkhead.Policy, err error) { after = true }
+	p.Command = func() error {
+		inFlight, queued := bulkhead.Stats("bulkhead-service-1")
+		assert.Equal(t, 1, inFlight)
+		assert.Equal(t, 0, queued)
+
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(bulkhead.Metric{}).String()].(bulkhead.Metric)
+
+	assert.Nil(t, err)
+	assert.True(t, before)
+	assert.True(t, after)
+	assert.True(t, m.Success())
+	assert.Equal(t, "bulkhead-service-1", m.ServiceID())
+	assert.Equal(t, 1, m.InFlight)
+	assert.Equal(t, 0, m.Queued)
+	assert.False(t, m.Rejected)
+
+	inFlight, _ := bulkhead.Stats("bulkhead-service-1")
+	assert.Equal(t, 0, inFlight)
+}
+
+func TestRunCommandError(t *testing.T) {
+	errTest := errors.New("err test")
+	p := bulkhead.New("bulkhead-service-2")
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(bulkhead.Metric{}).String()].(bulkhead.Metric)
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "bulkhead", perr.Policy)
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, errTest, m.MetricError())
+	assert.Equal(t, 1, m.Status)
+	assert.False(t, m.Rejected)
+}
+
+func TestRunRejected(t *testing.T) {
+	release := make(chan struct{})
+	p := bulkhead.New("bulkhead-service-3")
+	p.MaxConcurrent = 1
+	running := holdSlots(t, p, 1, release)
+	rejected := false
+
+	p.OnReject = func(p bulkhead.Policy, err error) { rejected = true }
+	p.Command = func() error { return nil }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(bulkhead.Metric{}).String()].(bulkhead.Metric)
+
+	assert.ErrorIs(t, err, bulkhead.ErrBulkheadFull)
+	assert.True(t, rejected)
+	assert.True(t, m.Rejected)
+	assert.ErrorIs(t, m.MetricError(), bulkhead.ErrBulkheadFull)
+
+	close(release)
+	running.Wait()
+}
+
+func TestRunQueued(t *testing.T) {
+	release := make(chan struct{})
+	p := bulkhead.New("bulkhead-service-4")
+	p.MaxConcurrent = 1
+	p.MaxQueue = 2
+	running := holdSlots(t, p, 1, release)
+
+	order := make(chan int, 2)
+	var wg sync.WaitGroup
+	for i := 1; i <= 2; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			q := p
+			q.Command = func() error {
+				order <- i
+				return nil
+			}
+			assert.Nil(t, q.Run(core.NewMetric()))
+		}(i)
+		waitStats(t, p.ServiceID, 1, i)
+	}
+
+	p.Command = func() error { return nil }
+	assert.ErrorIs(t, p.Run(core.NewMetric()), bulkhead.ErrBulkheadFull)
+
+	close(release)
+	running.Wait()
+	wg.Wait()
+
+	assert.Equal(t, 1, <-order)
+	assert.Equal(t, 2, <-order)
+}
+
+func TestRunMaxQueueWaitExceeded(t *testing.T) {
+	release := make(chan struct{})
+	clock := core.NewFakeClock(time.Now())
+	p := bulkhead.New("bulkhead-service-5")
+	p.MaxQueue = 1
+	p.MaxQueueWait = time.Second
+	p.Clock = clock
+	running := holdSlots(t, p, 1, release)
+
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}()
+
+	p.Command = func() error { return nil }
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(bulkhead.Metric{}).String()].(bulkhead.Metric)
+
+	assert.ErrorIs(t, err, bulkhead.ErrMaxQueueWaitExceeded)
+	assert.ErrorIs(t, err, bulkhead.ErrBulkheadFull)
+	assert.Equal(t, time.Second, m.QueueWait)
+	assert.True(t, m.Rejected)
+
+	_, queued := bulkhead.Stats(p.ServiceID)
+	assert.Equal(t, 0, queued)
+
+	close(release)
+	running.Wait()
+}
+
+func TestRunContextCanceledWhileQueued(t *testing.T) {
+	release := make(chan struct{})
+	p := bulkhead.New("bulkhead-service-6")
+	p.MaxQueue = 1
+	running := holdSlots(t, p, 1, release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		waitStats(t, p.ServiceID, 1, 1)
+		cancel()
+	}()
+
+	p.Command = func() error { return nil }
+	err := p.RunContext(ctx, core.NewMetric())
+
+	assert.ErrorIs(t, err, context.Canceled)
+
+	close(release)
+	running.Wait()
+
+	inFlight, queued := bulkhead.Stats(p.ServiceID)
+	assert.Equal(t, 0, inFlight)
+	assert.Equal(t, 0, queued)
+}
+
+func TestRunConcurrent(t *testing.T) {
+	const total = 200
+	var current, peak, completed int32
+	p := bulkhead.New("bulkhead-service-7")
+	p.MaxConcurrent = 5
+	p.MaxQueue = total
+	p.CommandContext = func(ctx context.Context) error {
+		n := atomic.AddInt32(&current, 1)
+		for {
+			old := atomic.LoadInt32(&peak)
+			if n <= old || atomic.CompareAndSwapInt32(&peak, old, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		atomic.AddInt32(&current, -1)
+		atomic.AddInt32(&completed, 1)
+
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(total)
+	for i := 0; i < total; i++ {
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, p.Run(core.NewMetric()))
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, atomic.LoadInt32(&peak), int32(5))
+	assert.Equal(t, int32(total), atomic.LoadInt32(&completed))
+}
+
+func TestRunPolicy(t *testing.T) {
+	errTest := errors.New("err test")
+	policy := new(mockPolicy)
+	policy.On("Run").Return(errTest)
+
+	p := bulkhead.New("bulkhead-service-8")
+	p.Policy = policy
+
+	err := p.Run(core.NewMetric())
+
+	assert.ErrorIs(t, err, errTest)
+}
+
+func TestRunSwallowErrors(t *testing.T) {
+	errTest := errors.New("err test")
+	release := make(chan struct{})
+	p := bulkhead.New("bulkhead-service-9")
+	p.SwallowErrors = true
+	p.Command = func() error { return errTest }
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	running := holdSlots(t, p, 1, release)
+	assert.Equal(t, bulkhead.ErrBulkheadFull, p.Run(core.NewMetric()))
+
+	close(release)
+	running.Wait()
+}
+
+func TestRunPanicReleasesSlot(t *testing.T) {
+	p := bulkhead.New("bulkhead-service-10")
+	p.Command = func() error { panic("command panic") }
+
+	assert.Panics(t, func() { _ = p.Run(core.NewMetric()) })
+
+	inFlight, queued := bulkhead.Stats("bulkhead-service-10")
+	assert.Equal(t, 0, inFlight)
+	assert.Equal(t, 0, queued)
+
+	p.Command = func() error { return nil }
+	assert.Nil(t, p.Run(core.NewMetric()))
+}
+
+func TestWithCommand(t *testing.T) {
+	p := bulkhead.New("remote-service")
+	c := func() error { return nil }
+	s := p.WithCommand(c)
+	p, _ = s.(bulkhead.Policy)
+
+	assert.NotNil(t, p.Command)
+}
+
+func TestWithCommandContext(t *testing.T) {
+	p := bulkhead.New("remote-service")
+	c := func(ctx context.Context) error { return nil }
+	s := p.WithCommandContext(c)
+	p, _ = s.(bulkhead.Policy)
+
+	assert.NotNil(t, p.CommandContext)
+}
+
+func TestWithPolicy(t *testing.T) {
+	p := bulkhead.New("remote-service")
+	s := p.WithPolicy(bulkhead.New("any"))
+	p, _ = s.(bulkhead.Policy)
+
+	assert.NotNil(t, p.Policy)
+}
+
+// holdSlots runs n executions which hold their slots until release is closed.
+func holdSlots(t *testing.T, p bulkhead.Policy, n int, release <-chan struct{}) *sync.WaitGroup {
+	var wg sync.WaitGroup
+	wg.Add(n)
+	p.Command = func() error {
+		<-release
+		return nil
+	}
+
+	for i := 0; i < n; i++ {
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, p.Run(core.NewMetric()))
+		}()
+	}
+	waitStats(t, p.ServiceID, n, 0)
+
+	return &wg
+}
+
+func waitStats(t *testing.T, serviceID string, inFlight, queued int) {
+	assert.Eventually(t, func() bool {
+		i, q := bulkhead.Stats(serviceID)
+		return i == inFlight && q == queued
+	}, time.Second, time.Millisecond)
+}
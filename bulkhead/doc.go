@@ -0,0 +1,136 @@
+/*
+The bulkhead pattern limits how many executions may call a service at the same time. A slow dependency
+can't take hold of every goroutine of the caller, and a struggling service isn't flooded by more calls
+than it can handle. Executions in excess wait in a bounded queue or are rejected.
+
+# Usage
+
+There are two ways to run a command under a policy. Using a Command supplier
+(anonymous function) or a wrapped policy.
+
+# Command supplier
+
+	p := bulkhead.New("service-id")
+	p.MaxConcurrent = 10
+	p.MaxQueue = 20
+	p.Command = func() error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+
+	if errors.Is(err, bulkhead.ErrBulkheadFull) {
+		// Rejected.
+		...
+	}
+
+	// Prints Bulkhead metric.
+	fmt.Println(metric)
+
+# Wrapped policy
+
+	policy := new(AnyPolicy)
+	policy.Command = func() error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	bh := bulkhead.New("service-id")
+	bh.MaxConcurrent = 10
+
+	// Instead of a command supplier, it is passed a policy.
+	bh.Policy = policy
+
+	metric := core.NewMetric()
+	err := bh.Run(metric)
+
+	mr := metric["bulkhead.Metric"] // or metric[reflect.TypeOf(bulkhead.Metric{}).String()]
+	bhMetric, _ := mr.(bulkhead.Metric)
+
+	// Prints Bulkhead metric.
+	fmt.Println(bhMetric)
+
+# Queue
+
+Executions of the same ServiceID share the bulkhead, whatever policy value runs them. At most MaxConcurrent
+executions run at the same time and at most MaxQueue wait for their turn, which they get in arrival order.
+An execution which arrives when the queue is full is rejected with ErrBulkheadFull. MaxQueueWait limits how
+long an execution waits in the queue, after which it is rejected with ErrMaxQueueWaitExceeded (which is an
+ErrBulkheadFull as well). The metric records the queue wait and the executions in flight and queued, and
+Stats tells those of a service at any time.
+
+	p := bulkhead.New("service-id")
+	p.MaxConcurrent = 10
+	p.MaxQueue = 20
+	p.MaxQueueWait = time.Second
+
+# Registry
+
+Bulkheads are kept by a Registry. Policies sharing a registry and a ServiceID share a bulkhead, so unrelated
+components (or tests) should use registries of their own. Policies without a Registry use the default one
+(see DefaultRegistry). A bulkhead keeps the MaxConcurrent and MaxQueue of the policy which created it: a policy
+of the same service with other settings fails with ErrSettingsConflict, until the bulkhead is removed.
+
+	registry := bulkhead.NewRegistry()
+
+	p := bulkhead.New("service-id")
+	p.Registry = registry
+	...
+
+	inFlight, queued := registry.Stats("service-id")
+	registry.Remove("service-id")
+
+# Errors
+
+A rejected execution makes the policy return a *core.PolicyError wrapping ErrBulkheadFull,
+ErrMaxQueueWaitExceeded or the error of the context. An execution which fails makes it return a
+*core.PolicyError wrapping the error of the command supplier (or of the wrapped policy). SwallowErrors
+makes the policy return rejection errors as they are and nil when the execution fails (see core.PolicyError).
+
+# Context
+
+Every policy may also run bound to a context.Context. Use CommandContext instead of Command so that the
+command supplier receives the context, and RunContext instead of Run. A wrapped policy receives the same context.
+
+	p := bulkhead.New("service-id")
+	p.CommandContext = func(ctx context.Context) error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	err := p.RunContext(ctx, core.NewMetric())
+
+An execution leaves the queue as soon as the context is done. The queue wait is measured by the policy Clock
+(see core.Clock), which tests may replace by a core.FakeClock.
+
+# Listener
+
+In order to keep tracking of what is happening on the execution, you may use listeners to generate some events.
+bulkhead supports listeners to track events before and after policy execution, and when an execution is rejected.
+
+	p := bulkhead.New("service-id")
+	...
+	p.BeforeBulkhead = func(p bulkhead.Policy) {
+		fmt.Println("Before bulkhead.")
+	}
+	p.AfterBulkhead = func(p bulkhead.Policy, err error) {
+		fmt.Println("After bulkhead.")
+	}
+	p.OnReject = func(p bulkhead.Policy, err error) {
+		fmt.Println("Rejected.")
+	}
+
+	_ = p.Run(core.Metric())
+*/
+package bulkhead
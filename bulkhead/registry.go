@@ -0,0 +1,80 @@
+package bulkhead
+
+import "sync"
+
+// Registry keeps the bulkheads of services. Policies sharing a registry and a ServiceID share a bulkhead,
+// while separate registries are isolated from each other. Policies without a Registry use the default one.
+type Registry struct {
+	mu         sync.Mutex
+	semaphores map[string]*semaphore
+}
+
+var defaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{semaphores: make(map[string]*semaphore)}
+}
+
+// DefaultRegistry returns the registry used by policies without a Registry.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Stats queries for the executions of the service in progress and waiting in the queue of the default
+// registry (see Registry.Stats).
+func Stats(serviceID string) (int, int) {
+	return defaultRegistry.Stats(serviceID)
+}
+
+// Stats queries for the executions of the service in progress and waiting in the queue.
+//
+// Returns the number of executions in flight and queued (none if the service has no bulkhead).
+func (r *Registry) Stats(serviceID string) (int, int) {
+	r.mu.Lock()
+	b := r.semaphores[serviceID]
+	r.mu.Unlock()
+
+	if b == nil {
+		return 0, 0
+	}
+
+	return b.stats()
+}
+
+// Remove discards the bulkhead of a service, so that it may be used with other settings. Executions in
+// progress give their slots back to the discarded bulkhead.
+func (r *Registry) Remove(serviceID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.semaphores, serviceID)
+}
+
+// get returns the bulkhead of the service, creating it with the settings of the policy if there is none.
+//
+// Possible error(s): ErrSettingsConflict.
+func (r *Registry) get(p Policy) (*semaphore, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.semaphores[p.ServiceID]
+	if b == nil {
+		b = &semaphore{maxConcurrent: p.MaxConcurrent, maxQueue: p.MaxQueue}
+		r.semaphores[p.ServiceID] = b
+	}
+
+	if b.maxConcurrent != p.MaxConcurrent || b.maxQueue != p.MaxQueue {
+		return nil, ErrSettingsConflict
+	}
+
+	return b, nil
+}
+
+func registryOf(p Policy) *Registry {
+	if p.Registry == nil {
+		return defaultRegistry
+	}
+
+	return p.Registry
+}
@@ -0,0 +1,81 @@
+package bulkhead_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/bulkhead"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	assert.NotNil(t, bulkhead.DefaultRegistry())
+	assert.Same(t, bulkhead.DefaultRegistry(), bulkhead.DefaultRegistry())
+}
+
+func TestRegistryIsolation(t *testing.T) {
+	first, second := bulkhead.NewRegistry(), bulkhead.NewRegistry()
+	release := make(chan struct{})
+	started := make(chan struct{})
+
+	p := bulkhead.New("service")
+	p.Registry = first
+	p.Command = func() error {
+		close(started)
+		<-release
+		return nil
+	}
+	done := make(chan error)
+	go func() { done <- p.Run(core.NewMetric()) }()
+	<-started
+
+	// The bulkhead of the first registry is full, the one of the second registry isn't.
+	p.Command = func() error { return nil }
+	assert.ErrorIs(t, p.Run(core.NewMetric()), bulkhead.ErrBulkheadFull)
+	p.Registry = second
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	inFlight, queued := first.Stats("service")
+	assert.Equal(t, 1, inFlight)
+	assert.Equal(t, 0, queued)
+	inFlight, _ = second.Stats("service")
+	assert.Equal(t, 0, inFlight)
+
+	close(release)
+	assert.Nil(t, <-done)
+}
+
+func TestRegistryStatsUnknownService(t *testing.T) {
+	inFlight, queued := bulkhead.NewRegistry().Stats("service")
+
+	assert.Equal(t, 0, inFlight)
+	assert.Equal(t, 0, queued)
+}
+
+func TestRegistrySettingsConflict(t *testing.T) {
+	r := bulkhead.NewRegistry()
+	p := bulkhead.New("service")
+	p.Registry = r
+	p.Command = func() error { return nil }
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	other := p
+	other.MaxConcurrent = 5
+	metric := core.NewMetric()
+	assert.ErrorIs(t, other.Run(metric), bulkhead.ErrSettingsConflict)
+	assert.Empty(t, metric)
+
+	other.MaxConcurrent = p.MaxConcurrent
+	other.MaxQueue = 5
+	assert.ErrorIs(t, other.Run(core.NewMetric()), bulkhead.ErrSettingsConflict)
+
+	// A policy with the same settings shares the bulkhead, whatever its queue wait.
+	other.MaxQueue = p.MaxQueue
+	other.MaxQueueWait = time.Second
+	assert.Nil(t, other.Run(core.NewMetric()))
+
+	r.Remove("service")
+	other.MaxConcurrent = 5
+	assert.Nil(t, other.Run(core.NewMetric()))
+}
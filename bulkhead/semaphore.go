@@ -0,0 +1,110 @@
+package bulkhead
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+)
+
+// semaphore keeps the executions in progress of a service and those waiting for their turn.
+// A released slot is handed over to the first waiting execution, so the queue is served in order.
+type semaphore struct {
+	maxConcurrent int
+	maxQueue      int
+
+	mu       sync.Mutex
+	inFlight int
+	waiters  []chan struct{}
+}
+
+// stats returns the number of executions in flight and queued.
+func (b *semaphore) stats() (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.inFlight, len(b.waiters)
+}
+
+// acquire takes a slot, waiting in the queue if needed, and records the counts and the wait on the metric.
+func (b *semaphore) acquire(ctx context.Context, p Policy, clock core.Clock, m *Metric) error {
+	b.mu.Lock()
+	m.Queued = len(b.waiters)
+
+	if b.inFlight < b.maxConcurrent && len(b.waiters) == 0 {
+		b.inFlight++
+		m.InFlight = b.inFlight
+		b.mu.Unlock()
+
+		return nil
+	}
+
+	if len(b.waiters) >= b.maxQueue {
+		b.mu.Unlock()
+		return ErrBulkheadFull
+	}
+
+	// Buffered, so that release never blocks on handing the slot over.
+	turn := make(chan struct{}, 1)
+	b.waiters = append(b.waiters, turn)
+	b.mu.Unlock()
+
+	queued := clock.Now()
+	var expired <-chan time.Time
+	if p.MaxQueueWait > 0 {
+		timer := clock.NewTimer(p.MaxQueueWait)
+		defer timer.Stop()
+		expired = timer.C()
+	}
+
+	var err error
+
+	select {
+	case <-turn:
+	case <-expired:
+		err = ErrMaxQueueWaitExceeded
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	m.QueueWait = clock.Now().Sub(queued)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err != nil && b.dequeue(turn) {
+		return err
+	}
+
+	// The slot was handed over, even if the wait was over at the same time.
+	m.InFlight = b.inFlight
+
+	return nil
+}
+
+// release hands the slot over to the first waiting execution or frees it.
+func (b *semaphore) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(b.waiters) == 0 {
+		b.inFlight--
+		return
+	}
+
+	turn := b.waiters[0]
+	b.waiters = b.waiters[1:]
+	turn <- struct{}{}
+}
+
+// dequeue removes a waiting execution from the queue. It returns false if the execution isn't waiting anymore.
+func (b *semaphore) dequeue(turn chan struct{}) bool {
+	for i, w := range b.waiters {
+		if w == turn {
+			b.waiters = append(b.waiters[:i], b.waiters[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
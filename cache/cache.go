@@ -0,0 +1,408 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+)
+
+var (
+	// Policy TTL is less than minimum required.
+	ErrTTLValidation = fmt.Errorf("ttl must be >= %d", MinTTL)
+
+	// Policy stale while revalidate is less than minimum required.
+	ErrStaleWhileRevalidateValidation = fmt.Errorf("stale while revalidate must be >= %d", MinStaleWhileRevalidate)
+
+	// Policy stale if error is less than minimum required.
+	ErrStaleIfErrorValidation = fmt.Errorf("stale if error must be >= %d", MinStaleIfError)
+
+	// Policy capacity is less than minimum required.
+	ErrCapacityValidation = fmt.Errorf("capacity must be >= %d", MinCapacity)
+
+	// No key function is set.
+	ErrKeyRequired = errors.New("key function required")
+
+	// No command nor wrapped policy is set.
+	ErrCommandRequired = errors.New("command nor wrapped policy provided")
+
+	// The default store of the service was made with another Capacity.
+	ErrSettingsConflict = errors.New("cache settings conflict with those of the service")
+)
+
+// Policy defines the cache algorithm execution policy.
+type Policy struct {
+	// The registered service id. Calls of the same service share the default store.
+	ServiceID string
+
+	// Function which tells the key of the result of a call (e.g. from values bound to ctx or captured
+	// by the function). An empty key bypasses the cache.
+	Key func(ctx context.Context) string
+
+	// How long a stored result is fresh, being served without executing the command supplier.
+	TTL time.Duration
+
+	// How long after TTL a stored result is still served, while it is refreshed in the background.
+	StaleWhileRevalidate time.Duration
+
+	// How long after TTL a stored result is still served when the execution fails.
+	StaleIfError time.Duration
+
+	// Errors which a stale result is served upon (any error if empty).
+	Errors []error
+
+	// Where results are stored (an in-memory LRUStore of Capacity entries, shared by the service, if not set).
+	Store Store
+
+	// Number of entries of the default store.
+	Capacity int
+
+	// Clock used to tell time (real time if not set).
+	Clock core.Clock
+
+	// Registry which keeps the default store of the service (the default registry if not set).
+	Registry *Registry
+
+	// Whether the policy returns nil when the command supplier or the wrapped policy fails, whose error
+	// is then only recorded in the metric.
+	SwallowErrors bool
+
+	// Function called before execution.
+	BeforeCache func(p Policy)
+
+	// Function called after execution.
+	AfterCache func(p Policy, err error)
+
+	// Function called when a refresh in the background finishes.
+	OnRevalidate func(p Policy, err error)
+
+	// The command supplier.
+	Command core.Command
+
+	// The context-aware command supplier. It takes precedence over Command.
+	CommandContext core.CommandContext
+
+	// Any policy that will be wrapped by this one.
+	Policy core.PolicySupplier
+}
+
+// Metric keeps the running state of the cache.
+type Metric struct {
+	// The registered service id.
+	ID string
+
+	// The execution status (success is non zero).
+	Status int
+
+	// When execution started.
+	StartedAt time.Time
+
+	// When execution finished.
+	FinishedAt time.Time
+
+	// The error (if execution wasn't succeeded)
+	Error error
+
+	// The key of the result.
+	Key string
+
+	// What the cache did about the call.
+	Outcome Outcome
+
+	// How old the served result was, if it was a stored one.
+	Age time.Duration
+
+	// The error which a stale result was served upon.
+	Cause error
+}
+
+// Outcome is what the cache did about a call.
+type Outcome int
+
+const (
+	// Indicates that no usable result was stored, so the command supplier was executed.
+	MissOutcome = Outcome(0)
+
+	// Indicates that a fresh result was served.
+	HitOutcome = Outcome(1)
+
+	// Indicates that a stale result was served while it is refreshed in the background.
+	StaleOutcome = Outcome(2)
+
+	// Indicates that a stale result was served because the execution failed.
+	StaleIfErrorOutcome = Outcome(3)
+
+	// Indicates that the call had no key or no result to be stored (see resiliencia.ExecuteT), so the
+	// command supplier was executed as it is.
+	BypassOutcome = Outcome(4)
+)
+
+const (
+	// Minimum expected to be set on TTL field of a cache policy.
+	MinTTL = 0
+
+	// Minimum expected to be set on StaleWhileRevalidate field of a cache policy.
+	MinStaleWhileRevalidate = 0
+
+	// Minimum expected to be set on StaleIfError field of a cache policy.
+	MinStaleIfError = 0
+
+	// Minimum expected to be set on Capacity field of a cache policy.
+	MinCapacity = 1
+
+	// Capacity set by New.
+	DefaultCapacity = 1024
+)
+
+// New creates a cache policy with default values set.
+func New(serviceID string) Policy {
+	return Policy{ServiceID: serviceID, Capacity: DefaultCapacity}
+}
+
+// Run executes a command supplier or a wrapped policy in a cache.
+//
+// Possible error(s): ErrTTLValidation, ErrStaleWhileRevalidateValidation, ErrStaleIfErrorValidation,
+// ErrCapacityValidation, ErrKeyRequired, ErrCommandRequired, ErrSettingsConflict (see RunContext).
+func (p Policy) Run(metric core.Metric) error {
+	return p.RunContext(context.Background(), metric)
+}
+
+// RunContext executes a command supplier or a wrapped policy in a cache bound to a context.
+// Results are taken from and delivered to the result bound to ctx (see resiliencia.ExecuteT),
+// so a call without one bypasses the cache.
+//
+// Validation errors and ErrSettingsConflict are returned as they are. Any other error is a
+// *core.PolicyError wrapping the error of the command supplier or of the wrapped policy, unless
+// SwallowErrors is set.
+//
+// Possible error(s): ErrTTLValidation, ErrStaleWhileRevalidateValidation, ErrStaleIfErrorValidation,
+// ErrCapacityValidation, ErrKeyRequired, ErrCommandRequired, ErrSettingsConflict.
+func (p Policy) RunContext(ctx context.Context, metric core.Metric) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+
+	store, err := registryOf(p).store(p)
+	if err != nil {
+		return err
+	}
+
+	clock := core.ClockOrDefault(p.Clock)
+	m := Metric{ID: p.ServiceID, StartedAt: clock.Now()}
+	if p.BeforeCache != nil {
+		p.BeforeCache(p)
+	}
+
+	err = lookup(ctx, p, store, clock, metric, &m)
+	if err != nil {
+		m.Status = 1
+		m.Error = err
+	}
+
+	if p.AfterCache != nil {
+		p.AfterCache(p, err)
+	}
+	m.FinishedAt = clock.Now()
+	metric[reflect.TypeOf(m).String()] = m
+
+	return failure(p, err)
+}
+
+// WithCommand encapsulates this policy in a new policy with given command supplier.
+func (p Policy) WithCommand(command core.Command) core.PolicySupplier {
+	p.Command = command
+	return p
+}
+
+// WithCommandContext encapsulates this policy in a new policy with given context-aware command supplier.
+func (p Policy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.CommandContext = command
+	return p
+}
+
+// WithPolicy encapsulates this policy in a new policy with wrapped policy.
+func (p Policy) WithPolicy(policy core.PolicySupplier) core.PolicySupplier {
+	p.Policy = policy
+	return p
+}
+
+// lookup serves the stored result of the call or executes it, recording the outcome on the metric.
+func lookup(ctx context.Context, p Policy, store Store, clock core.Clock, metric core.Metric, m *Metric) error {
+	result := core.ResultFromContext(ctx)
+	if result != nil {
+		m.Key = p.Key(ctx)
+	}
+	if m.Key == "" {
+		m.Outcome = BypassOutcome
+		return pickError(execute(ctx, p, metric), metric)
+	}
+
+	entry, found := store.Load(m.Key)
+	now := clock.Now()
+	age := now.Sub(entry.StoredAt)
+
+	switch {
+	case found && age < p.TTL:
+		m.Outcome, m.Age = HitOutcome, age
+		result.Set(entry.Value)
+
+		return nil
+	case found && age < p.TTL+p.StaleWhileRevalidate:
+		m.Outcome, m.Age = StaleOutcome, age
+		result.Set(entry.Value)
+		revalidate(ctx, p, store, m.Key)
+
+		return nil
+	case found && !now.Before(entry.ExpiresAt):
+		store.Delete(m.Key)
+		found = false
+	}
+
+	m.Outcome = MissOutcome
+	value, ok, err := refresh(ctx, p, store, m.Key, metric)
+	if err == nil {
+		if ok {
+			result.Set(value)
+		}
+
+		return nil
+	}
+
+	age = clock.Now().Sub(entry.StoredAt)
+	if found && age < p.TTL+p.StaleIfError && staleError(p, err) {
+		m.Outcome, m.Age, m.Cause = StaleIfErrorOutcome, age, err
+		result.Set(entry.Value)
+
+		return nil
+	}
+
+	return err
+}
+
+// refresh executes the command supplier or the wrapped policy, storing the result it produces.
+// It returns the result and whether there is one.
+func refresh(ctx context.Context, p Policy, store Store, key string, metric core.Metric) (any, bool, error) {
+	rctx, result := core.NewResultContext(ctx)
+	if err := pickError(execute(rctx, p, metric), metric); err != nil {
+		return nil, false, err
+	}
+
+	value, ok := result.Get()
+	if ok {
+		now := core.ClockOrDefault(p.Clock).Now()
+		store.Save(key, Entry{Value: value, StoredAt: now, ExpiresAt: now.Add(p.TTL + staleness(p))})
+	}
+
+	return value, ok, nil
+}
+
+// revalidate refreshes the result of the key in the background, unless it is already being refreshed.
+// The refresh keeps the values bound to ctx, but it isn't canceled along with it.
+func revalidate(ctx context.Context, p Policy, store Store, key string) {
+	r := registryOf(p)
+	if !r.startRevalidation(p.ServiceID, key) {
+		return
+	}
+
+	go func() {
+		_, _, err := refresh(detached{ctx}, p, store, key, core.NewMetric())
+		r.endRevalidation(p.ServiceID, key)
+
+		if p.OnRevalidate != nil {
+			p.OnRevalidate(p, err)
+		}
+	}()
+}
+
+// detached is a context which keeps the values of another one, but neither its deadline nor its cancellation.
+type detached struct{ context.Context }
+
+func (detached) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detached) Done() <-chan struct{}       { return nil }
+func (detached) Err() error                  { return nil }
+
+func execute(ctx context.Context, p Policy, metric core.Metric) error {
+	if p.Policy == nil {
+		if p.CommandContext != nil {
+			return p.CommandContext(ctx)
+		}
+
+		return p.Command()
+	}
+
+	return core.RunPolicy(ctx, p.Policy, metric)
+}
+
+func pickError(err error, metric core.MetricRecorder) error {
+	if err != nil {
+		return err
+	}
+
+	return metric.MetricError()
+}
+
+// failure wraps the error of the execution, unless errors are swallowed.
+func failure(p Policy, cause error) error {
+	if cause == nil || p.SwallowErrors {
+		return nil
+	}
+
+	return &core.PolicyError{Policy: "cache", ServiceID: p.ServiceID, Cause: cause}
+}
+
+func staleError(p Policy, err error) bool {
+	return len(p.Errors) == 0 || core.ErrorInErrors(p.Errors, err)
+}
+
+// staleness is how long after TTL a stored result may still be served.
+func staleness(p Policy) time.Duration {
+	if p.StaleWhileRevalidate > p.StaleIfError {
+		return p.StaleWhileRevalidate
+	}
+
+	return p.StaleIfError
+}
+
+func validate(p Policy) error {
+	switch {
+	case p.TTL < MinTTL:
+		return ErrTTLValidation
+	case p.StaleWhileRevalidate < MinStaleWhileRevalidate:
+		return ErrStaleWhileRevalidateValidation
+	case p.StaleIfError < MinStaleIfError:
+		return ErrStaleIfErrorValidation
+	case p.Store == nil && p.Capacity < MinCapacity:
+		return ErrCapacityValidation
+	case p.Key == nil:
+		return ErrKeyRequired
+	case p.Command == nil && p.CommandContext == nil && p.Policy == nil:
+		return ErrCommandRequired
+	default:
+		return nil
+	}
+}
+
+// ServiceID returns the service id registered to the policy binded to this metric.
+func (m Metric) ServiceID() string {
+	return m.ID
+}
+
+// PolicyDuration returns the policy execution duration.
+// In short, finished at less (-) started at.
+func (m Metric) PolicyDuration() time.Duration {
+	return m.FinishedAt.Sub(m.StartedAt)
+}
+
+// Success returns whether the policy execution succeeded or not.
+// In short, status is zero and error is nil.
+func (m Metric) Success() bool {
+	return (m.Status == 0) && (m.Error == nil)
+}
+
+// MetricError returns the error that a command supplier or a wrapped policy raised.
+func (m Metric) MetricError() error {
+	return m.Error
+}
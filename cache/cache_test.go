@@ -0,0 +1,453 @@
+package cache_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia"
+	"github.com/aureliano/resiliencia/cache"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPolicy struct{ mock.Mock }
+
+func (p *mockPolicy) Run(_ core.Metric) error {
+	args := p.Called()
+	return args.Error(0)
+}
+
+func (p *mockPolicy) WithCommand(_ core.Command) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+func (p *mockPolicy) WithPolicy(_ core.PolicySupplier) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+func key(context.Context) string { return "key" }
+
+// run runs the policy with a result bound to the context, as result-returning executions do.
+func run(p cache.Policy) (any, cache.Metric, error) {
+	ctx, result := core.NewResultContext(context.Background())
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+
+	value, _ := result.Get()
+	m, _ := metric[reflect.TypeOf(cache.Metric{}).String()].(cache.Metric)
+
+	return value, m, err
+}
+
+// counter returns a command supplier which produces how many times it was called.
+func counter(calls *int32) core.CommandContext {
+	return func(ctx context.Context) error {
+		core.SetResult(ctx, int(atomic.AddInt32(calls, 1)))
+		return nil
+	}
+}
+
+func TestPolicyImplementsPolicySupplier(t *testing.T) {
+	p := cache.New("remote-service")
+	i := reflect.TypeOf((*core.PolicySupplier)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(p).Implements(i))
+}
+
+func TestMetricImplementsMetricRecorder(t *testing.T) {
+	m := cache.Metric{}
+	i := reflect.TypeOf((*core.MetricRecorder)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(m).Implements(i))
+}
+
+func TestNew(t *testing.T) {
+	p := cache.New("remote-service")
+
+	assert.Equal(t, "remote-service", p.ServiceID)
+	assert.Equal(t, cache.DefaultCapacity, p.Capacity)
+	assert.Zero(t, p.TTL)
+	assert.Nil(t, p.Store)
+}
+
+func TestRunValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(p *cache.Policy)
+		err    error
+	}{
+		{"ttl", func(p *cache.Policy) { p.TTL = -1 }, cache.ErrTTLValidation},
+		{
+			"stale while revalidate",
+			func(p *cache.Policy) { p.StaleWhileRevalidate = -1 },
+			cache.ErrStaleWhileRevalidateValidation,
+		},
+		{"stale if error", func(p *cache.Policy) { p.StaleIfError = -1 }, cache.ErrStaleIfErrorValidation},
+		{"capacity", func(p *cache.Policy) { p.Capacity = 0 }, cache.ErrCapacityValidation},
+		{"key", func(p *cache.Policy) { p.Key = nil }, cache.ErrKeyRequired},
+		{"command", func(p *cache.Policy) { p.Command = nil }, cache.ErrCommandRequired},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := cache.New("remote-service")
+			p.Key = key
+			p.Command = func() error { return nil }
+			c.change(&p)
+
+			assert.ErrorIs(t, p.Run(core.NewMetric()), c.err)
+		})
+	}
+
+	p := cache.New("remote-service")
+	p.Key = key
+	p.Capacity = 0
+	p.Store = cache.NewLRUStore(1)
+	p.Command = func() error { return nil }
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+}
+
+func TestRunMissAndHit(t *testing.T) {
+	var calls int32
+	before, after := false, false
+	clock := core.NewFakeClock(time.Now())
+	cache.Reset("cache-service-1")
+
+	p := cache.New("cache-service-1")
+	p.Key = key
+	p.TTL = time.Minute
+	p.Clock = clock
+	p.BeforeCache = func(p cache.Policy) { before = true }
+	p.AfterCache = func(p cache.Policy, err error) { after = true }
+	p.CommandContext = counter(&calls)
+
+	value, m, err := run(p)
+
+	assert.Nil(t, err)
+	assert.True(t, before)
+	assert.True(t, after)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, cache.MissOutcome, m.Outcome)
+	assert.Equal(t, "key", m.Key)
+	assert.Equal(t, "cache-service-1", m.ServiceID())
+	assert.True(t, m.Success())
+
+	clock.Advance(time.Second * 59)
+	value, m, err = run(p)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, cache.HitOutcome, m.Outcome)
+	assert.Equal(t, time.Second*59, m.Age)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	clock.Advance(time.Second)
+	value, m, _ = run(p)
+
+	assert.Equal(t, 2, value)
+	assert.Equal(t, cache.MissOutcome, m.Outcome)
+}
+
+func TestRunStaleWhileRevalidate(t *testing.T) {
+	var calls int32
+	revalidated := make(chan error, 1)
+	clock := core.NewFakeClock(time.Now())
+	cache.Reset("cache-service-2")
+
+	p := cache.New("cache-service-2")
+	p.Key = key
+	p.TTL = time.Minute
+	p.StaleWhileRevalidate = time.Minute
+	p.Clock = clock
+	p.OnRevalidate = func(p cache.Policy, err error) { revalidated <- err }
+	p.CommandContext = counter(&calls)
+
+	_, _, _ = run(p)
+	clock.Advance(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ctx, result := core.NewResultContext(ctx)
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	cancel()
+
+	value, _ := result.Get()
+	m, _ := metric[reflect.TypeOf(cache.Metric{}).String()].(cache.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, cache.StaleOutcome, m.Outcome)
+	assert.Equal(t, time.Minute, m.Age)
+
+	// The refresh outlives the call which started it.
+	assert.Nil(t, <-revalidated)
+
+	value, m, _ = run(p)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, cache.HitOutcome, m.Outcome)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRunStaleWhileRevalidateOnce(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	revalidated := make(chan error, 1)
+	clock := core.NewFakeClock(time.Now())
+	cache.Reset("cache-service-3")
+
+	p := cache.New("cache-service-3")
+	p.Key = key
+	p.StaleWhileRevalidate = time.Minute
+	p.Clock = clock
+	p.OnRevalidate = func(p cache.Policy, err error) { revalidated <- err }
+	p.CommandContext = func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) > 1 {
+			<-release
+		}
+		core.SetResult(ctx, "value")
+
+		return nil
+	}
+
+	_, _, _ = run(p)
+	_, m1, _ := run(p)
+	_, m2, _ := run(p)
+	close(release)
+
+	assert.Equal(t, cache.StaleOutcome, m1.Outcome)
+	assert.Equal(t, cache.StaleOutcome, m2.Outcome)
+	assert.Nil(t, <-revalidated)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestRunStaleIfError(t *testing.T) {
+	errTest := errors.New("err test")
+	fail := false
+	clock := core.NewFakeClock(time.Now())
+	cache.Reset("cache-service-4")
+
+	p := cache.New("cache-service-4")
+	p.Key = key
+	p.TTL = time.Minute
+	p.StaleIfError = time.Hour
+	p.Clock = clock
+	p.CommandContext = func(ctx context.Context) error {
+		if fail {
+			return errTest
+		}
+		core.SetResult(ctx, "value")
+
+		return nil
+	}
+
+	_, _, _ = run(p)
+	fail = true
+	clock.Advance(time.Minute * 30)
+	value, m, err := run(p)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value", value)
+	assert.Equal(t, cache.StaleIfErrorOutcome, m.Outcome)
+	assert.Equal(t, time.Minute*30, m.Age)
+	assert.Equal(t, errTest, m.Cause)
+	assert.True(t, m.Success())
+
+	clock.Advance(time.Minute * 31)
+	value, m, err = run(p)
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "cache", perr.Policy)
+	assert.ErrorIs(t, err, errTest)
+	assert.Nil(t, value)
+	assert.Equal(t, cache.MissOutcome, m.Outcome)
+	assert.Equal(t, errTest, m.MetricError())
+}
+
+func TestRunStaleIfErrorUnhandledError(t *testing.T) {
+	errTest := errors.New("err test")
+	errOther := errors.New("err other")
+	fail := false
+	clock := core.NewFakeClock(time.Now())
+	cache.Reset("cache-service-5")
+
+	p := cache.New("cache-service-5")
+	p.Key = key
+	p.StaleIfError = time.Hour
+	p.Errors = []error{errOther}
+	p.Clock = clock
+	p.CommandContext = func(ctx context.Context) error {
+		if fail {
+			return errTest
+		}
+		core.SetResult(ctx, "value")
+
+		return nil
+	}
+
+	_, _, _ = run(p)
+	fail = true
+	value, m, err := run(p)
+
+	assert.ErrorIs(t, err, errTest)
+	assert.Nil(t, value)
+	assert.Equal(t, cache.MissOutcome, m.Outcome)
+}
+
+func TestRunBypass(t *testing.T) {
+	var calls int32
+	cache.Reset("cache-service-6")
+
+	p := cache.New("cache-service-6")
+	p.Key = func(context.Context) string { return "" }
+	p.TTL = time.Hour
+	p.CommandContext = counter(&calls)
+
+	_, _, _ = run(p)
+	value, m, err := run(p)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, cache.BypassOutcome, m.Outcome)
+
+	// Without a result bound to the context, there is nothing to be stored.
+	p.Key = key
+	p.Command = func() error { return nil }
+	p.CommandContext = nil
+	metric := core.NewMetric()
+	err = p.Run(metric)
+	m, _ = metric[reflect.TypeOf(cache.Metric{}).String()].(cache.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, cache.BypassOutcome, m.Outcome)
+	assert.Empty(t, m.Key)
+}
+
+func TestRunStore(t *testing.T) {
+	var calls int32
+	now := time.Now()
+	store := cache.NewLRUStore(1)
+
+	p := cache.New("cache-service-7")
+	p.Key = key
+	p.TTL = time.Minute
+	p.StaleIfError = time.Hour
+	p.Store = store
+	p.Clock = core.NewFakeClock(now)
+	p.CommandContext = counter(&calls)
+
+	_, _, _ = run(p)
+	entry, ok := store.Load("key")
+
+	assert.True(t, ok)
+	assert.Equal(t, 1, entry.Value)
+	assert.Equal(t, now, entry.StoredAt)
+	assert.Equal(t, now.Add(time.Minute+time.Hour), entry.ExpiresAt)
+}
+
+func TestRunExpiredEntry(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+	store := cache.NewLRUStore(1)
+	store.Save("key", cache.Entry{Value: "value", StoredAt: clock.Now(), ExpiresAt: clock.Now().Add(time.Minute)})
+
+	p := cache.New("cache-service-8")
+	p.Key = key
+	p.StaleIfError = time.Hour
+	p.Store = store
+	p.Clock = clock
+	p.Command = func() error { return errTest }
+
+	clock.Advance(time.Minute)
+	_, _, err := run(p)
+
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, 0, store.Len())
+}
+
+func TestRunPolicy(t *testing.T) {
+	errTest := errors.New("err test")
+	policy := new(mockPolicy)
+	policy.On("Run").Return(errTest)
+	cache.Reset("cache-service-9")
+
+	p := cache.New("cache-service-9")
+	p.Key = key
+	p.Policy = policy
+
+	_, _, err := run(p)
+
+	assert.ErrorIs(t, err, errTest)
+}
+
+func TestRunSwallowErrors(t *testing.T) {
+	errTest := errors.New("err test")
+	cache.Reset("cache-service-10")
+
+	p := cache.New("cache-service-10")
+	p.Key = key
+	p.SwallowErrors = true
+	p.Command = func() error { return errTest }
+
+	_, m, err := run(p)
+
+	assert.Nil(t, err)
+	assert.Equal(t, errTest, m.MetricError())
+}
+
+func TestExecuteT(t *testing.T) {
+	var calls int32
+	cache.Reset("cache-service-11")
+
+	p := cache.New("cache-service-11")
+	p.Key = key
+	p.TTL = time.Hour
+	chain := resiliencia.ChainOfResponsibility{Policies: []core.PolicySupplier{p}}
+	command := func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		return "value", nil
+	}
+
+	_, _, _ = resiliencia.ExecuteT(context.Background(), chain, command)
+	value, metric, err := resiliencia.ExecuteT(context.Background(), chain, command)
+	m, _ := metric[reflect.TypeOf(cache.Metric{}).String()].(cache.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value", value)
+	assert.Equal(t, cache.HitOutcome, m.Outcome)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestWithCommand(t *testing.T) {
+	p := cache.New("remote-service")
+	c := func() error { return nil }
+	s := p.WithCommand(c)
+	p, _ = s.(cache.Policy)
+
+	assert.NotNil(t, p.Command)
+}
+
+func TestWithCommandContext(t *testing.T) {
+	p := cache.New("remote-service")
+	c := func(ctx context.Context) error { return nil }
+	s := p.WithCommandContext(c)
+	p, _ = s.(cache.Policy)
+
+	assert.NotNil(t, p.CommandContext)
+}
+
+func TestWithPolicy(t *testing.T) {
+	p := cache.New("remote-service")
+	s := p.WithPolicy(cache.New("any"))
+	p, _ = s.(cache.Policy)
+
+	assert.NotNil(t, p.Policy)
+}
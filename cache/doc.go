@@ -0,0 +1,131 @@
+/*
+The cache pattern serves the result of a former call instead of making the call again. Besides saving
+calls, it lets a stale result be served while it is refreshed in the background (stale while revalidate),
+or when the call fails (stale if error), which is a common fallback: serve the last good value.
+
+# Usage
+
+A cache stores results, so it is meant for result-returning executions (see resiliencia.ExecuteT), which
+bind a result to the context passed through the policies. There are two ways to run a command under a
+policy. Using a Command supplier (anonymous function) or a wrapped policy.
+
+# Command supplier
+
+	p := cache.New("service-id")
+	p.TTL = time.Minute
+	p.StaleIfError = time.Hour
+	p.Key = func(ctx context.Context) string {
+		return userID
+	}
+
+	chain := resiliencia.ChainOfResponsibility{Policies: []core.PolicySupplier{p}}
+	user, metric, err := resiliencia.ExecuteT(ctx, chain, func(ctx context.Context) (User, error) {
+		// Your business logic.
+		...
+
+		// Returns the result and error or nil otherwise.
+		return user, nil
+	})
+
+	if err != nil {
+		// Error handling.
+		...
+	}
+
+	// Prints Cache metric.
+	fmt.Println(metric["cache.Metric"])
+
+# Wrapped policy
+
+	policy := new(AnyPolicy)
+	policy.CommandContext = func(ctx context.Context) error {
+		// Your business logic.
+		...
+
+		// Stores the result and returns error or nil otherwise.
+		core.SetResult(ctx, user)
+		return nil
+	}
+
+	ch := cache.New("service-id")
+	ch.TTL = time.Minute
+	ch.Key = func(ctx context.Context) string {
+		return userID
+	}
+
+	// Instead of a command supplier, it is passed a policy.
+	ch.Policy = policy
+
+	ctx, result := core.NewResultContext(context.Background())
+	metric := core.NewMetric()
+	err := ch.RunContext(ctx, metric)
+
+	mr := metric["cache.Metric"] // or metric[reflect.TypeOf(cache.Metric{}).String()]
+	chMetric, _ := mr.(cache.Metric)
+
+	// Prints the result and Cache metric.
+	fmt.Println(result.Get())
+	fmt.Println(chMetric)
+
+# Keys
+
+Key tells the key of the result of a call, which is usually captured by the function or taken from a
+value bound to the context. A call with an empty key, or without a result bound to its context (e.g.
+one made by Run), bypasses the cache.
+
+# Freshness
+
+A stored result is fresh for TTL, being served without making the call. For StaleWhileRevalidate after
+that, it is served stale while a call refreshes it in the background (only one at a time per key). The
+refresh keeps the values bound to the context of the call which started it, but it isn't canceled along
+with it. For StaleIfError after TTL, it is served stale when the call fails with an error in Errors (any
+error if empty). The metric records whether the call was a hit, a miss or served stale, and how old the
+served result was.
+
+# Store
+
+Results are stored in an in-memory LRUStore of Capacity entries, shared by the calls of the service,
+unless Store is set. Any implementation of Store may be used (e.g. one backed by a remote cache), and
+Entry.ExpiresAt tells when an entry may be dropped. Reset empties the default store of a service.
+
+# Registry
+
+Default stores are kept by a Registry, along with the results being revalidated. Policies sharing a
+registry and a ServiceID share a default store, so unrelated components (or tests) should use registries
+of their own. Policies without a Registry use the default one (see DefaultRegistry). A default store keeps
+the Capacity of the policy which made it: a policy of the same service with another Capacity (and no Store)
+fails with ErrSettingsConflict, until Reset drops the store.
+
+	registry := cache.NewRegistry()
+
+	p := cache.New("service-id")
+	p.Registry = registry
+	...
+
+	registry.Reset("service-id")
+
+# Errors
+
+The policy returns a *core.PolicyError wrapping the error of the command supplier or of the wrapped
+policy, unless a stale result is served. SwallowErrors makes the policy return nil, leaving the error
+only in the metric (see core.PolicyError).
+
+# Listener
+
+In order to keep tracking of what is happening on the execution, you may use listeners to generate some events.
+cache supports listeners to track events before and after policy execution, and when a refresh in the
+background finishes.
+
+	p := cache.New("service-id")
+	...
+	p.BeforeCache = func(p cache.Policy) {
+		fmt.Println("Before cache.")
+	}
+	p.AfterCache = func(p cache.Policy, err error) {
+		fmt.Println("After cache.")
+	}
+	p.OnRevalidate = func(p cache.Policy, err error) {
+		fmt.Println("Revalidated:", err)
+	}
+*/
+package cache
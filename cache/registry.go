@@ -0,0 +1,94 @@
+package cache
+
+import "sync"
+
+// Registry keeps the default stores of services and the results being revalidated. Policies sharing a
+// registry and a ServiceID share a default store, while separate registries are isolated from each other.
+// Policies without a Registry use the default one.
+type Registry struct {
+	mu            sync.Mutex
+	stores        map[string]*LRUStore
+	revalidations map[string]struct{}
+}
+
+var defaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{stores: make(map[string]*LRUStore), revalidations: make(map[string]struct{})}
+}
+
+// DefaultRegistry returns the registry used by policies without a Registry.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Reset drops the default store of the service in the default registry (see Registry.Reset).
+func Reset(serviceID string) {
+	defaultRegistry.Reset(serviceID)
+}
+
+// Reset drops the default store of the service, so that its next call finds it empty, with the Capacity
+// of the policy making it.
+func (r *Registry) Reset(serviceID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.stores, serviceID)
+}
+
+// store returns the store of the policy. Calls of the same service share a default store, which is made
+// with the capacity of the policy if there is none.
+//
+// Possible error(s): ErrSettingsConflict.
+func (r *Registry) store(p Policy) (Store, error) {
+	if p.Store != nil {
+		return p.Store, nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s := r.stores[p.ServiceID]
+	if s == nil {
+		s = NewLRUStore(p.Capacity)
+		r.stores[p.ServiceID] = s
+	}
+
+	if s.capacity != p.Capacity {
+		return nil, ErrSettingsConflict
+	}
+
+	return s, nil
+}
+
+// startRevalidation marks the key of the service as being revalidated. It returns false if it already is.
+func (r *Registry) startRevalidation(serviceID, key string) bool {
+	id := serviceID + "\x00" + key
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.revalidations[id]; ok {
+		return false
+	}
+	r.revalidations[id] = struct{}{}
+
+	return true
+}
+
+// endRevalidation marks the key of the service as no longer being revalidated.
+func (r *Registry) endRevalidation(serviceID, key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.revalidations, serviceID+"\x00"+key)
+}
+
+func registryOf(p Policy) *Registry {
+	if p.Registry == nil {
+		return defaultRegistry
+	}
+
+	return p.Registry
+}
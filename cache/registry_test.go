@@ -0,0 +1,71 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/cache"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	assert.NotNil(t, cache.DefaultRegistry())
+	assert.Same(t, cache.DefaultRegistry(), cache.DefaultRegistry())
+}
+
+func TestRegistryIsolation(t *testing.T) {
+	var calls int32
+	first, second := cache.NewRegistry(), cache.NewRegistry()
+
+	p := cache.New("service")
+	p.Key = key
+	p.TTL = time.Minute
+	p.Clock = core.NewFakeClock(time.Now())
+	p.CommandContext = counter(&calls)
+
+	p.Registry = first
+	_, _, _ = run(p)
+	value, m, _ := run(p)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, cache.HitOutcome, m.Outcome)
+
+	// The default store of the second registry is empty, and it may have another capacity.
+	p.Registry = second
+	p.Capacity = 1
+	value, m, _ = run(p)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, cache.MissOutcome, m.Outcome)
+}
+
+func TestRegistrySettingsConflict(t *testing.T) {
+	var calls int32
+	r := cache.NewRegistry()
+
+	p := cache.New("service")
+	p.Key = key
+	p.TTL = time.Minute
+	p.Registry = r
+	p.Clock = core.NewFakeClock(time.Now())
+	p.CommandContext = counter(&calls)
+	_, _, _ = run(p)
+
+	other := p
+	other.Capacity = p.Capacity * 2
+	_, m, err := run(other)
+	assert.ErrorIs(t, err, cache.ErrSettingsConflict)
+	assert.Zero(t, m)
+
+	// A store of its own doesn't conflict with the default store of the service.
+	other.Store = cache.NewLRUStore(1)
+	_, _, err = run(other)
+	assert.Nil(t, err)
+
+	// A reset service gets a default store with the new capacity.
+	r.Reset("service")
+	other.Store = nil
+	value, m, err := run(other)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, value)
+	assert.Equal(t, cache.MissOutcome, m.Outcome)
+}
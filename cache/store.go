@@ -0,0 +1,107 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Entry is a result kept by a store.
+type Entry struct {
+	// The result produced by the command supplier or by the wrapped policy.
+	Value any
+
+	// When the result was stored.
+	StoredAt time.Time
+
+	// When the entry is of no use anymore, not even stale, so that stores may drop it.
+	ExpiresAt time.Time
+}
+
+// Store is the interface of the storage of cached results. Implementations must be safe for concurrent use.
+type Store interface {
+	// Load returns the entry of the key and whether there is one.
+	Load(key string) (Entry, bool)
+
+	// Save stores the entry of the key.
+	Save(key string, entry Entry)
+
+	// Delete removes the entry of the key.
+	Delete(key string)
+}
+
+// LRUStore is a Store which keeps up to a number of entries in memory, evicting the least recently
+// used one when it is full. It is the default store of a policy.
+type LRUStore struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List
+}
+
+type lruItem struct {
+	key   string
+	entry Entry
+}
+
+// NewLRUStore creates an empty in-memory store of the given capacity (at least MinCapacity).
+func NewLRUStore(capacity int) *LRUStore {
+	if capacity < MinCapacity {
+		capacity = MinCapacity
+	}
+
+	return &LRUStore{capacity: capacity, entries: make(map[string]*list.Element), order: list.New()}
+}
+
+// Load returns the entry of the key and whether there is one.
+func (s *LRUStore) Load(key string) (Entry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return Entry{}, false
+	}
+	s.order.MoveToFront(e)
+
+	return e.Value.(*lruItem).entry, true
+}
+
+// Save stores the entry of the key, evicting the least recently used entry if the store is full.
+func (s *LRUStore) Save(key string, entry Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		e.Value.(*lruItem).entry = entry
+		s.order.MoveToFront(e)
+
+		return
+	}
+
+	s.entries[key] = s.order.PushFront(&lruItem{key: key, entry: entry})
+	for s.order.Len() > s.capacity {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*lruItem).key)
+	}
+}
+
+// Delete removes the entry of the key.
+func (s *LRUStore) Delete(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if e, ok := s.entries[key]; ok {
+		s.order.Remove(e)
+		delete(s.entries, key)
+	}
+}
+
+// Len returns the number of entries in the store.
+func (s *LRUStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
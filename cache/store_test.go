@@ -0,0 +1,68 @@
+package cache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/cache"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRUStoreImplementsStore(t *testing.T) {
+	var s cache.Store = cache.NewLRUStore(1)
+	assert.NotNil(t, s)
+}
+
+func TestNewLRUStoreMinCapacity(t *testing.T) {
+	s := cache.NewLRUStore(0)
+	s.Save("a", cache.Entry{Value: 1})
+	s.Save("b", cache.Entry{Value: 2})
+
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestLRUStoreLoadSave(t *testing.T) {
+	now := time.Now()
+	s := cache.NewLRUStore(2)
+
+	_, ok := s.Load("a")
+	assert.False(t, ok)
+
+	s.Save("a", cache.Entry{Value: 1, StoredAt: now})
+	s.Save("a", cache.Entry{Value: 2, StoredAt: now})
+	entry, ok := s.Load("a")
+
+	assert.True(t, ok)
+	assert.Equal(t, 2, entry.Value)
+	assert.Equal(t, now, entry.StoredAt)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestLRUStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	s := cache.NewLRUStore(2)
+	s.Save("a", cache.Entry{Value: 1})
+	s.Save("b", cache.Entry{Value: 2})
+
+	// Loading a makes b the least recently used.
+	_, _ = s.Load("a")
+	s.Save("c", cache.Entry{Value: 3})
+
+	_, ok := s.Load("b")
+	assert.False(t, ok)
+	_, ok = s.Load("a")
+	assert.True(t, ok)
+	_, ok = s.Load("c")
+	assert.True(t, ok)
+	assert.Equal(t, 2, s.Len())
+}
+
+func TestLRUStoreDelete(t *testing.T) {
+	s := cache.NewLRUStore(2)
+	s.Save("a", cache.Entry{Value: 1})
+	s.Delete("a")
+	s.Delete("b")
+
+	_, ok := s.Load("a")
+	assert.False(t, ok)
+	assert.Equal(t, 0, s.Len())
+}
@@ -1,6 +1,8 @@
 package resiliencia
 
 import (
+	"context"
+
 	"github.com/aureliano/resiliencia/core"
 )
 
@@ -15,11 +17,28 @@ type Chainer interface {
 	Execute(command core.Command) (core.Metric, error)
 }
 
+// ContextChainer is the interface that context-aware chains of responsibility implement. It extends
+// Chainer so that a caller's cancellation or deadline is propagated through the chain.
+type ContextChainer interface {
+	Chainer
+
+	// ExecuteContext starts the chain of responsibility bound to a context.
+	ExecuteContext(ctx context.Context, command core.CommandContext) (core.Metric, error)
+}
+
 // Execute starts the chain of responsibility. The command supplier is set at the end
 // of the chain.
 //
 // Returns chained metrics.
 func (c ChainOfResponsibility) Execute(command core.Command) (core.Metric, error) {
+	return c.ExecuteContext(context.Background(), core.ContextCommand(command))
+}
+
+// ExecuteContext starts the chain of responsibility bound to a context. The context-aware
+// command supplier is set at the end of the chain and the context is passed through every policy.
+//
+// Returns chained metrics.
+func (c ChainOfResponsibility) ExecuteContext(ctx context.Context, command core.CommandContext) (core.Metric, error) {
 	if err := validateChain(c, command); err != nil {
 		return nil, err
 	}
@@ -27,19 +46,35 @@ func (c ChainOfResponsibility) Execute(command core.Command) (core.Metric, error
 	lindex := len(c.Policies) - 1
 	for i := lindex; i >= 0; i-- {
 		if i == lindex {
-			c.Policies[i] = c.Policies[i].WithCommand(command).WithPolicy(nil)
+			c.Policies[i] = core.BindCommand(ctx, c.Policies[i], command).WithPolicy(nil)
 		} else {
-			c.Policies[i] = c.Policies[i].WithCommand(nil).WithPolicy(c.Policies[i+1])
+			c.Policies[i] = core.BindCommand(ctx, c.Policies[i], nil).WithPolicy(c.Policies[i+1])
 		}
 	}
 
 	metric := core.NewMetric()
-	err := c.Policies[0].Run(metric)
+	err := core.RunPolicy(ctx, c.Policies[0], metric)
 
 	return metric, err
 }
 
-func validateChain(c ChainOfResponsibility, cmd core.Command) error {
+// executeChain starts the chain bound to a context. Chainers that don't implement ContextChainer
+// receive a plain command supplier which calls the context-aware one with ctx.
+func executeChain(ctx context.Context, chain Chainer, command core.CommandContext) (core.Metric, error) {
+	if c, ok := chain.(ContextChainer); ok {
+		return c.ExecuteContext(ctx, command)
+	}
+
+	if command == nil {
+		return chain.Execute(nil)
+	}
+
+	return chain.Execute(func() error {
+		return command(ctx)
+	})
+}
+
+func validateChain(c ChainOfResponsibility, cmd core.CommandContext) error {
 	switch {
 	case len(c.Policies) == 0:
 		return ErrPolicyRequired
@@ -2,6 +2,8 @@ package resiliencia_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -21,6 +23,13 @@ func TestChainerImplementsChainer(t *testing.T) {
 	assert.True(t, reflect.TypeOf(d).Implements(i))
 }
 
+func TestChainerImplementsContextChainer(t *testing.T) {
+	d := resiliencia.ChainOfResponsibility{}
+	i := reflect.TypeOf((*resiliencia.ContextChainer)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(d).Implements(i))
+}
+
 func TestChainerExecutePolicyRequired(t *testing.T) {
 	c := resiliencia.Chain()
 
@@ -362,3 +371,42 @@ func TestChainerExecuteTimeout(t *testing.T) {
 	assert.Nil(t, tm.Error)
 	assert.Greater(t, tm.PolicyDuration(), time.Duration(0))
 }
+
+func TestChainerExecuteContext(t *testing.T) {
+	type key struct{}
+	id := "service-id"
+	ctx := context.WithValue(context.Background(), key{}, id)
+	tmp := timeout.New(id)
+	tmp.Timeout = time.Second * 5
+	c := resiliencia.ChainContext(retry.New(id), tmp)
+
+	metric, err := c.ExecuteContext(ctx, func(ctx context.Context) error {
+		assert.Equal(t, id, ctx.Value(key{}))
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.True(t, metric.Success())
+	assert.Len(t, metric, 2)
+}
+
+func TestChainerExecuteContextCanceled(t *testing.T) {
+	id := "service-id"
+	errTest := errors.New("err test")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	rtp := retry.New(id)
+	rtp.Tries = 5
+	rtp.Errors = []error{errTest}
+	c := resiliencia.ChainContext(rtp)
+
+	calls := 0
+	metric, err := c.ExecuteContext(ctx, func(ctx context.Context) error {
+		calls++
+		cancel()
+		return errTest
+	})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.False(t, metric.Success())
+	assert.Equal(t, 1, calls)
+}
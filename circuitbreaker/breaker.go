@@ -0,0 +1,336 @@
+package circuitbreaker
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// breaker is the state machine of the circuit breaker of a service. Every transition is made (and its
+// event queued) while holding its lock, and policy hooks are only called once it is released.
+type breaker struct {
+	id       string
+	store    StateStore
+	registry *Registry
+
+	mu      sync.Mutex
+	status  CircuitBreaker
+	window  slidingWindow
+	removed bool
+}
+
+// transition is a state change to be notified to the policy hooks and to the registry subscribers.
+type transition struct {
+	status CircuitBreaker
+	event  Event
+}
+
+func (b *breaker) snapshot() CircuitBreaker {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.status
+}
+
+// state returns the current state, letting an open circuit become half open once its reset timeout elapsed.
+func (b *breaker) state(p Policy, now time.Time) (CircuitState, []transition) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	before := b.status
+	transitions := b.halfOpen(p, now)
+	b.save(before)
+
+	return b.status.State, transitions
+}
+
+// admit decides whether a call may be made, recording the state on the metric (and the
+// rejection error, if any). It returns the transitions made and whether the call is a trial call.
+func (b *breaker) admit(p Policy, now time.Time, m *Metric) ([]transition, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !sameWindow(p, b.window) {
+		b.window = newWindow(p)
+	}
+
+	before := b.status
+	transitions := b.halfOpen(p, now)
+	probe := false
+
+	switch {
+	case b.status.State == OpenState:
+		m.Error = ErrCircuitIsOpen
+	case b.status.State == ForcedOpenState:
+		m.Error = ErrCircuitIsForcedOpen
+	case b.status.State != HalfOpenState:
+	case p.HalfOpenMaxCalls > 0 && b.status.HalfOpenCalls >= p.HalfOpenMaxCalls:
+		m.Error = ErrTooManyHalfOpenCalls
+	default:
+		b.status.HalfOpenCalls++
+		probe = true
+	}
+	b.record(m, now)
+	b.save(before)
+
+	return transitions, probe
+}
+
+// complete records the outcome of a call, recording the state on the metric. It returns the
+// transitions made and the resulting status.
+func (b *breaker) complete(p Policy, now time.Time, probe bool, err error, m *Metric) ([]transition, CircuitBreaker) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	before := b.status
+	if probe {
+		b.status.HalfOpenCalls--
+	}
+	if b.status.State == DisabledState {
+		b.record(m, now)
+		return nil, b.status
+	}
+	if b.window != nil {
+		b.window.record(now, outcome{failed: err != nil, slow: m.Slow})
+	}
+	if err != nil {
+		b.status.ErrorCount++
+	}
+
+	var transitions []transition
+	reason := b.tripReason(p, now, err)
+
+	switch {
+	case reason == SlowCallRateTrip && err == nil:
+		transitions = b.open(p, now, reason, ErrSlowCallRateExceeded)
+	case reason != NoTrip:
+		transitions = b.open(p, now, reason, err)
+	case b.status.State == HalfOpenState && err == nil:
+		b.status.HalfOpenSuccesses++
+		if b.status.HalfOpenSuccesses >= p.HalfOpenSuccessThreshold {
+			transitions = b.close(p, now)
+		}
+	}
+	b.record(m, now)
+	b.save(before)
+
+	return transitions, b.status
+}
+
+func (b *breaker) tripReason(p Policy, now time.Time, err error) TripReason {
+	switch {
+	// A late outcome of a call made before the circuit was open, or a circuit forced into a state.
+	case b.status.State == OpenState || forced(b.status.State):
+		return NoTrip
+	case err != nil && !handledError(p, err):
+		return UnhandledErrorTrip
+	case b.status.State == HalfOpenState && err != nil:
+		return FailedProbeTrip
+	case b.status.State == HalfOpenState:
+		return NoTrip
+	case b.window == nil && err != nil && b.status.ErrorCount > p.ThresholdErrors:
+		return ErrorThresholdTrip
+	case b.window == nil:
+		return NoTrip
+	}
+
+	stats := b.window.stats(now)
+	switch {
+	case stats.calls < p.MinimumCalls:
+		return NoTrip
+	case stats.failureRate() >= p.FailureRateThreshold:
+		return FailureRateTrip
+	case p.SlowCallDuration > 0 && stats.slowCallRate() >= p.SlowCallRateThreshold:
+		return SlowCallRateTrip
+	default:
+		return NoTrip
+	}
+}
+
+// abandon frees the trial slot of a call which never completed (e.g. its command panicked).
+func (b *breaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.status.HalfOpenCalls--
+}
+
+// reset closes the circuit and clears its counters. Trial calls in progress are still accounted.
+func (b *breaker) reset(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	from := b.status.State
+	b.status = CircuitBreaker{HalfOpenCalls: b.status.HalfOpenCalls}
+	b.window = nil
+	b.store.Save(b.id, b.status)
+
+	if from != ClosedState {
+		b.transition(from, now, nil)
+	}
+}
+
+// force puts the circuit into a forced state, which only changes when the circuit is reset or forced again.
+func (b *breaker) force(state CircuitState, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	from := b.status.State
+	if from == state {
+		return
+	}
+
+	before := b.status
+	b.status.State = state
+	b.status.TripReason = NoTrip
+	b.status.HalfOpenSuccesses = 0
+	b.save(before)
+	b.transition(from, now, nil)
+}
+
+// remove detaches the breaker from the store, so that calls still in progress don't save it back.
+func (b *breaker) remove() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.removed = true
+}
+
+// save stores the status if it changed. Trial calls in progress aren't worth saving on their own.
+func (b *breaker) save(before CircuitBreaker) {
+	after := b.status
+	before.HalfOpenCalls, after.HalfOpenCalls = 0, 0
+
+	if !b.removed && before != after {
+		b.store.Save(b.id, b.status)
+	}
+}
+
+func (b *breaker) record(m *Metric, now time.Time) {
+	m.State = b.status.State
+	m.ErrorCount = b.status.ErrorCount
+	m.TripReason = b.status.TripReason
+	m.OpenDuration = b.status.OpenDuration
+
+	if b.window != nil {
+		stats := b.window.stats(now)
+		m.Calls = stats.calls
+		m.FailedCalls = stats.failed
+		m.FailureRate = stats.failureRate()
+		m.SlowCalls = stats.slow
+		m.SlowCallRate = stats.slowCallRate()
+	}
+}
+
+func (b *breaker) open(p Policy, now time.Time, reason TripReason, err error) []transition {
+	from := b.status.State
+	previous := b.status.OpenDuration
+	b.status.State = OpenState
+	b.status.TripReason = reason
+	b.status.TimeErrorOcurred = now
+	b.status.OpenDuration = p.ResetTimeout
+
+	// The wait grows while trial calls keep failing.
+	if from == HalfOpenState && previous > 0 {
+		b.status.OpenDuration = nextOpenDuration(p, previous)
+	}
+
+	return []transition{b.transition(from, now, err)}
+}
+
+func (b *breaker) close(p Policy, now time.Time) []transition {
+	from := b.status.State
+	b.status.State = ClosedState
+	b.status.ErrorCount = 0
+	b.status.TripReason = NoTrip
+	b.status.OpenDuration = 0
+	b.window = newWindow(p)
+
+	return []transition{b.transition(from, now, nil)}
+}
+
+func (b *breaker) halfOpen(p Policy, now time.Time) []transition {
+	if b.status.State != OpenState || now.Sub(b.status.TimeErrorOcurred) < b.openDuration(p) {
+		return nil
+	}
+
+	b.status.State = HalfOpenState
+	b.status.HalfOpenSuccesses = 0
+
+	return []transition{b.transition(OpenState, now, nil)}
+}
+
+// openDuration is how long the circuit stays open. Statuses saved without it wait ResetTimeout.
+func (b *breaker) openDuration(p Policy) time.Duration {
+	if b.status.OpenDuration > 0 {
+		return b.status.OpenDuration
+	}
+
+	return p.ResetTimeout
+}
+
+func nextOpenDuration(p Policy, previous time.Duration) time.Duration {
+	if p.ResetTimeoutMultiplier <= 1 {
+		return p.ResetTimeout
+	}
+
+	next := float64(previous) * p.ResetTimeoutMultiplier
+	if next >= math.MaxInt64 {
+		next = math.MaxInt64
+	}
+
+	d := time.Duration(next)
+	if p.MaxResetTimeout > 0 && d > p.MaxResetTimeout {
+		d = p.MaxResetTimeout
+	}
+
+	return d
+}
+
+// transition describes the change from the given state to the current one, and queues its event on
+// the registry, so that events are published in the order the transitions are made.
+func (b *breaker) transition(from CircuitState, now time.Time, cause error) transition {
+	e := Event{
+		ServiceID:  b.id,
+		From:       from,
+		To:         b.status.State,
+		Cause:      cause,
+		TripReason: b.status.TripReason,
+		Time:       now,
+		ErrorCount: b.status.ErrorCount,
+	}
+
+	if b.window != nil {
+		stats := b.window.stats(now)
+		e.Calls = stats.calls
+		e.FailedCalls = stats.failed
+		e.SlowCalls = stats.slow
+	}
+
+	b.registry.queue(e)
+
+	return transition{status: b.status, event: e}
+}
+
+func forced(state CircuitState) bool {
+	return state == ForcedOpenState || state == ForcedClosedState || state == DisabledState
+}
+
+// notify calls the policy hooks of the transitions, and then publishes the events queued on the registry.
+// Each hook receives its own copy of the status.
+func notify(r *Registry, p Policy, transitions []transition) {
+	for _, t := range transitions {
+		status := t.status
+
+		switch {
+		case status.State == OpenState && p.OnOpenCircuit != nil:
+			p.OnOpenCircuit(p, &status, t.event.Cause)
+		case status.State == HalfOpenState && p.OnHalfOpenCircuit != nil:
+			p.OnHalfOpenCircuit(p, &status)
+		case status.State == ClosedState && p.OnClosedCircuit != nil:
+			p.OnClosedCircuit(p, &status)
+		}
+	}
+
+	r.publish()
+}
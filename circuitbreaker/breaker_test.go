@@ -0,0 +1,84 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunConcurrentTransitions(t *testing.T) {
+	errTest := errors.New("err test")
+	var opens, halfOpens, closes atomic.Int64
+
+	p := circuitbreaker.New("concurrent-transitions")
+	p.ThresholdErrors = 3
+	p.ResetTimeout = circuitbreaker.MinResetTimeout
+	p.HalfOpenMaxCalls = 2
+	p.HalfOpenSuccessThreshold = 2
+	p.Errors = []error{errTest}
+	p.OnOpenCircuit = func(p circuitbreaker.Policy, status *circuitbreaker.CircuitBreaker, err error) {
+		assert.Equal(t, circuitbreaker.OpenState, status.State)
+		opens.Add(1)
+		status.State = circuitbreaker.ClosedState
+	}
+	p.OnHalfOpenCircuit = func(p circuitbreaker.Policy, status *circuitbreaker.CircuitBreaker) {
+		assert.Equal(t, circuitbreaker.HalfOpenState, status.State)
+		halfOpens.Add(1)
+		status.State = circuitbreaker.OpenState
+	}
+	p.OnClosedCircuit = func(p circuitbreaker.Policy, status *circuitbreaker.CircuitBreaker) {
+		assert.Equal(t, circuitbreaker.ClosedState, status.State)
+		closes.Add(1)
+		status.ErrorCount = 100
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 2000; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			policy := p
+			policy.Command = func() error {
+				if i%3 == 0 {
+					return errTest
+				}
+				return nil
+			}
+
+			for j := 0; j < 5; j++ {
+				_ = policy.Run(core.NewMetric())
+				_, _ = circuitbreaker.State(policy)
+				time.Sleep(time.Millisecond)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Brings the circuit back to closed.
+	time.Sleep(p.ResetTimeout)
+	p.Command = func() error { return nil }
+	for i := 0; i < p.HalfOpenSuccessThreshold; i++ {
+		_ = p.Run(core.NewMetric())
+	}
+
+	var status circuitbreaker.CircuitBreaker
+	p.BeforeCircuitBreaker = func(p circuitbreaker.Policy, s *circuitbreaker.CircuitBreaker) {
+		status = *s
+	}
+	_ = p.Run(core.NewMetric())
+
+	assert.Equal(t, circuitbreaker.ClosedState, status.State)
+	assert.Equal(t, 0, status.HalfOpenCalls)
+	assert.Equal(t, 0, status.ErrorCount)
+	assert.Greater(t, opens.Load(), int64(0))
+	assert.Greater(t, closes.Load(), int64(0))
+	assert.LessOrEqual(t, halfOpens.Load(), opens.Load())
+	assert.LessOrEqual(t, closes.Load(), halfOpens.Load())
+}
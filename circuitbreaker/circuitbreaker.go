@@ -1,10 +1,10 @@
 package circuitbreaker
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
-	"sync"
 	"time"
 
 	"github.com/aureliano/resiliencia/core"
@@ -17,12 +17,56 @@ var (
 	// Policy reset timeout is less than minimum required.
 	ErrResetTimeoutValidation = fmt.Errorf("reset timeout must be >= %dms", MinResetTimeout.Milliseconds())
 
+	// Policy reset timeout multiplier is less than minimum required.
+	ErrResetTimeoutMultiplierValidation = fmt.Errorf(
+		"reset timeout multiplier must be 0 or >= %d", MinResetTimeoutMultiplier)
+
+	// Policy max reset timeout is less than reset timeout.
+	ErrMaxResetTimeoutValidation = errors.New("max reset timeout must be 0 or >= reset timeout")
+
+	// Policy window size is less than minimum required.
+	ErrWindowSizeValidation = fmt.Errorf("window size must be >= %d", MinWindowSize)
+
+	// Policy window duration is less than minimum required.
+	ErrWindowDurationValidation = fmt.Errorf("window duration must be >= %s", MinWindowDuration)
+
+	// Policy failure rate threshold is out of range.
+	ErrFailureRateThresholdValidation = errors.New("failure rate threshold must be > 0 and <= 100")
+
+	// Policy minimum calls is less than minimum required.
+	ErrMinimumCallsValidation = fmt.Errorf("minimum calls must be >= %d", MinMinimumCalls)
+
+	// Policy slow call duration is less than minimum required.
+	ErrSlowCallDurationValidation = fmt.Errorf("slow call duration must be >= %d", MinSlowCallDuration)
+
+	// Policy slow call rate threshold is out of range.
+	ErrSlowCallRateThresholdValidation = errors.New("slow call rate threshold must be > 0 and <= 100")
+
+	// Policy sets a slow call duration without a sliding window.
+	ErrSlowCallWindowValidation = errors.New("slow call detection requires a sliding window")
+
+	// Policy half open max calls is less than minimum required.
+	ErrHalfOpenMaxCallsValidation = fmt.Errorf("half open max calls must be >= %d", MinHalfOpenMaxCalls)
+
+	// Policy half open success threshold is less than minimum required.
+	ErrHalfOpenSuccessThresholdValidation = fmt.Errorf(
+		"half open success threshold must be >= %d", MinHalfOpenSuccessThreshold)
+
+	// Circuit breaker is half open and all trial calls are already in progress.
+	ErrTooManyHalfOpenCalls = errors.New("too many calls while circuit is half open")
+
+	// Too many calls of the sliding window were slow.
+	ErrSlowCallRateExceeded = errors.New("slow call rate exceeded")
+
 	// No command nor wrapped policy is set.
 	ErrCommandRequired = errors.New("command nor wrapped policy provided")
 
 	// Circuit breaker is open and accesses to service are not allowed.
 	ErrCircuitIsOpen = errors.New("circuit is open")
 
+	// Circuit breaker was forced open and accesses to service are not allowed.
+	ErrCircuitIsForcedOpen = fmt.Errorf("%w by force", ErrCircuitIsOpen)
+
 	// No circuit breaker found to the given service.
 	ErrCircuitBreakerNotFound = errors.New("no circuit breaker found")
 )
@@ -38,9 +82,55 @@ type Policy struct {
 	// How long to wait to change the circuit state to HalfOpen.
 	ResetTimeout time.Duration
 
+	// Factor by which the wait is multiplied each time a trial call fails and the circuit is open again
+	// (zero means a fixed ResetTimeout). The wait goes back to ResetTimeout once the circuit is closed.
+	ResetTimeoutMultiplier float64
+
+	// Maximum wait until the circuit state changes to HalfOpen (zero means no limit).
+	MaxResetTimeout time.Duration
+
+	// How the circuit breaker decides to open the circuit (ErrorCountWindow if not set).
+	WindowType WindowType
+
+	// Number of calls kept by a CountBasedWindow.
+	WindowSize int
+
+	// How long calls are kept by a TimeBasedWindow.
+	WindowDuration time.Duration
+
+	// Failure rate (percentage) of the sliding window from which the circuit is open.
+	FailureRateThreshold float64
+
+	// Minimum number of calls in the sliding window before the failure rate is evaluated.
+	MinimumCalls int
+
+	// Duration from which a call is considered slow (zero disables slow call detection).
+	// It requires a sliding window.
+	SlowCallDuration time.Duration
+
+	// Slow call rate (percentage) of the sliding window from which the circuit is open.
+	SlowCallRateThreshold float64
+
+	// Number of trial calls permitted to run at the same time while the circuit is half open
+	// (zero means no limit). Calls in excess are rejected.
+	HalfOpenMaxCalls int
+
+	// Number of successful trial calls required to close the circuit (zero means one).
+	HalfOpenSuccessThreshold int
+
 	// Expected erros (not expected errors will open the circuit breaker immediately).
 	Errors []error
 
+	// Clock used to tell time and to time the reset out (real time if not set).
+	Clock core.Clock
+
+	// Registry which keeps the circuit breaker of the service (the default registry if not set).
+	Registry *Registry
+
+	// Whether the policy returns its bare errors (e.g. ErrCircuitIsOpen), leaving the error of the
+	// command supplier or of the wrapped policy only in the metric, as former versions did.
+	SwallowErrors bool
+
 	// Function called before execution.
 	BeforeCircuitBreaker func(p Policy, status *CircuitBreaker)
 
@@ -59,6 +149,9 @@ type Policy struct {
 	// The command supplier.
 	Command core.Command
 
+	// The context-aware command supplier. It takes precedence over Command.
+	CommandContext core.CommandContext
+
 	// Any policy that will be wrapped by this one.
 	Policy core.PolicySupplier
 }
@@ -85,11 +178,38 @@ type Metric struct {
 
 	// How many errors occurred.
 	ErrorCount int
+
+	// Number of calls in the sliding window.
+	Calls int
+
+	// Number of failed calls in the sliding window.
+	FailedCalls int
+
+	// Failure rate (percentage) of the sliding window.
+	FailureRate float64
+
+	// Number of slow calls in the sliding window.
+	SlowCalls int
+
+	// Slow call rate (percentage) of the sliding window.
+	SlowCallRate float64
+
+	// Whether this call was slow.
+	Slow bool
+
+	// Why the circuit was open.
+	TripReason TripReason
+
+	// How long the circuit is open until it changes to HalfOpen.
+	OpenDuration time.Duration
 }
 
 // CircuitState is the circuit breaker state.
 type CircuitState int
 
+// TripReason is the condition which opened the circuit.
+type TripReason int
+
 // CircuitBreaker is the abstraction of a circuit breaker policy.
 type CircuitBreaker struct {
 	// Circuit breaker state (closed, open or half open).
@@ -100,11 +220,18 @@ type CircuitBreaker struct {
 
 	// How many errors occurred.
 	ErrorCount int
-}
 
-type circuitBreakerCache struct {
-	mu    sync.Mutex
-	cache map[string]*CircuitBreaker
+	// Why the circuit was open.
+	TripReason TripReason
+
+	// Number of trial calls in progress.
+	HalfOpenCalls int
+
+	// Number of successful trial calls since the circuit is half open.
+	HalfOpenSuccesses int
+
+	// How long the circuit is open until it changes to HalfOpen.
+	OpenDuration time.Duration
 }
 
 const (
@@ -117,95 +244,210 @@ const (
 	// Indicates that circuit breaker is in a state between healthy and unhealthy.
 	HalfOpenState = CircuitState(2)
 
+	// Indicates that circuit breaker was forced open and no requests will be serviced until it is reset.
+	ForcedOpenState = CircuitState(3)
+
+	// Indicates that circuit breaker was forced closed and requests will be fulfilled until it is reset,
+	// whatever errors occur. Calls are still recorded.
+	ForcedClosedState = CircuitState(4)
+
+	// Indicates that circuit breaker was disabled and requests will be fulfilled without being recorded
+	// until it is reset.
+	DisabledState = CircuitState(5)
+
+	// Indicates that the circuit wasn't open.
+	NoTrip = TripReason(0)
+
+	// Indicates that the circuit was open because errors exceeded ThresholdErrors.
+	ErrorThresholdTrip = TripReason(1)
+
+	// Indicates that the circuit was open because the failure rate reached FailureRateThreshold.
+	FailureRateTrip = TripReason(2)
+
+	// Indicates that the circuit was open because the slow call rate reached SlowCallRateThreshold.
+	SlowCallRateTrip = TripReason(3)
+
+	// Indicates that the circuit was open because an error isn't in Errors policy field.
+	UnhandledErrorTrip = TripReason(4)
+
+	// Indicates that the circuit was open again because a call failed while it was half open.
+	FailedProbeTrip = TripReason(5)
+
 	// Minimum expected to be set on ResetTimeout field of a circuit breaker policy.
 	MinResetTimeout = time.Millisecond * 5
 
 	// Minimum expected to be set on ThresholdErrors field of a circuit breaker policy.
 	MinThresholdErrors = 0
-)
 
-var cbCache = newCache()
+	// Minimum expected to be set on ResetTimeoutMultiplier field of a circuit breaker policy (unless zero).
+	MinResetTimeoutMultiplier = 1
+
+	// Minimum expected to be set on WindowSize field of a circuit breaker policy.
+	MinWindowSize = 1
+
+	// Minimum expected to be set on WindowDuration field of a circuit breaker policy.
+	MinWindowDuration = time.Second
+
+	// Minimum expected to be set on MinimumCalls field of a circuit breaker policy.
+	MinMinimumCalls = 1
+
+	// Minimum expected to be set on SlowCallDuration field of a circuit breaker policy.
+	MinSlowCallDuration = 0
+
+	// Minimum expected to be set on HalfOpenMaxCalls field of a circuit breaker policy.
+	MinHalfOpenMaxCalls = 0
+
+	// Minimum expected to be set on HalfOpenSuccessThreshold field of a circuit breaker policy.
+	MinHalfOpenSuccessThreshold = 0
 
-// State queries for a circuit breaker state by service id.
+	// Default number of calls kept by a CountBasedWindow.
+	DefaultWindowSize = 100
+
+	// Default duration of a TimeBasedWindow.
+	DefaultWindowDuration = time.Minute
+
+	// Default failure rate threshold of a sliding window.
+	DefaultFailureRateThreshold = 50.0
+
+	// Default minimum number of calls in a sliding window.
+	DefaultMinimumCalls = 10
+
+	// Default slow call rate threshold of a sliding window.
+	DefaultSlowCallRateThreshold = 100.0
+
+	// Default number of trial calls permitted to run at the same time while the circuit is half open.
+	DefaultHalfOpenMaxCalls = 1
+
+	// Default number of successful trial calls required to close the circuit.
+	DefaultHalfOpenSuccessThreshold = 1
+)
+
+// State queries for a circuit breaker state by service id in the registry of the policy.
 //
 // Returns the state of a circuit breaker or an error if no circuit breaker is found.
 //
 // Possible error(s): ErrCircuitBreakerNotFound.
 func State(p Policy) (CircuitState, error) {
-	cbCache.mu.Lock()
-	defer cbCache.mu.Unlock()
+	return registryOf(p).State(p)
+}
 
-	cb := cbCache.cache[p.ServiceID]
-	if cb == nil {
-		return -1, ErrCircuitBreakerNotFound
-	}
-	setInitialState(p, cb)
+// ForceOpen forces the circuit breaker of a service in the default registry open (see Registry.ForceOpen).
+func ForceOpen(serviceID string) {
+	defaultRegistry.ForceOpen(serviceID)
+}
 
-	return cb.State, nil
+// ForceClosed forces the circuit breaker of a service in the default registry closed (see Registry.ForceClosed).
+func ForceClosed(serviceID string) {
+	defaultRegistry.ForceClosed(serviceID)
+}
+
+// Disable disables the circuit breaker of a service in the default registry (see Registry.Disable).
+func Disable(serviceID string) {
+	defaultRegistry.Disable(serviceID)
+}
+
+// Reset closes the circuit breaker of a service in the default registry and clears its counters.
+//
+// Possible error(s): ErrCircuitBreakerNotFound.
+func Reset(serviceID string) error {
+	return defaultRegistry.Reset(serviceID)
 }
 
 // New creates a circuit breaker policy with default values set.
 func New(serviceID string) Policy {
 	return Policy{
-		ServiceID:       serviceID,
-		ThresholdErrors: MinThresholdErrors,
-		ResetTimeout:    time.Second * 1,
+		ServiceID:             serviceID,
+		ThresholdErrors:       MinThresholdErrors,
+		ResetTimeout:          time.Second * 1,
+		WindowSize:            DefaultWindowSize,
+		WindowDuration:        DefaultWindowDuration,
+		FailureRateThreshold:  DefaultFailureRateThreshold,
+		MinimumCalls:          DefaultMinimumCalls,
+		SlowCallRateThreshold: DefaultSlowCallRateThreshold,
+
+		HalfOpenMaxCalls:         DefaultHalfOpenMaxCalls,
+		HalfOpenSuccessThreshold: DefaultHalfOpenSuccessThreshold,
 	}
 }
 
 // Run executes a command supplier or a wrapped policy in a circuit breaker.
 //
-// Possible error(s): ErrThresholdValidation, ErrResetTimeoutValidation, ErrCommandRequiredErrCircuitIsOpen.
+// Possible error(s): ErrThresholdValidation, ErrResetTimeoutValidation, ErrResetTimeoutMultiplierValidation,
+// ErrMaxResetTimeoutValidation, ErrWindowSizeValidation,
+// ErrWindowDurationValidation, ErrFailureRateThresholdValidation, ErrMinimumCallsValidation,
+// ErrSlowCallDurationValidation, ErrSlowCallRateThresholdValidation, ErrSlowCallWindowValidation,
+// ErrHalfOpenMaxCallsValidation, ErrHalfOpenSuccessThresholdValidation, ErrCommandRequired,
+// ErrCircuitIsOpen, ErrCircuitIsForcedOpen, ErrTooManyHalfOpenCalls (see RunContext).
 func (p Policy) Run(metric core.Metric) error {
+	return p.RunContext(context.Background(), metric)
+}
+
+// RunContext executes a command supplier or a wrapped policy in a circuit breaker bound to a context.
+// The context is passed on to the command supplier or to the wrapped policy.
+//
+// Validation errors are returned as they are. Any other error is a *core.PolicyError wrapping the
+// rejection error (ErrCircuitIsOpen, ErrCircuitIsForcedOpen or ErrTooManyHalfOpenCalls) or the error
+// of the command supplier or of the wrapped policy, unless SwallowErrors is set.
+//
+// Possible error(s): ErrThresholdValidation, ErrResetTimeoutValidation, ErrResetTimeoutMultiplierValidation,
+// ErrMaxResetTimeoutValidation, ErrWindowSizeValidation,
+// ErrWindowDurationValidation, ErrFailureRateThresholdValidation, ErrMinimumCallsValidation,
+// ErrSlowCallDurationValidation, ErrSlowCallRateThresholdValidation, ErrSlowCallWindowValidation,
+// ErrHalfOpenMaxCallsValidation, ErrHalfOpenSuccessThresholdValidation, ErrCommandRequired,
+// ErrCircuitIsOpen, ErrCircuitIsForcedOpen, ErrTooManyHalfOpenCalls.
+func (p Policy) RunContext(ctx context.Context, metric core.Metric) error {
 	if err := validate(p); err != nil {
 		return err
 	}
 
-	cbCache.mu.Lock()
-	cb := cbCache.cache[p.ServiceID]
-	if cb == nil {
-		cb = new(CircuitBreaker)
-		cbCache.cache[p.ServiceID] = cb
-	}
-	cbCache.mu.Unlock()
-
-	m := Metric{ID: p.ServiceID, StartedAt: time.Now()}
+	r := registryOf(p)
+	b := r.get(p.ServiceID)
+	clock := core.ClockOrDefault(p.Clock)
+	m := Metric{ID: p.ServiceID, StartedAt: clock.Now()}
 	if p.BeforeCircuitBreaker != nil {
-		p.BeforeCircuitBreaker(p, cb)
+		status := b.snapshot()
+		p.BeforeCircuitBreaker(p, &status)
 	}
 
-	setInitialState(p, cb)
-	m.State = cb.State
-	m.ErrorCount = cb.ErrorCount
+	transitions, probe := b.admit(p, clock.Now(), &m)
+	notify(r, p, transitions)
 
-	if cb.State == OpenState {
+	if m.Error != nil {
 		m.Status = 1
-		m.Error = ErrCircuitIsOpen
-		m.FinishedAt = time.Now()
+		m.FinishedAt = clock.Now()
 		metric[reflect.TypeOf(m).String()] = m
 
-		return ErrCircuitIsOpen
+		return failure(p, m.Error, nil)
 	}
 
-	err := execute(p, metric)
+	completed := false
+	defer func() {
+		if probe && !completed {
+			b.abandon()
+		}
+	}()
+
+	started := clock.Now()
+	err := execute(ctx, p, metric)
 	err = pickError(err, metric)
+	m.Slow = p.SlowCallDuration > 0 && clock.Now().Sub(started) >= p.SlowCallDuration
 
 	if err != nil {
 		m.Error = err
 		m.Status = 1
 	}
 
-	setPostState(p, cb, err)
-	m.State = cb.State
-	m.ErrorCount = cb.ErrorCount
+	transitions, status := b.complete(p, clock.Now(), probe, err, &m)
+	completed = true
+	notify(r, p, transitions)
 
 	if p.AfterCircuitBreaker != nil {
-		p.AfterCircuitBreaker(p, cb, err)
+		p.AfterCircuitBreaker(p, &status, err)
 	}
-	m.FinishedAt = time.Now()
+	m.FinishedAt = clock.Now()
 	metric[reflect.TypeOf(m).String()] = m
 
-	return nil
+	return failure(p, nil, err)
 }
 
 // WithCommand encapsulates this policy in a new policy with given command supplier.
@@ -214,65 +456,39 @@ func (p Policy) WithCommand(command core.Command) core.PolicySupplier {
 	return p
 }
 
+// WithCommandContext encapsulates this policy in a new policy with given context-aware command supplier.
+func (p Policy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.CommandContext = command
+	return p
+}
+
 // WithPolicy encapsulates this policy in a new policy with wrapped policy.
 func (p Policy) WithPolicy(policy core.PolicySupplier) core.PolicySupplier {
 	p.Policy = policy
 	return p
 }
 
-func execute(p Policy, metric core.Metric) error {
-	if p.Command != nil && p.Policy == nil {
-		return p.Command()
-	}
-
-	return p.Policy.Run(metric)
-}
-
-func setInitialState(p Policy, cb *CircuitBreaker) {
-	circuitIsOpen := cb.State == OpenState
-	shouldChangeToHalfOpen := time.Since(cb.TimeErrorOcurred) >= p.ResetTimeout
-
-	if circuitIsOpen && shouldChangeToHalfOpen {
-		halfOpenCircuit(p, cb)
-	}
-}
-
-func setPostState(p Policy, cb *CircuitBreaker, err error) {
-	if err != nil {
-		expectedError := handledError(p, err)
-		cb.ErrorCount++
-
-		if cb.ErrorCount > p.ThresholdErrors || !expectedError || (cb.State == HalfOpenState) {
-			openCircuit(p, cb, err)
+func execute(ctx context.Context, p Policy, metric core.Metric) error {
+	if p.Policy == nil {
+		if p.CommandContext != nil {
+			return p.CommandContext(ctx)
 		}
-	} else if cb.State == HalfOpenState {
-		closeCircuit(p, cb)
-	}
-}
-
-func openCircuit(p Policy, cb *CircuitBreaker, err error) {
-	cb.State = OpenState
-	cb.TimeErrorOcurred = time.Now()
 
-	if p.OnOpenCircuit != nil {
-		p.OnOpenCircuit(p, cb, err)
+		return p.Command()
 	}
-}
-
-func closeCircuit(p Policy, cb *CircuitBreaker) {
-	cb.State = ClosedState
-	cb.ErrorCount = 0
 
-	if p.OnClosedCircuit != nil {
-		p.OnClosedCircuit(p, cb)
-	}
+	return core.RunPolicy(ctx, p.Policy, metric)
 }
 
-func halfOpenCircuit(p Policy, cb *CircuitBreaker) {
-	cb.State = HalfOpenState
-
-	if p.OnHalfOpenCircuit != nil {
-		p.OnHalfOpenCircuit(p, cb)
+// failure wraps the rejection error or the error of the execution, unless errors are swallowed.
+func failure(p Policy, err, cause error) error {
+	switch {
+	case err == nil && cause == nil:
+		return nil
+	case p.SwallowErrors:
+		return err
+	default:
+		return &core.PolicyError{Policy: "circuitbreaker", ServiceID: p.ServiceID, Err: err, Cause: cause}
 	}
 }
 
@@ -286,15 +502,42 @@ func validate(p Policy) error {
 		return ErrThresholdValidation
 	case p.ResetTimeout < MinResetTimeout:
 		return ErrResetTimeoutValidation
-	case p.Command == nil && p.Policy == nil:
+	case p.ResetTimeoutMultiplier != 0 && p.ResetTimeoutMultiplier < MinResetTimeoutMultiplier:
+		return ErrResetTimeoutMultiplierValidation
+	case p.MaxResetTimeout != 0 && p.MaxResetTimeout < p.ResetTimeout:
+		return ErrMaxResetTimeoutValidation
+	case p.HalfOpenMaxCalls < MinHalfOpenMaxCalls:
+		return ErrHalfOpenMaxCallsValidation
+	case p.HalfOpenSuccessThreshold < MinHalfOpenSuccessThreshold:
+		return ErrHalfOpenSuccessThresholdValidation
+	case p.Command == nil && p.CommandContext == nil && p.Policy == nil:
 		return ErrCommandRequired
 	default:
-		return nil
+		return validateWindow(p)
 	}
 }
 
-func newCache() *circuitBreakerCache {
-	return &circuitBreakerCache{cache: make(map[string]*CircuitBreaker)}
+func validateWindow(p Policy) error {
+	switch {
+	case p.SlowCallDuration < MinSlowCallDuration:
+		return ErrSlowCallDurationValidation
+	case p.WindowType == ErrorCountWindow && p.SlowCallDuration > 0:
+		return ErrSlowCallWindowValidation
+	case p.WindowType == ErrorCountWindow:
+		return nil
+	case p.WindowType == CountBasedWindow && p.WindowSize < MinWindowSize:
+		return ErrWindowSizeValidation
+	case p.WindowType == TimeBasedWindow && p.WindowDuration < MinWindowDuration:
+		return ErrWindowDurationValidation
+	case p.FailureRateThreshold <= 0 || p.FailureRateThreshold > 100:
+		return ErrFailureRateThresholdValidation
+	case p.MinimumCalls < MinMinimumCalls:
+		return ErrMinimumCallsValidation
+	case p.SlowCallDuration > 0 && (p.SlowCallRateThreshold <= 0 || p.SlowCallRateThreshold > 100):
+		return ErrSlowCallRateThresholdValidation
+	default:
+		return nil
+	}
 }
 
 func pickError(err error, metric core.MetricRecorder) error {
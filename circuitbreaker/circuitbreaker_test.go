@@ -1,6 +1,7 @@
 package circuitbreaker_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -73,10 +74,12 @@ func TestCircuitBreakerState(t *testing.T) {
 	assert.EqualValues(t, -1, state)
 	assert.Equal(t, err, circuitbreaker.ErrCircuitBreakerNotFound)
 
+	clock := core.NewFakeClock(time.Now())
 	p := circuitbreaker.Policy{
 		ServiceID:       "service-name",
 		ThresholdErrors: 1,
 		ResetTimeout:    time.Millisecond * 50,
+		Clock:           clock,
 	}
 
 	p.Command = func() error { return nil }
@@ -90,12 +93,12 @@ func TestCircuitBreakerState(t *testing.T) {
 	p.Command = func() error { return fmt.Errorf("any") }
 	metric = core.NewMetric()
 	err = p.Run(metric)
-	assert.Nil(t, err)
+	assert.EqualError(t, err, "circuitbreaker (service-name): any")
 	state, err = circuitbreaker.State(p)
 	assert.Nil(t, err)
 	assert.EqualValues(t, circuitbreaker.OpenState, state)
 
-	time.Sleep(time.Millisecond * 50)
+	clock.Advance(time.Millisecond * 50)
 	state, err = circuitbreaker.State(p)
 	assert.Nil(t, err)
 	assert.EqualValues(t, circuitbreaker.HalfOpenState, state)
@@ -114,6 +117,11 @@ func TestNew(t *testing.T) {
 	assert.Equal(t, "backend-service-name", p.ServiceID)
 	assert.Equal(t, 0, p.ThresholdErrors)
 	assert.Equal(t, time.Second*1, p.ResetTimeout)
+	assert.Equal(t, circuitbreaker.ErrorCountWindow, p.WindowType)
+	assert.Equal(t, circuitbreaker.DefaultWindowSize, p.WindowSize)
+	assert.Equal(t, circuitbreaker.DefaultWindowDuration, p.WindowDuration)
+	assert.Equal(t, circuitbreaker.DefaultFailureRateThreshold, p.FailureRateThreshold)
+	assert.Equal(t, circuitbreaker.DefaultMinimumCalls, p.MinimumCalls)
 }
 
 func TestRunValidatePolicyThresholdErrors(t *testing.T) {
@@ -169,7 +177,7 @@ func TestRunCommandCircuitIsOpen(t *testing.T) {
 	err := p.Run(r)
 	i := r[reflect.TypeOf(circuitbreaker.Metric{}).String()]
 	m, _ := i.(circuitbreaker.Metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest)
 
 	assert.Equal(t, "backend-service-name-1", m.ID)
 	assert.Equal(t, 1, m.Status)
@@ -217,7 +225,7 @@ func TestRunCommandCircuitHalfOpenSetToClosed(t *testing.T) {
 	err := p.Run(r)
 	i := r[reflect.TypeOf(circuitbreaker.Metric{}).String()]
 	m, _ := i.(circuitbreaker.Metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest)
 
 	state, _ = circuitbreaker.State(p)
 	assert.EqualValues(t, circuitbreaker.OpenState, state)
@@ -276,9 +284,12 @@ func TestRunCommandCircuitHalfOpenSetToOpen(t *testing.T) {
 	errTest := errors.New("err test")
 
 	var state circuitbreaker.CircuitState
+	clock := core.NewFakeClock(time.Now())
 	p := circuitbreaker.Policy{
 		ServiceID:            "backend-service-name-2",
 		ThresholdErrors:      1,
+		Clock:                clock,
+		Registry:             circuitbreaker.NewRegistry(nil),
 		ResetTimeout:         time.Millisecond * 300,
 		Errors:               []error{errTest},
 		BeforeCircuitBreaker: func(p circuitbreaker.Policy, status *circuitbreaker.CircuitBreaker) {},
@@ -291,7 +302,7 @@ func TestRunCommandCircuitHalfOpenSetToOpen(t *testing.T) {
 	p.Command = func() error { return errTest }
 	r := core.NewMetric()
 	err := p.Run(r)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest)
 	assert.False(t, r.Success())
 
 	state, _ = circuitbreaker.State(p)
@@ -299,7 +310,7 @@ func TestRunCommandCircuitHalfOpenSetToOpen(t *testing.T) {
 
 	r = core.NewMetric()
 	err = p.Run(r)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest)
 	assert.False(t, r.Success())
 	state, _ = circuitbreaker.State(p)
 	assert.EqualValues(t, circuitbreaker.OpenState, state)
@@ -310,14 +321,18 @@ func TestRunCommandCircuitHalfOpenSetToOpen(t *testing.T) {
 	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitIsOpen)
 	assert.EqualValues(t, circuitbreaker.OpenState, state)
 
-	time.Sleep(time.Millisecond * 350)
+	clock.Advance(time.Millisecond * 299)
+	state, _ = circuitbreaker.State(p)
+	assert.EqualValues(t, circuitbreaker.OpenState, state)
+
+	clock.Advance(time.Millisecond)
 	state, _ = circuitbreaker.State(p)
 	assert.EqualValues(t, circuitbreaker.HalfOpenState, state)
 
 	p.Command = func() error { return errTest }
 	r = core.NewMetric()
 	err = p.Run(r)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest)
 
 	state, _ = circuitbreaker.State(p)
 	assert.EqualValues(t, circuitbreaker.OpenState, state)
@@ -344,7 +359,7 @@ func TestRunCommandHandledErrors(t *testing.T) {
 	err := p.Run(r)
 	i := r[reflect.TypeOf(circuitbreaker.Metric{}).String()]
 	m, _ := i.(circuitbreaker.Metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest1)
 	assert.EqualValues(t, circuitbreaker.ClosedState, state)
 
 	assert.Equal(t, "backend-service-name-3", m.ID)
@@ -360,7 +375,7 @@ func TestRunCommandHandledErrors(t *testing.T) {
 	err = p.Run(r)
 	i = r[reflect.TypeOf(circuitbreaker.Metric{}).String()]
 	m, _ = i.(circuitbreaker.Metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest2)
 	assert.EqualValues(t, circuitbreaker.ClosedState, state)
 
 	assert.Equal(t, "backend-service-name-3", m.ID)
@@ -393,7 +408,7 @@ func TestRunCommandUnhandledError(t *testing.T) {
 	err := p.Run(r)
 	i := r[reflect.TypeOf(circuitbreaker.Metric{}).String()]
 	m, _ := i.(circuitbreaker.Metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest1)
 	assert.EqualValues(t, circuitbreaker.ClosedState, state)
 
 	assert.Equal(t, "backend-service-name-4", m.ID)
@@ -409,7 +424,7 @@ func TestRunCommandUnhandledError(t *testing.T) {
 	err = p.Run(r)
 	i = r[reflect.TypeOf(circuitbreaker.Metric{}).String()]
 	m, _ = i.(circuitbreaker.Metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest2)
 	assert.EqualValues(t, circuitbreaker.OpenState, state)
 
 	assert.Equal(t, "backend-service-name-4", m.ID)
@@ -421,6 +436,26 @@ func TestRunCommandUnhandledError(t *testing.T) {
 	assert.False(t, m.Success())
 }
 
+func TestRunContextCommand(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "backend-service-context")
+
+	p := circuitbreaker.New("backend-service-context")
+	p.CommandContext = func(ctx context.Context) error {
+		assert.Equal(t, "backend-service-context", ctx.Value(key{}))
+		return nil
+	}
+
+	r := core.NewMetric()
+	err := p.RunContext(ctx, r)
+	i := r[reflect.TypeOf(circuitbreaker.Metric{}).String()]
+	m, _ := i.(circuitbreaker.Metric)
+
+	assert.Nil(t, err)
+	assert.EqualValues(t, circuitbreaker.ClosedState, m.State)
+	assert.True(t, m.Success())
+}
+
 func TestRunPolicyCircuitIsOpen(t *testing.T) {
 	errTest := errors.New("err test")
 
@@ -448,7 +483,7 @@ func TestRunPolicyCircuitIsOpen(t *testing.T) {
 	metric := core.NewMetric()
 	metric[reflect.TypeOf(mockMetric).String()] = mockMetric
 	err := cbPolicy.Run(metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest)
 
 	r := metric[reflect.TypeOf(Metric{}).String()]
 	childMetric, _ := r.(Metric)
@@ -518,7 +553,7 @@ func TestRunPolicyCircuitHalfOpenSetToClosed(t *testing.T) {
 	metric := core.NewMetric()
 	metric[reflect.TypeOf(mockMetric).String()] = mockMetric
 	err := cbPolicy.Run(metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest)
 
 	state, _ := circuitbreaker.State(cbPolicy)
 	assert.EqualValues(t, circuitbreaker.OpenState, state)
@@ -626,7 +661,7 @@ func TestRunPolicyHandledErrors(t *testing.T) {
 	metric := core.NewMetric()
 	metric[reflect.TypeOf(mockMetric).String()] = mockMetric
 	err := cbPolicy.Run(metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest1)
 
 	state, _ := circuitbreaker.State(cbPolicy)
 	assert.EqualValues(t, circuitbreaker.ClosedState, state)
@@ -668,7 +703,7 @@ func TestRunPolicyHandledErrors(t *testing.T) {
 	metric[reflect.TypeOf(mockMetric).String()] = mockMetric
 	cbPolicy.Policy = policy
 	err = cbPolicy.Run(metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest2)
 
 	r = metric[reflect.TypeOf(circuitbreaker.Metric{}).String()]
 	cbMetric, _ = r.(circuitbreaker.Metric)
@@ -714,7 +749,7 @@ func TestRunPolicyUnhandledError(t *testing.T) {
 	metric := core.NewMetric()
 	metric[reflect.TypeOf(mockMetric).String()] = mockMetric
 	err := cbPolicy.Run(metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest1)
 
 	state, _ := circuitbreaker.State(cbPolicy)
 	assert.EqualValues(t, circuitbreaker.ClosedState, state)
@@ -752,7 +787,7 @@ func TestRunPolicyUnhandledError(t *testing.T) {
 	metric[reflect.TypeOf(mockMetric).String()] = mockMetric
 	cbPolicy.Policy = policy
 	err = cbPolicy.Run(metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest2)
 
 	r = metric[reflect.TypeOf(circuitbreaker.Metric{}).String()]
 	cbMetric, _ = r.(circuitbreaker.Metric)
@@ -769,6 +804,45 @@ func TestRunPolicyUnhandledError(t *testing.T) {
 	assert.EqualValues(t, circuitbreaker.OpenState, state)
 }
 
+func TestRunPolicyError(t *testing.T) {
+	errTest := errors.New("service unavailable")
+	p := circuitbreaker.New("service-id")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.ThresholdErrors = 0
+	p.Errors = []error{errTest}
+	p.Command = func() error { return errTest }
+
+	err := p.Run(core.NewMetric())
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "circuitbreaker", perr.Policy)
+	assert.Equal(t, "service-id", perr.ServiceID)
+	assert.ErrorIs(t, err, errTest)
+
+	err = p.Run(core.NewMetric())
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitIsOpen)
+	assert.NotErrorIs(t, err, errTest)
+}
+
+func TestRunSwallowErrors(t *testing.T) {
+	errTest := errors.New("service unavailable")
+	p := circuitbreaker.New("service-id")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.ThresholdErrors = 0
+	p.Errors = []error{errTest}
+	p.SwallowErrors = true
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(circuitbreaker.Metric{}).String()].(circuitbreaker.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, errTest, m.Error)
+	assert.Equal(t, circuitbreaker.ErrCircuitIsOpen, p.Run(core.NewMetric()))
+}
+
 func TestWithCommand(t *testing.T) {
 	p := circuitbreaker.New("id")
 	assert.Nil(t, p.Command)
@@ -778,6 +852,15 @@ func TestWithCommand(t *testing.T) {
 	assert.NotNil(t, p.Command)
 }
 
+func TestWithCommandContext(t *testing.T) {
+	p := circuitbreaker.New("id")
+	assert.Nil(t, p.CommandContext)
+
+	np := p.WithCommandContext(func(ctx context.Context) error { return nil })
+	p, _ = np.(circuitbreaker.Policy)
+	assert.NotNil(t, p.CommandContext)
+}
+
 func TestWithPolicy(t *testing.T) {
 	p := circuitbreaker.New("id")
 	assert.Nil(t, p.Policy)
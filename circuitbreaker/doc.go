@@ -65,6 +65,174 @@ There are two ways to run a command under a policy. Using a Command supplier
 	// Prints Circuit Breaker metric.
 	fmt.Println(cbMetric)
 
+# Errors
+
+A rejected call makes the policy return a *core.PolicyError wrapping ErrCircuitIsOpen, ErrCircuitIsForcedOpen
+or ErrTooManyHalfOpenCalls. A call which fails makes it return a *core.PolicyError wrapping the error of the
+command supplier (or of the wrapped policy). SwallowErrors brings back the former behavior, where rejection
+errors are returned as they are and failed calls are only recorded in the metric (see core.PolicyError).
+
+	if errors.Is(err, circuitbreaker.ErrCircuitIsOpen) {
+		// The call was rejected.
+		...
+	}
+
+# Reset timeout
+
+An open circuit becomes half open once ResetTimeout elapses. A service which stays down would be probed every
+ResetTimeout forever, so the wait may grow instead: each time a trial call fails, it is multiplied by
+ResetTimeoutMultiplier, up to MaxResetTimeout. It goes back to ResetTimeout once the circuit is closed.
+The current wait is the OpenDuration of both CircuitBreaker and Metric.
+
+	p := circuitbreaker.New("service-id")
+	p.ResetTimeout = time.Second * 5
+	p.ResetTimeoutMultiplier = 2
+	p.MaxResetTimeout = time.Minute * 5
+
+# Sliding window
+
+By default the circuit opens once more than ThresholdErrors errors occurred since it was last closed, however
+spread over time they are. Set WindowType to evaluate the failure rate of recent calls instead: the last
+WindowSize calls (CountBasedWindow) or the calls made within the last WindowDuration (TimeBasedWindow).
+The circuit opens when the failure rate reaches FailureRateThreshold percent, as long as the window holds
+at least MinimumCalls calls. The window is emptied whenever the circuit is closed.
+
+	p := circuitbreaker.New("service-id")
+	p.WindowType = circuitbreaker.TimeBasedWindow
+	p.WindowDuration = time.Second * 30
+	p.FailureRateThreshold = 50
+	p.MinimumCalls = 20
+
+The metric reports the Calls, FailedCalls and FailureRate of the window.
+
+# Slow calls
+
+A dependency which answers every call, but too slowly, may trip the circuit as well. Calls which take
+SlowCallDuration or longer are counted as slow by the sliding window, and the circuit opens when the slow
+call rate reaches SlowCallRateThreshold percent. Slow call detection requires a sliding window.
+
+	p := circuitbreaker.New("service-id")
+	p.WindowType = circuitbreaker.CountBasedWindow
+	p.SlowCallDuration = time.Second * 5
+	p.SlowCallRateThreshold = 80
+
+The metric reports the SlowCalls and SlowCallRate of the window. Both CircuitBreaker and Metric tell the
+TripReason of an open circuit, and OnOpenCircuit receives ErrSlowCallRateExceeded when slow calls tripped it.
+
+# Half open
+
+Once ResetTimeout elapses, the circuit is half open and trial calls are let through to find out whether the
+service recovered. At most HalfOpenMaxCalls trial calls run at the same time (New sets one), further calls
+fail fast with ErrTooManyHalfOpenCalls. The circuit is closed after HalfOpenSuccessThreshold successful trial
+calls and open again as soon as a trial call fails.
+
+	p := circuitbreaker.New("service-id")
+	p.HalfOpenMaxCalls = 3
+	p.HalfOpenSuccessThreshold = 5
+
+# Registry
+
+Circuit breakers are kept by a Registry. Policies sharing a registry and a ServiceID share a circuit breaker,
+so unrelated components (or tests) should use registries of their own. Policies without a Registry use the
+default one (see DefaultRegistry).
+
+	registry := circuitbreaker.NewRegistry(nil)
+
+	p := circuitbreaker.New("service-id")
+	p.Registry = registry
+	...
+
+	fmt.Println(registry.List())
+	status, err := registry.Status("service-id")
+	err = registry.Reset("service-id")
+	err = registry.Remove("service-id")
+
+A registry saves the status of a service to its StateStore whenever it changes, and loads it back the first
+time the service is used. NewRegistry defaults to a MemoryStore.
+
+# Manual override
+
+Operators may take over the circuit breaker of a service. A circuit forced open (ForcedOpenState) rejects
+every call with ErrCircuitIsForcedOpen, which is an ErrCircuitIsOpen. A circuit forced closed
+(ForcedClosedState) lets every call through and keeps recording them, but never opens. A disabled circuit
+(DisabledState) lets every call through without recording them. A forced state only changes when the
+circuit is reset (or forced into another state), and it is saved to the state store like any other state.
+
+	registry.ForceOpen("service-id")
+	registry.ForceClosed("service-id")
+	registry.Disable("service-id")
+	err := registry.Reset("service-id")
+
+The package functions ForceOpen, ForceClosed, Disable and Reset act on the default registry. Forced states
+are reported by the metric and by the status which listeners receive.
+
+# Events
+
+A registry delivers an Event for every state change of every circuit breaker it keeps, manual ones included,
+so a single component can log and alert on all of them. Events carry the service id, the states before and
+after the change, the error which opened the circuit, when it happened and the counters of the circuit.
+
+	unsubscribe := registry.Subscribe(func(e circuitbreaker.Event) {
+		log.Printf("circuit %s: %d -> %d (%v)", e.ServiceID, e.From, e.To, e.Cause)
+	})
+	defer unsubscribe()
+
+Events are delivered one at a time, in the order the changes were made. Handlers are called by the
+goroutine which changed the state (or by one still delivering earlier events), so they must not block for long.
+SubscribeChannel sends events to a channel instead, dropping them while the channel is full.
+
+	events := make(chan circuitbreaker.Event, 100)
+	unsubscribe = registry.SubscribeChannel(events)
+
+# Persistence
+
+A FileStore keeps statuses in a JSON file, which is rewritten atomically in the background on every change,
+so that calls never wait for the disk. Flush waits until the changes are written (e.g. before the process
+exits). Circuits open when the process stopped are restored open, and they become half open once the
+remainder of the reset timeout elapses.
+
+	store, err := circuitbreaker.NewFileStore("/var/lib/app/circuits.json")
+	if err != nil {
+		// Error handling.
+		...
+	}
+	store.OnError = func(err error) {
+		log.Println(err)
+	}
+
+	defer store.Flush()
+
+	p := circuitbreaker.New("service-id")
+	p.Registry = circuitbreaker.NewRegistry(store)
+
+# Context
+
+Every policy may also run bound to a context.Context. Use CommandContext instead of Command so that the
+command supplier receives the context, and RunContext instead of Run. A wrapped policy receives the same context.
+
+	p := circuitbreaker.New("service-id")
+	p.CommandContext = func(ctx context.Context) error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	err := p.RunContext(ctx, core.NewMetric())
+
+# Clock
+
+The reset timeout is measured by the policy Clock (see core.Clock). Tests may set a core.FakeClock and
+advance it instead of waiting for the circuit to become half open.
+
+	clock := core.NewFakeClock(time.Now())
+	p := circuitbreaker.New("service-id")
+	p.Clock = clock
+	...
+
+	clock.Advance(p.ResetTimeout)
+
 # Listener
 
 In order to keep tracking of what is happening on the execution, you may use listeners to generate some events.
@@ -99,5 +267,9 @@ Circuit Breaker supports listeners to track some events that are described bello
 	}
 
 	_ = p.Run(core.Metric())
+
+The state of a circuit breaker is safe for concurrent use: every transition of a service is atomic. Listeners
+are called after the transition is made, and each one receives its own copy of the circuit breaker status,
+so changing it has no effect on the circuit breaker.
 */
 package circuitbreaker
@@ -0,0 +1,145 @@
+package circuitbreaker
+
+import "time"
+
+// Event is a state change of a circuit breaker.
+type Event struct {
+	// The registered service id.
+	ServiceID string
+
+	// State before the change.
+	From CircuitState
+
+	// State after the change.
+	To CircuitState
+
+	// The error which opened the circuit (nil if the circuit wasn't open by an error).
+	Cause error
+
+	// Why the circuit was open.
+	TripReason TripReason
+
+	// When the change happened.
+	Time time.Time
+
+	// How many errors occurred.
+	ErrorCount int
+
+	// Number of calls in the sliding window.
+	Calls int
+
+	// Number of failed calls in the sliding window.
+	FailedCalls int
+
+	// Number of slow calls in the sliding window.
+	SlowCalls int
+}
+
+type subscriber struct {
+	id      int
+	handler func(e Event)
+}
+
+// Subscribe registers a handler to be called with every state change of every circuit breaker in the
+// registry, including manual ones. Events are delivered one at a time, in the order the changes were made.
+// The handler is called once the circuit breaker is released, by the goroutine which made the change or
+// by one delivering earlier events, so it must not block for long.
+//
+// Returns a function which unsubscribes the handler.
+func (r *Registry) Subscribe(handler func(e Event)) func() {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	r.nextID++
+	id := r.nextID
+	r.subscribers = append(r.subscribers, subscriber{id: id, handler: handler})
+
+	return func() {
+		r.subMu.Lock()
+		defer r.subMu.Unlock()
+
+		for i, s := range r.subscribers {
+			if s.id == id {
+				r.subscribers = append(r.subscribers[:i:i], r.subscribers[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// SubscribeChannel registers a channel to receive every state change of every circuit breaker in the
+// registry (see Subscribe). Events are sent without blocking, so they are dropped while the channel is full.
+//
+// Returns a function which unsubscribes the channel.
+func (r *Registry) SubscribeChannel(c chan<- Event) func() {
+	return r.Subscribe(func(e Event) {
+		select {
+		case c <- e:
+		default:
+		}
+	})
+}
+
+// queue appends an event to be published. Breakers queue events while holding their lock, so that the
+// queue keeps the order of the changes. Nothing is queued without subscribers.
+func (r *Registry) queue(e Event) {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	if len(r.subscribers) > 0 {
+		r.events = append(r.events, e)
+	}
+}
+
+// publish delivers the queued events to the subscribers, in order. Only one goroutine publishes at a time:
+// the others leave their events to it, so that handlers are never called concurrently or out of order.
+func (r *Registry) publish() {
+	r.subMu.Lock()
+	publishing := r.publishing
+	r.publishing = true
+	r.subMu.Unlock()
+
+	if publishing {
+		return
+	}
+
+	done := false
+	defer func() {
+		// A handler panicked: let the next change publish the remaining events.
+		if !done {
+			r.subMu.Lock()
+			r.publishing = false
+			r.subMu.Unlock()
+		}
+	}()
+
+	for {
+		e, subscribers, ok := r.next()
+		if !ok {
+			done = true
+			return
+		}
+
+		for _, s := range subscribers {
+			s.handler(e)
+		}
+	}
+}
+
+// next dequeues the next event to publish, or stops publishing if there is none.
+func (r *Registry) next() (Event, []subscriber, bool) {
+	r.subMu.Lock()
+	defer r.subMu.Unlock()
+
+	if len(r.events) == 0 {
+		r.events = nil
+		r.publishing = false
+
+		return Event{}, nil, false
+	}
+
+	e := r.events[0]
+	r.events = r.events[1:]
+
+	return e, r.subscribers, true
+}
@@ -0,0 +1,176 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistrySubscribe(t *testing.T) {
+	errTest := errors.New("err test")
+	now := time.Date(2023, 5, 10, 8, 0, 0, 0, time.UTC)
+	clock := core.NewFakeClock(now)
+	r := circuitbreaker.NewRegistry(nil)
+	var events []circuitbreaker.Event
+	r.Subscribe(func(e circuitbreaker.Event) { events = append(events, e) })
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	p.Clock = clock
+	p.Errors = []error{errTest}
+
+	runWindow(p, errTest)
+	clock.Advance(p.ResetTimeout)
+	runWindow(p, nil)
+
+	assert.Equal(t, []circuitbreaker.Event{
+		{
+			ServiceID:  "service",
+			From:       circuitbreaker.ClosedState,
+			To:         circuitbreaker.OpenState,
+			Cause:      errTest,
+			TripReason: circuitbreaker.ErrorThresholdTrip,
+			Time:       now,
+			ErrorCount: 1,
+		},
+		{
+			ServiceID:  "service",
+			From:       circuitbreaker.OpenState,
+			To:         circuitbreaker.HalfOpenState,
+			TripReason: circuitbreaker.ErrorThresholdTrip,
+			Time:       now.Add(p.ResetTimeout),
+			ErrorCount: 1,
+		},
+		{
+			ServiceID: "service",
+			From:      circuitbreaker.HalfOpenState,
+			To:        circuitbreaker.ClosedState,
+			Time:      now.Add(p.ResetTimeout),
+		},
+	}, events)
+}
+
+func TestRegistrySubscribeWindow(t *testing.T) {
+	errTest := errors.New("err test")
+	r := circuitbreaker.NewRegistry(nil)
+	var events []circuitbreaker.Event
+	r.Subscribe(func(e circuitbreaker.Event) { events = append(events, e) })
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	p.WindowType = circuitbreaker.CountBasedWindow
+	p.MinimumCalls = 2
+	p.Errors = []error{errTest}
+
+	runWindow(p, nil)
+	runWindow(p, errTest)
+
+	assert.Len(t, events, 1)
+	assert.Equal(t, circuitbreaker.FailureRateTrip, events[0].TripReason)
+	assert.Equal(t, 2, events[0].Calls)
+	assert.Equal(t, 1, events[0].FailedCalls)
+	assert.Equal(t, 0, events[0].SlowCalls)
+}
+
+func TestRegistrySubscribeManual(t *testing.T) {
+	now := time.Date(2023, 5, 10, 8, 0, 0, 0, time.UTC)
+	r := circuitbreaker.NewRegistry(nil)
+	r.Clock = core.NewFakeClock(now)
+	var events []circuitbreaker.Event
+	r.Subscribe(func(e circuitbreaker.Event) { events = append(events, e) })
+
+	r.ForceOpen("service")
+	r.ForceOpen("service")
+	r.Disable("service")
+	assert.Nil(t, r.Reset("service"))
+	assert.Nil(t, r.Reset("service"))
+	r.ForceClosed("service")
+
+	assert.Equal(t, []circuitbreaker.Event{
+		{ServiceID: "service", From: circuitbreaker.ClosedState, To: circuitbreaker.ForcedOpenState, Time: now},
+		{ServiceID: "service", From: circuitbreaker.ForcedOpenState, To: circuitbreaker.DisabledState, Time: now},
+		{ServiceID: "service", From: circuitbreaker.DisabledState, To: circuitbreaker.ClosedState, Time: now},
+		{ServiceID: "service", From: circuitbreaker.ClosedState, To: circuitbreaker.ForcedClosedState, Time: now},
+	}, events)
+}
+
+func TestRegistryUnsubscribe(t *testing.T) {
+	r := circuitbreaker.NewRegistry(nil)
+	var first, second int
+	unsubscribe := r.Subscribe(func(e circuitbreaker.Event) { first++ })
+	r.Subscribe(func(e circuitbreaker.Event) { second++ })
+
+	r.ForceOpen("service")
+	unsubscribe()
+	unsubscribe()
+	r.ForceClosed("service")
+
+	assert.Equal(t, 1, first)
+	assert.Equal(t, 2, second)
+}
+
+func TestRegistrySubscribeOrder(t *testing.T) {
+	r := circuitbreaker.NewRegistry(nil)
+	var events []circuitbreaker.Event
+	r.Subscribe(func(e circuitbreaker.Event) { events = append(events, e) })
+	r.ForceOpen("service")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				_ = r.Reset("service")
+				r.ForceOpen("service")
+			}
+		}()
+	}
+	wg.Wait()
+
+	// Each event starts from the state the previous one ended in.
+	for i := 1; i < len(events); i++ {
+		assert.Equal(t, events[i-1].To, events[i].From, "event %d", i)
+	}
+}
+
+func TestRegistrySubscribePanic(t *testing.T) {
+	r := circuitbreaker.NewRegistry(nil)
+	var events []circuitbreaker.Event
+	r.Subscribe(func(e circuitbreaker.Event) {
+		events = append(events, e)
+		if e.To == circuitbreaker.ForcedOpenState {
+			panic("handler panic")
+		}
+	})
+
+	assert.Panics(t, func() { r.ForceOpen("service") })
+	r.ForceClosed("service")
+
+	assert.Len(t, events, 2)
+}
+
+func TestRegistrySubscribeChannel(t *testing.T) {
+	r := circuitbreaker.NewRegistry(nil)
+	c := make(chan circuitbreaker.Event, 1)
+	unsubscribe := r.SubscribeChannel(c)
+	defer unsubscribe()
+
+	r.ForceOpen("service-a")
+	r.ForceOpen("service-b")
+
+	e := <-c
+	assert.Equal(t, "service-a", e.ServiceID)
+	assert.Equal(t, circuitbreaker.ForcedOpenState, e.To)
+
+	select {
+	case e = <-c:
+		assert.Fail(t, "event should have been dropped", e.ServiceID)
+	default:
+	}
+}
@@ -0,0 +1,225 @@
+package circuitbreaker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// FileStore is a StateStore which keeps statuses in a JSON file, so that circuit breakers survive
+// process restarts. The file is rewritten atomically in the background whenever a status changes, so
+// that calls never wait for the disk. Changes made while the file is written are coalesced into the
+// next write. Flush waits until every change is written.
+type FileStore struct {
+	// Function called (in the background) when the file can't be written. It must be set before the
+	// store is used.
+	OnError func(err error)
+
+	path string
+
+	mu       sync.Mutex
+	idle     *sync.Cond
+	statuses map[string]CircuitBreaker
+	pending  bool
+	writing  bool
+	err      error
+}
+
+type fileState struct {
+	Services map[string]fileStatus `json:"services"`
+}
+
+type fileStatus struct {
+	State             CircuitState  `json:"state"`
+	TimeErrorOcurred  time.Time     `json:"timeErrorOccurred"`
+	ErrorCount        int           `json:"errorCount"`
+	TripReason        TripReason    `json:"tripReason"`
+	HalfOpenSuccesses int           `json:"halfOpenSuccesses"`
+	OpenDuration      time.Duration `json:"openDuration"`
+}
+
+// NewFileStore creates a state store backed by the file at path, loading the statuses it holds.
+// A missing file is created on the first change.
+//
+// Possible error(s): any error reading or decoding the file.
+func NewFileStore(path string) (*FileStore, error) {
+	s := &FileStore{path: path, statuses: make(map[string]CircuitBreaker)}
+	s.idle = sync.NewCond(&s.mu)
+
+	data, err := os.ReadFile(path) //nolint:gosec // the path is chosen by the application.
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read circuit breaker state file: %w", err)
+	}
+
+	state := fileState{}
+	if err = json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("decode circuit breaker state file: %w", err)
+	}
+
+	for id, status := range state.Services {
+		s.statuses[id] = status.circuitBreaker()
+	}
+
+	return s, nil
+}
+
+// Load returns the status of the service and whether there is one.
+func (s *FileStore) Load(serviceID string) (CircuitBreaker, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.statuses[serviceID]
+
+	return status, ok
+}
+
+// Save stores the status of the service and schedules a rewrite of the file.
+func (s *FileStore) Save(serviceID string, status CircuitBreaker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statuses[serviceID] = status
+	s.changed()
+}
+
+// Delete removes the status of the service and schedules a rewrite of the file.
+func (s *FileStore) Delete(serviceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.statuses, serviceID)
+	s.changed()
+}
+
+// Keys returns the ids of the services with a status stored, sorted.
+func (s *FileStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.statuses))
+	for key := range s.statuses {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// Err returns the error of the last write of the file (nil if it succeeded).
+func (s *FileStore) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.err
+}
+
+// Flush waits until every change made so far is written to the file.
+//
+// Possible error(s): the error of the last write of the file.
+func (s *FileStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.writing {
+		s.idle.Wait()
+	}
+
+	return s.err
+}
+
+// changed starts writing the file in the background, unless it is being written already.
+func (s *FileStore) changed() {
+	s.pending = true
+	if !s.writing {
+		s.writing = true
+		go s.write()
+	}
+}
+
+// write rewrites the file until no change is pending. Only the lock of the store is held meanwhile.
+func (s *FileStore) write() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for s.pending {
+		s.pending = false
+		state := fileState{Services: make(map[string]fileStatus, len(s.statuses))}
+		for id, status := range s.statuses {
+			state.Services[id] = newFileStatus(status)
+		}
+		s.mu.Unlock()
+
+		err := writeFile(s.path, state)
+		if err != nil && s.OnError != nil {
+			s.OnError(err)
+		}
+
+		s.mu.Lock()
+		s.err = err
+	}
+
+	s.writing = false
+	s.idle.Broadcast()
+}
+
+// writeFile writes to a temporary file which then replaces the file, so that it is never left half written.
+func writeFile(path string, state fileState) error {
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode circuit breaker state file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("write circuit breaker state file: %w", err)
+	}
+	defer func() { _ = os.Remove(tmp.Name()) }()
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return fmt.Errorf("write circuit breaker state file: %w", err)
+	}
+
+	return nil
+}
+
+func newFileStatus(status CircuitBreaker) fileStatus {
+	return fileStatus{
+		State:             status.State,
+		TimeErrorOcurred:  status.TimeErrorOcurred,
+		ErrorCount:        status.ErrorCount,
+		TripReason:        status.TripReason,
+		HalfOpenSuccesses: status.HalfOpenSuccesses,
+		OpenDuration:      status.OpenDuration,
+	}
+}
+
+// circuitBreaker converts the saved status. Trial calls in progress aren't saved.
+func (s fileStatus) circuitBreaker() CircuitBreaker {
+	return CircuitBreaker{
+		State:             s.State,
+		TimeErrorOcurred:  s.TimeErrorOcurred,
+		ErrorCount:        s.ErrorCount,
+		TripReason:        s.TripReason,
+		HalfOpenSuccesses: s.HalfOpenSuccesses,
+		OpenDuration:      s.OpenDuration,
+	}
+}
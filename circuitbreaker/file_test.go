@@ -0,0 +1,137 @@
+package circuitbreaker_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFileStoreMissingFile(t *testing.T) {
+	store, err := circuitbreaker.NewFileStore(filepath.Join(t.TempDir(), "state.json"))
+
+	assert.Nil(t, err)
+	assert.Empty(t, store.Keys())
+}
+
+func TestNewFileStoreCorruptedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	assert.Nil(t, os.WriteFile(path, []byte("{"), 0o600))
+
+	store, err := circuitbreaker.NewFileStore(path)
+
+	assert.Nil(t, store)
+	assert.ErrorContains(t, err, "decode circuit breaker state file")
+}
+
+func TestNewFileStoreUnreadableFile(t *testing.T) {
+	store, err := circuitbreaker.NewFileStore(t.TempDir())
+
+	assert.Nil(t, store)
+	assert.ErrorContains(t, err, "read circuit breaker state file")
+}
+
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	occurred := time.Date(2023, 5, 10, 8, 0, 0, 0, time.UTC)
+
+	store, err := circuitbreaker.NewFileStore(path)
+	assert.Nil(t, err)
+
+	store.Save("service-a", circuitbreaker.CircuitBreaker{
+		State:            circuitbreaker.OpenState,
+		TimeErrorOcurred: occurred,
+		ErrorCount:       3,
+		TripReason:       circuitbreaker.ErrorThresholdTrip,
+		HalfOpenCalls:    2,
+	})
+	store.Save("service-b", circuitbreaker.CircuitBreaker{})
+	store.Delete("service-b")
+	assert.Nil(t, store.Flush())
+	assert.Nil(t, store.Err())
+
+	store, err = circuitbreaker.NewFileStore(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"service-a"}, store.Keys())
+
+	status, ok := store.Load("service-a")
+	assert.True(t, ok)
+	assert.Equal(t, circuitbreaker.CircuitBreaker{
+		State:            circuitbreaker.OpenState,
+		TimeErrorOcurred: occurred,
+		ErrorCount:       3,
+		TripReason:       circuitbreaker.ErrorThresholdTrip,
+	}, status)
+
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	assert.Len(t, entries, 1)
+}
+
+func TestFileStoreWriteError(t *testing.T) {
+	var werr error
+	store, err := circuitbreaker.NewFileStore(filepath.Join(t.TempDir(), "missing", "state.json"))
+	assert.Nil(t, err)
+	store.OnError = func(err error) { werr = err }
+
+	store.Save("service", circuitbreaker.CircuitBreaker{})
+
+	assert.ErrorContains(t, store.Flush(), "write circuit breaker state file")
+	assert.ErrorContains(t, store.Err(), "write circuit breaker state file")
+	assert.ErrorIs(t, werr, store.Err())
+
+	_, ok := store.Load("service")
+	assert.True(t, ok)
+}
+
+func TestFileStoreCoalescesWrites(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	store, _ := circuitbreaker.NewFileStore(path)
+
+	for i := 1; i <= 100; i++ {
+		store.Save("service", circuitbreaker.CircuitBreaker{ErrorCount: i})
+	}
+	assert.Nil(t, store.Flush())
+
+	store, err := circuitbreaker.NewFileStore(path)
+	assert.Nil(t, err)
+
+	status, _ := store.Load("service")
+	assert.Equal(t, 100, status.ErrorCount)
+}
+
+func TestFileStoreRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+	clock := core.NewFakeClock(time.Now())
+
+	store, _ := circuitbreaker.NewFileStore(path)
+	p := circuitbreaker.New("service")
+	p.Registry = circuitbreaker.NewRegistry(store)
+	p.Clock = clock
+	p.ResetTimeout = time.Minute
+	openCircuit(t, p)
+
+	// The process restarts while the circuit is open.
+	assert.Nil(t, store.Flush())
+	clock.Advance(time.Second * 40)
+	store, err := circuitbreaker.NewFileStore(path)
+	assert.Nil(t, err)
+	p.Registry = circuitbreaker.NewRegistry(store)
+	p.Command = func() error { return nil }
+
+	assert.ErrorIs(t, p.Run(core.NewMetric()), circuitbreaker.ErrCircuitIsOpen)
+
+	// Only the remaining reset timeout is waited.
+	clock.Advance(time.Second * 20)
+	state, err := circuitbreaker.State(p)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.HalfOpenState, state)
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+	status, _ := store.Load("service")
+	assert.Equal(t, circuitbreaker.ClosedState, status.State)
+	assert.Nil(t, store.Flush())
+}
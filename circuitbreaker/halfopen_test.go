@@ -0,0 +1,135 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunValidatePolicyHalfOpen(t *testing.T) {
+	p := circuitbreaker.New("half-open-validation")
+	p.Command = func() error { return nil }
+
+	p.HalfOpenMaxCalls = -1
+	assert.ErrorIs(t, p.Run(core.NewMetric()), circuitbreaker.ErrHalfOpenMaxCallsValidation)
+
+	p.HalfOpenMaxCalls = 0
+	p.HalfOpenSuccessThreshold = -1
+	assert.ErrorIs(t, p.Run(core.NewMetric()), circuitbreaker.ErrHalfOpenSuccessThresholdValidation)
+}
+
+func TestRunHalfOpenMaxCalls(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+
+	p := circuitbreaker.New("half-open-max-calls")
+	p.Errors = []error{errTest}
+	p.Clock = clock
+
+	runWindow(p, errTest)
+	clock.Advance(p.ResetTimeout)
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	c := make(chan error, 1)
+
+	probe := p
+	probe.Command = func() error {
+		close(started)
+		<-release
+		return nil
+	}
+	go func() { c <- probe.Run(core.NewMetric()) }()
+	<-started
+
+	p.Command = func() error { return nil }
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(circuitbreaker.Metric{}).String()].(circuitbreaker.Metric)
+
+	assert.ErrorIs(t, err, circuitbreaker.ErrTooManyHalfOpenCalls)
+	assert.ErrorIs(t, m.MetricError(), circuitbreaker.ErrTooManyHalfOpenCalls)
+	assert.Equal(t, circuitbreaker.HalfOpenState, m.State)
+
+	close(release)
+	assert.Nil(t, <-c)
+
+	state, _ := circuitbreaker.State(p)
+	assert.Equal(t, circuitbreaker.ClosedState, state)
+}
+
+func TestRunHalfOpenPanicFreesTrialCall(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+
+	p := circuitbreaker.New("half-open-panic")
+	p.Errors = []error{errTest}
+	p.HalfOpenMaxCalls = 1
+	p.Clock = clock
+
+	runWindow(p, errTest)
+	clock.Advance(p.ResetTimeout)
+
+	p.Command = func() error { panic("command panic") }
+	assert.Panics(t, func() { _ = p.Run(core.NewMetric()) })
+
+	p.Command = func() error { return nil }
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	state, _ := circuitbreaker.State(p)
+	assert.Equal(t, circuitbreaker.ClosedState, state)
+}
+
+func TestRunHalfOpenSuccessThreshold(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+	var statuses []circuitbreaker.CircuitBreaker
+
+	p := circuitbreaker.New("half-open-success-threshold")
+	p.Errors = []error{errTest}
+	p.HalfOpenSuccessThreshold = 2
+	p.Clock = clock
+	p.AfterCircuitBreaker = func(p circuitbreaker.Policy, status *circuitbreaker.CircuitBreaker, err error) {
+		statuses = append(statuses, *status)
+	}
+
+	runWindow(p, errTest)
+	clock.Advance(p.ResetTimeout)
+
+	m := runWindow(p, nil)
+	assert.Equal(t, circuitbreaker.HalfOpenState, m.State)
+	assert.Equal(t, 1, statuses[1].HalfOpenSuccesses)
+	assert.Equal(t, 0, statuses[1].HalfOpenCalls)
+
+	m = runWindow(p, nil)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+}
+
+func TestRunHalfOpenSuccessThresholdFailedProbe(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+
+	p := circuitbreaker.New("half-open-success-threshold-failed-probe")
+	p.Errors = []error{errTest}
+	p.HalfOpenSuccessThreshold = 2
+	p.Clock = clock
+
+	runWindow(p, errTest)
+	clock.Advance(p.ResetTimeout)
+
+	m := runWindow(p, nil)
+	assert.Equal(t, circuitbreaker.HalfOpenState, m.State)
+
+	m = runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+	assert.Equal(t, circuitbreaker.FailedProbeTrip, m.TripReason)
+
+	clock.Advance(p.ResetTimeout)
+	m = runWindow(p, nil)
+	assert.Equal(t, circuitbreaker.HalfOpenState, m.State)
+}
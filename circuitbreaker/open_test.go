@@ -0,0 +1,81 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunValidatePolicyResetTimeoutBackoff(t *testing.T) {
+	p := circuitbreaker.New("reset-timeout-backoff-validation")
+	p.Command = func() error { return nil }
+
+	p.ResetTimeoutMultiplier = 0.5
+	assert.ErrorIs(t, p.Run(core.NewMetric()), circuitbreaker.ErrResetTimeoutMultiplierValidation)
+
+	p.ResetTimeoutMultiplier = 2
+	p.MaxResetTimeout = p.ResetTimeout - 1
+	assert.ErrorIs(t, p.Run(core.NewMetric()), circuitbreaker.ErrMaxResetTimeoutValidation)
+}
+
+func TestRunResetTimeoutBackoff(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+
+	p := circuitbreaker.New("reset-timeout-backoff")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.Errors = []error{errTest}
+	p.ResetTimeout = time.Second
+	p.ResetTimeoutMultiplier = 2
+	p.MaxResetTimeout = time.Second * 5
+	p.Clock = clock
+
+	m := runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+	assert.Equal(t, time.Second, m.OpenDuration)
+
+	for _, d := range []time.Duration{time.Second * 2, time.Second * 4, time.Second * 5, time.Second * 5} {
+		previous := m.OpenDuration
+		clock.Advance(previous - time.Millisecond)
+		state, _ := circuitbreaker.State(p)
+		assert.Equal(t, circuitbreaker.OpenState, state)
+
+		clock.Advance(time.Millisecond)
+		m = runWindow(p, errTest)
+		assert.Equal(t, circuitbreaker.OpenState, m.State)
+		assert.Equal(t, circuitbreaker.FailedProbeTrip, m.TripReason)
+		assert.Equal(t, d, m.OpenDuration)
+	}
+
+	status, _ := p.Registry.Status(p.ServiceID)
+	assert.Equal(t, time.Second*5, status.OpenDuration)
+
+	clock.Advance(m.OpenDuration)
+	m = runWindow(p, nil)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, time.Duration(0), m.OpenDuration)
+
+	m = runWindow(p, errTest)
+	assert.Equal(t, time.Second, m.OpenDuration)
+}
+
+func TestRunResetTimeoutFixed(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+
+	p := circuitbreaker.New("reset-timeout-fixed")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.Errors = []error{errTest}
+	p.Clock = clock
+
+	runWindow(p, errTest)
+	clock.Advance(p.ResetTimeout)
+	m := runWindow(p, errTest)
+
+	assert.Equal(t, circuitbreaker.FailedProbeTrip, m.TripReason)
+	assert.Equal(t, p.ResetTimeout, m.OpenDuration)
+}
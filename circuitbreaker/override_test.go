@@ -0,0 +1,130 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryForceOpen(t *testing.T) {
+	r := circuitbreaker.NewRegistry(nil)
+	var status circuitbreaker.CircuitBreaker
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	p.Command = func() error { return nil }
+	p.BeforeCircuitBreaker = func(p circuitbreaker.Policy, s *circuitbreaker.CircuitBreaker) {
+		status = *s
+	}
+
+	r.ForceOpen("service")
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(circuitbreaker.Metric{}).String()].(circuitbreaker.Metric)
+
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitIsForcedOpen)
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitIsOpen)
+	assert.Equal(t, circuitbreaker.ForcedOpenState, m.State)
+	assert.Equal(t, circuitbreaker.ForcedOpenState, status.State)
+
+	state, _ := r.State(p)
+	assert.Equal(t, circuitbreaker.ForcedOpenState, state)
+
+	assert.Nil(t, r.Reset("service"))
+	assert.Nil(t, p.Run(core.NewMetric()))
+}
+
+func TestRegistryForceClosed(t *testing.T) {
+	errTest := errors.New("err test")
+	r := circuitbreaker.NewRegistry(nil)
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	openCircuit(t, p)
+
+	r.ForceClosed("service")
+	for i := 0; i < 3; i++ {
+		m := runWindow(p, errTest)
+		assert.Equal(t, circuitbreaker.ForcedClosedState, m.State)
+		assert.Equal(t, i+2, m.ErrorCount)
+		assert.ErrorIs(t, m.MetricError(), errTest)
+	}
+
+	assert.Nil(t, r.Reset("service"))
+	m := runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+}
+
+func TestRegistryForceClosedWindow(t *testing.T) {
+	errTest := errors.New("err test")
+	r := circuitbreaker.NewRegistry(nil)
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	p.WindowType = circuitbreaker.CountBasedWindow
+	p.MinimumCalls = 1
+	p.Errors = []error{errTest}
+
+	r.ForceClosed("service")
+	m := runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.ForcedClosedState, m.State)
+	assert.Equal(t, 1, m.FailedCalls)
+	assert.Equal(t, 100.0, m.FailureRate)
+}
+
+func TestRegistryDisable(t *testing.T) {
+	errTest := errors.New("err test")
+	r := circuitbreaker.NewRegistry(nil)
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	p.WindowType = circuitbreaker.CountBasedWindow
+	p.MinimumCalls = 1
+
+	r.Disable("service")
+	m := runWindow(p, errTest)
+
+	assert.Equal(t, circuitbreaker.DisabledState, m.State)
+	assert.Equal(t, 0, m.ErrorCount)
+	assert.Equal(t, 0, m.Calls)
+	assert.ErrorIs(t, m.MetricError(), errTest)
+}
+
+func TestRegistryForcedStatePersisted(t *testing.T) {
+	store := circuitbreaker.NewMemoryStore()
+	circuitbreaker.NewRegistry(store).ForceOpen("service")
+
+	p := circuitbreaker.New("service")
+	p.Registry = circuitbreaker.NewRegistry(store)
+	p.Command = func() error { return nil }
+
+	assert.ErrorIs(t, p.Run(core.NewMetric()), circuitbreaker.ErrCircuitIsForcedOpen)
+}
+
+func TestDefaultRegistryOverrides(t *testing.T) {
+	p := circuitbreaker.New("default-registry-overrides")
+	p.Command = func() error { return nil }
+
+	circuitbreaker.ForceOpen(p.ServiceID)
+	state, _ := circuitbreaker.State(p)
+	assert.Equal(t, circuitbreaker.ForcedOpenState, state)
+
+	circuitbreaker.ForceClosed(p.ServiceID)
+	state, _ = circuitbreaker.State(p)
+	assert.Equal(t, circuitbreaker.ForcedClosedState, state)
+
+	circuitbreaker.Disable(p.ServiceID)
+	state, _ = circuitbreaker.State(p)
+	assert.Equal(t, circuitbreaker.DisabledState, state)
+
+	assert.Nil(t, circuitbreaker.Reset(p.ServiceID))
+	state, _ = circuitbreaker.State(p)
+	assert.Equal(t, circuitbreaker.ClosedState, state)
+
+	assert.ErrorIs(t, circuitbreaker.Reset("unknown-service"), circuitbreaker.ErrCircuitBreakerNotFound)
+	assert.Nil(t, circuitbreaker.DefaultRegistry().Remove(p.ServiceID))
+}
@@ -0,0 +1,207 @@
+package circuitbreaker
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+)
+
+// Registry keeps the circuit breakers of services. Policies sharing a registry and a ServiceID share a
+// circuit breaker, while separate registries are isolated from each other. Policies without a Registry
+// use the default one.
+type Registry struct {
+	// Clock used to tell when circuit breakers are forced or reset (real time if not set).
+	// It must be set before the registry is used.
+	Clock core.Clock
+
+	mu       sync.Mutex
+	store    StateStore
+	breakers map[string]*breaker
+
+	subMu       sync.Mutex
+	subscribers []subscriber
+	nextID      int
+	events      []Event
+	publishing  bool
+}
+
+var defaultRegistry = NewRegistry(nil)
+
+// NewRegistry creates a registry which keeps circuit breaker statuses in the given store
+// (a MemoryStore if nil).
+func NewRegistry(store StateStore) *Registry {
+	if store == nil {
+		store = NewMemoryStore()
+	}
+
+	return &Registry{store: store, breakers: make(map[string]*breaker)}
+}
+
+// DefaultRegistry returns the registry used by policies without a Registry.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// State queries for a circuit breaker state by service id.
+//
+// Returns the state of a circuit breaker or an error if no circuit breaker is found.
+//
+// Possible error(s): ErrCircuitBreakerNotFound.
+func (r *Registry) State(p Policy) (CircuitState, error) {
+	b := r.lookup(p.ServiceID)
+	if b == nil {
+		return -1, ErrCircuitBreakerNotFound
+	}
+
+	state, transitions := b.state(p, core.ClockOrDefault(p.Clock).Now())
+	notify(r, p, transitions)
+
+	return state, nil
+}
+
+// Status queries for a copy of a circuit breaker status by service id.
+//
+// Returns the status of a circuit breaker or an error if no circuit breaker is found.
+//
+// Possible error(s): ErrCircuitBreakerNotFound.
+func (r *Registry) Status(serviceID string) (CircuitBreaker, error) {
+	b := r.lookup(serviceID)
+	if b == nil {
+		return CircuitBreaker{}, ErrCircuitBreakerNotFound
+	}
+
+	return b.snapshot(), nil
+}
+
+// Reset closes the circuit breaker of a service, leaving any forced state, and clears its counters.
+//
+// Possible error(s): ErrCircuitBreakerNotFound.
+func (r *Registry) Reset(serviceID string) error {
+	b := r.lookup(serviceID)
+	if b == nil {
+		return ErrCircuitBreakerNotFound
+	}
+	b.reset(r.now())
+	r.publish()
+
+	return nil
+}
+
+// ForceOpen forces the circuit breaker of a service open, creating it if there is none. Every call is
+// rejected with ErrCircuitIsForcedOpen until the circuit breaker is reset.
+func (r *Registry) ForceOpen(serviceID string) {
+	r.get(serviceID).force(ForcedOpenState, r.now())
+	r.publish()
+}
+
+// ForceClosed forces the circuit breaker of a service closed, creating it if there is none. Every call is
+// let through, and recorded, but the circuit isn't open whatever errors occur until it is reset.
+func (r *Registry) ForceClosed(serviceID string) {
+	r.get(serviceID).force(ForcedClosedState, r.now())
+	r.publish()
+}
+
+// Disable disables the circuit breaker of a service, creating it if there is none. Every call is let
+// through without being recorded until the circuit breaker is reset.
+func (r *Registry) Disable(serviceID string) {
+	r.get(serviceID).force(DisabledState, r.now())
+	r.publish()
+}
+
+// Remove discards the circuit breaker of a service. It is created again, closed, the next time the service
+// is used.
+//
+// Possible error(s): ErrCircuitBreakerNotFound.
+func (r *Registry) Remove(serviceID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.breakers[serviceID]
+	_, ok := r.store.Load(serviceID)
+	if b == nil && !ok {
+		return ErrCircuitBreakerNotFound
+	}
+
+	if b != nil {
+		b.remove()
+	}
+	delete(r.breakers, serviceID)
+	r.store.Delete(serviceID)
+
+	return nil
+}
+
+// List returns the ids of the services which have a circuit breaker, sorted.
+func (r *Registry) List() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ids := r.store.Keys()
+	for id := range r.breakers {
+		if _, ok := r.store.Load(id); !ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	return ids
+}
+
+// get returns the breaker of the service, creating it (from the stored status, if any) if there is none.
+func (r *Registry) get(serviceID string) *breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.breakers[serviceID]
+	if b == nil {
+		b = r.restore(serviceID)
+		if b == nil {
+			b = &breaker{id: serviceID, store: r.store, registry: r}
+		}
+		r.breakers[serviceID] = b
+	}
+
+	return b
+}
+
+// lookup returns the breaker of the service or nil if there is none.
+func (r *Registry) lookup(serviceID string) *breaker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	b := r.breakers[serviceID]
+	if b == nil {
+		b = r.restore(serviceID)
+		if b != nil {
+			r.breakers[serviceID] = b
+		}
+	}
+
+	return b
+}
+
+func (r *Registry) restore(serviceID string) *breaker {
+	status, ok := r.store.Load(serviceID)
+	if !ok {
+		return nil
+	}
+
+	// Trial calls in progress were lost with the process that saved the status.
+	status.HalfOpenCalls = 0
+
+	return &breaker{id: serviceID, store: r.store, registry: r, status: status}
+}
+
+func (r *Registry) now() time.Time {
+	return core.ClockOrDefault(r.Clock).Now()
+}
+
+func registryOf(p Policy) *Registry {
+	if p.Registry == nil {
+		return defaultRegistry
+	}
+
+	return p.Registry
+}
@@ -0,0 +1,165 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func openCircuit(t *testing.T, p circuitbreaker.Policy) {
+	t.Helper()
+
+	errTest := errors.New("err test")
+	p.Errors = nil
+	p.Command = func() error { return errTest }
+	_ = p.Run(core.NewMetric())
+
+	state, err := circuitbreaker.State(p)
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.OpenState, state)
+}
+
+func TestRegistryIsolation(t *testing.T) {
+	r1 := circuitbreaker.NewRegistry(nil)
+	r2 := circuitbreaker.NewRegistry(nil)
+
+	p := circuitbreaker.New("isolated-service")
+	p.Registry = r1
+	openCircuit(t, p)
+
+	p.Registry = r2
+	_, err := circuitbreaker.State(p)
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitBreakerNotFound)
+
+	p.Command = func() error { return nil }
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	state, _ := r2.State(p)
+	assert.Equal(t, circuitbreaker.ClosedState, state)
+	state, _ = r1.State(p)
+	assert.Equal(t, circuitbreaker.OpenState, state)
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	p := circuitbreaker.New("default-registry-service")
+	p.Command = func() error { return nil }
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	assert.Contains(t, circuitbreaker.DefaultRegistry().List(), "default-registry-service")
+	assert.Nil(t, circuitbreaker.DefaultRegistry().Remove("default-registry-service"))
+}
+
+func TestRegistryStatus(t *testing.T) {
+	r := circuitbreaker.NewRegistry(nil)
+	_, err := r.Status("service")
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitBreakerNotFound)
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	openCircuit(t, p)
+
+	status, err := r.Status("service")
+	assert.Nil(t, err)
+	assert.Equal(t, circuitbreaker.OpenState, status.State)
+	assert.Equal(t, 1, status.ErrorCount)
+	assert.Equal(t, circuitbreaker.UnhandledErrorTrip, status.TripReason)
+}
+
+func TestRegistryReset(t *testing.T) {
+	r := circuitbreaker.NewRegistry(nil)
+	assert.ErrorIs(t, r.Reset("service"), circuitbreaker.ErrCircuitBreakerNotFound)
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	openCircuit(t, p)
+
+	assert.Nil(t, r.Reset("service"))
+	status, _ := r.Status("service")
+	assert.Equal(t, circuitbreaker.CircuitBreaker{}, status)
+
+	p.Command = func() error { return nil }
+	assert.Nil(t, p.Run(core.NewMetric()))
+}
+
+func TestRegistryResetWindow(t *testing.T) {
+	errTest := errors.New("err test")
+	r := circuitbreaker.NewRegistry(nil)
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	p.WindowType = circuitbreaker.CountBasedWindow
+	p.MinimumCalls = 2
+	p.Errors = []error{errTest}
+
+	m := runWindow(p, errTest)
+	assert.Equal(t, 1, m.FailedCalls)
+
+	assert.Nil(t, r.Reset("service"))
+	m = runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, 1, m.Calls)
+}
+
+func TestRegistryRemove(t *testing.T) {
+	store := circuitbreaker.NewMemoryStore()
+	r := circuitbreaker.NewRegistry(store)
+	assert.ErrorIs(t, r.Remove("service"), circuitbreaker.ErrCircuitBreakerNotFound)
+
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	openCircuit(t, p)
+
+	assert.Nil(t, r.Remove("service"))
+	_, err := r.State(p)
+	assert.ErrorIs(t, err, circuitbreaker.ErrCircuitBreakerNotFound)
+	assert.Empty(t, r.List())
+	assert.Empty(t, store.Keys())
+}
+
+func TestRegistryList(t *testing.T) {
+	store := circuitbreaker.NewMemoryStore()
+	store.Save("service-c", circuitbreaker.CircuitBreaker{})
+	r := circuitbreaker.NewRegistry(store)
+	assert.Equal(t, []string{"service-c"}, r.List())
+
+	for _, id := range []string{"service-b", "service-a"} {
+		p := circuitbreaker.New(id)
+		p.Registry = r
+		p.Command = func() error { return nil }
+		_ = p.Run(core.NewMetric())
+	}
+
+	assert.Equal(t, []string{"service-a", "service-b", "service-c"}, r.List())
+}
+
+func TestRegistryStore(t *testing.T) {
+	store := circuitbreaker.NewMemoryStore()
+	clock := core.NewFakeClock(time.Now())
+	store.Save("service", circuitbreaker.CircuitBreaker{
+		State:            circuitbreaker.OpenState,
+		TimeErrorOcurred: clock.Now(),
+		ErrorCount:       2,
+		HalfOpenCalls:    1,
+	})
+
+	r := circuitbreaker.NewRegistry(store)
+	p := circuitbreaker.New("service")
+	p.Registry = r
+	p.Clock = clock
+	p.Command = func() error { return nil }
+
+	assert.ErrorIs(t, p.Run(core.NewMetric()), circuitbreaker.ErrCircuitIsOpen)
+
+	clock.Advance(p.ResetTimeout)
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	status, ok := store.Load("service")
+	assert.True(t, ok)
+	assert.Equal(t, circuitbreaker.ClosedState, status.State)
+	assert.Equal(t, 0, status.ErrorCount)
+	assert.Equal(t, 0, status.HalfOpenCalls)
+}
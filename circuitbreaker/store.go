@@ -0,0 +1,74 @@
+package circuitbreaker
+
+import (
+	"sort"
+	"sync"
+)
+
+// StateStore is the interface of the storage of circuit breaker statuses. A Registry saves the status of
+// a service whenever it changes and loads it back the first time the service is used.
+// Implementations must be safe for concurrent use.
+type StateStore interface {
+	// Load returns the status of the service and whether there is one.
+	Load(serviceID string) (CircuitBreaker, bool)
+
+	// Save stores the status of the service.
+	Save(serviceID string, status CircuitBreaker)
+
+	// Delete removes the status of the service.
+	Delete(serviceID string)
+
+	// Keys returns the ids of the services with a status stored.
+	Keys() []string
+}
+
+// MemoryStore is a StateStore which keeps statuses in memory. It is the default store of a Registry.
+type MemoryStore struct {
+	mu       sync.Mutex
+	statuses map[string]CircuitBreaker
+}
+
+// NewMemoryStore creates an empty in-memory state store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{statuses: make(map[string]CircuitBreaker)}
+}
+
+// Load returns the status of the service and whether there is one.
+func (s *MemoryStore) Load(serviceID string) (CircuitBreaker, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	status, ok := s.statuses[serviceID]
+
+	return status, ok
+}
+
+// Save stores the status of the service.
+func (s *MemoryStore) Save(serviceID string, status CircuitBreaker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.statuses[serviceID] = status
+}
+
+// Delete removes the status of the service.
+func (s *MemoryStore) Delete(serviceID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.statuses, serviceID)
+}
+
+// Keys returns the ids of the services with a status stored, sorted.
+func (s *MemoryStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.statuses))
+	for key := range s.statuses {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,30 @@
+package circuitbreaker_test
+
+import (
+	"testing"
+
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := circuitbreaker.NewMemoryStore()
+
+	_, ok := store.Load("service-a")
+	assert.False(t, ok)
+	assert.Empty(t, store.Keys())
+
+	store.Save("service-b", circuitbreaker.CircuitBreaker{State: circuitbreaker.OpenState, ErrorCount: 3})
+	store.Save("service-a", circuitbreaker.CircuitBreaker{})
+
+	status, ok := store.Load("service-b")
+	assert.True(t, ok)
+	assert.Equal(t, circuitbreaker.OpenState, status.State)
+	assert.Equal(t, 3, status.ErrorCount)
+	assert.Equal(t, []string{"service-a", "service-b"}, store.Keys())
+
+	store.Delete("service-b")
+	_, ok = store.Load("service-b")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"service-a"}, store.Keys())
+}
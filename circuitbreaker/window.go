@@ -0,0 +1,161 @@
+package circuitbreaker
+
+import "time"
+
+// WindowType is the way a circuit breaker decides to open the circuit.
+type WindowType int
+
+const (
+	// Indicates that the circuit opens once ThresholdErrors errors occurred since it was closed.
+	ErrorCountWindow = WindowType(0)
+
+	// Indicates that the circuit opens on the failure rate of the last WindowSize calls.
+	CountBasedWindow = WindowType(1)
+
+	// Indicates that the circuit opens on the failure rate of the calls made within the last WindowDuration.
+	TimeBasedWindow = WindowType(2)
+)
+
+const windowBuckets = 10
+
+type windowStats struct {
+	calls  int
+	failed int
+	slow   int
+}
+
+type outcome struct {
+	failed bool
+	slow   bool
+}
+
+type slidingWindow interface {
+	record(now time.Time, o outcome)
+	stats(now time.Time) windowStats
+}
+
+type countWindow struct {
+	outcomes []outcome
+	next     int
+	size     int
+	total    windowStats
+}
+
+type timeBucket struct {
+	start time.Time
+	windowStats
+}
+
+type timeWindow struct {
+	duration time.Duration
+	buckets  [windowBuckets]timeBucket
+}
+
+func newWindow(p Policy) slidingWindow {
+	switch p.WindowType {
+	case CountBasedWindow:
+		return &countWindow{outcomes: make([]outcome, p.WindowSize)}
+	case TimeBasedWindow:
+		return &timeWindow{duration: p.WindowDuration}
+	default:
+		return nil
+	}
+}
+
+// sameWindow tells whether the window still fits the policy, which may have been changed between calls.
+func sameWindow(p Policy, w slidingWindow) bool {
+	switch w := w.(type) {
+	case *countWindow:
+		return p.WindowType == CountBasedWindow && len(w.outcomes) == p.WindowSize
+	case *timeWindow:
+		return p.WindowType == TimeBasedWindow && w.duration == p.WindowDuration
+	default:
+		return p.WindowType == ErrorCountWindow
+	}
+}
+
+func (w *countWindow) record(_ time.Time, o outcome) {
+	if w.size == len(w.outcomes) {
+		w.total.remove(w.outcomes[w.next])
+	} else {
+		w.size++
+	}
+
+	w.outcomes[w.next] = o
+	w.next = (w.next + 1) % len(w.outcomes)
+	w.total.add(o)
+}
+
+func (w *countWindow) stats(_ time.Time) windowStats {
+	return w.total
+}
+
+func (w *timeWindow) record(now time.Time, o outcome) {
+	w.bucket(now).add(o)
+}
+
+func (w *timeWindow) stats(now time.Time) windowStats {
+	total := windowStats{}
+
+	for _, bucket := range w.buckets {
+		if !bucket.start.IsZero() && now.Sub(bucket.start) < w.duration {
+			total.calls += bucket.calls
+			total.failed += bucket.failed
+			total.slow += bucket.slow
+		}
+	}
+
+	return total
+}
+
+func (w *timeWindow) bucket(now time.Time) *timeBucket {
+	width := int64(w.duration / windowBuckets)
+	if width == 0 {
+		width = 1
+	}
+	slot := now.UnixNano() / width
+	start := time.Unix(0, slot*width)
+
+	bucket := &w.buckets[slot%windowBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = timeBucket{start: start}
+	}
+
+	return bucket
+}
+
+func (s *windowStats) add(o outcome) {
+	s.calls++
+	if o.failed {
+		s.failed++
+	}
+	if o.slow {
+		s.slow++
+	}
+}
+
+func (s *windowStats) remove(o outcome) {
+	s.calls--
+	if o.failed {
+		s.failed--
+	}
+	if o.slow {
+		s.slow--
+	}
+}
+
+func (s windowStats) failureRate() float64 {
+	return s.rate(s.failed)
+}
+
+func (s windowStats) slowCallRate() float64 {
+	return s.rate(s.slow)
+}
+
+func (s windowStats) rate(count int) float64 {
+	if s.calls == 0 {
+		return 0
+	}
+
+	return float64(count) * 100 / float64(s.calls)
+}
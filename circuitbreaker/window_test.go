@@ -0,0 +1,269 @@
+package circuitbreaker_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func runWindow(p circuitbreaker.Policy, err error) circuitbreaker.Metric {
+	p.Command = func() error { return err }
+	metric := core.NewMetric()
+	_ = p.Run(metric)
+
+	i := metric[reflect.TypeOf(circuitbreaker.Metric{}).String()]
+	m, _ := i.(circuitbreaker.Metric)
+
+	return m
+}
+
+func TestRunValidatePolicyWindow(t *testing.T) {
+	tcs := []struct {
+		name   string
+		change func(p *circuitbreaker.Policy)
+		err    error
+	}{
+		{"window size", func(p *circuitbreaker.Policy) { p.WindowSize = 0 }, circuitbreaker.ErrWindowSizeValidation},
+		{
+			"window duration",
+			func(p *circuitbreaker.Policy) {
+				p.WindowType = circuitbreaker.TimeBasedWindow
+				p.WindowDuration = time.Millisecond
+			},
+			circuitbreaker.ErrWindowDurationValidation,
+		},
+		{
+			"failure rate zero",
+			func(p *circuitbreaker.Policy) { p.FailureRateThreshold = 0 },
+			circuitbreaker.ErrFailureRateThresholdValidation,
+		},
+		{
+			"failure rate above 100",
+			func(p *circuitbreaker.Policy) { p.FailureRateThreshold = 100.1 },
+			circuitbreaker.ErrFailureRateThresholdValidation,
+		},
+		{"minimum calls", func(p *circuitbreaker.Policy) { p.MinimumCalls = 0 }, circuitbreaker.ErrMinimumCallsValidation},
+		{
+			"slow call duration",
+			func(p *circuitbreaker.Policy) { p.SlowCallDuration = -1 },
+			circuitbreaker.ErrSlowCallDurationValidation,
+		},
+		{
+			"slow call rate",
+			func(p *circuitbreaker.Policy) {
+				p.SlowCallDuration = time.Second
+				p.SlowCallRateThreshold = 0
+			},
+			circuitbreaker.ErrSlowCallRateThresholdValidation,
+		},
+		{
+			"slow call without window",
+			func(p *circuitbreaker.Policy) {
+				p.WindowType = circuitbreaker.ErrorCountWindow
+				p.SlowCallDuration = time.Second
+			},
+			circuitbreaker.ErrSlowCallWindowValidation,
+		},
+	}
+
+	for _, tc := range tcs {
+		t.Run(tc.name, func(t *testing.T) {
+			p := circuitbreaker.New("window-validation")
+			p.WindowType = circuitbreaker.CountBasedWindow
+			p.Command = func() error { return nil }
+			tc.change(&p)
+
+			assert.ErrorIs(t, p.Run(core.NewMetric()), tc.err)
+		})
+	}
+}
+
+func TestRunCountBasedWindow(t *testing.T) {
+	errTest := errors.New("err test")
+	p := circuitbreaker.New("count-based-window")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.WindowType = circuitbreaker.CountBasedWindow
+	p.WindowSize = 4
+	p.MinimumCalls = 4
+	p.FailureRateThreshold = 50
+	p.Errors = []error{errTest}
+
+	runWindow(p, nil)
+	runWindow(p, errTest)
+	m := runWindow(p, nil)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, 3, m.Calls)
+	assert.Equal(t, 1, m.FailedCalls)
+
+	m = runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+	assert.Equal(t, 4, m.Calls)
+	assert.Equal(t, 2, m.FailedCalls)
+	assert.Equal(t, 50.0, m.FailureRate)
+	assert.Equal(t, circuitbreaker.FailureRateTrip, m.TripReason)
+}
+
+func TestRunCountBasedWindowSlides(t *testing.T) {
+	errTest := errors.New("err test")
+	p := circuitbreaker.New("count-based-window-slides")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.WindowType = circuitbreaker.CountBasedWindow
+	p.WindowSize = 4
+	p.MinimumCalls = 4
+	p.FailureRateThreshold = 50
+	p.Errors = []error{errTest}
+
+	runWindow(p, errTest)
+	for i := 0; i < 4; i++ {
+		runWindow(p, nil)
+	}
+
+	m := runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, 4, m.Calls)
+	assert.Equal(t, 1, m.FailedCalls)
+	assert.Equal(t, 25.0, m.FailureRate)
+}
+
+func TestRunTimeBasedWindow(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Date(2023, 5, 10, 8, 0, 0, 0, time.UTC))
+	p := circuitbreaker.New("time-based-window")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.WindowType = circuitbreaker.TimeBasedWindow
+	p.WindowDuration = time.Second * 10
+	p.MinimumCalls = 2
+	p.FailureRateThreshold = 60
+	p.Errors = []error{errTest}
+	p.Clock = clock
+
+	m := runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, 1, m.Calls)
+
+	// Errors spread over time don't trip the circuit.
+	clock.Advance(time.Second * 11)
+	m = runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, 1, m.Calls)
+	assert.Equal(t, 2, m.ErrorCount)
+
+	m = runWindow(p, nil)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, 2, m.Calls)
+
+	clock.Advance(time.Second)
+	m = runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+	assert.Equal(t, 3, m.Calls)
+	assert.Equal(t, 2, m.FailedCalls)
+}
+
+func TestRunWindowResetOnClose(t *testing.T) {
+	errTest := errors.New("err test")
+	clock := core.NewFakeClock(time.Now())
+	p := circuitbreaker.New("window-reset-on-close")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.WindowType = circuitbreaker.CountBasedWindow
+	p.MinimumCalls = 1
+	p.Errors = []error{errTest}
+	p.Clock = clock
+
+	m := runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+
+	clock.Advance(p.ResetTimeout)
+	m = runWindow(p, nil)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, 0, m.Calls)
+	assert.Equal(t, 0, m.FailedCalls)
+	assert.Equal(t, 0.0, m.FailureRate)
+}
+
+func TestRunSlowCallRate(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	var (
+		reason circuitbreaker.TripReason
+		cause  error
+	)
+
+	p := circuitbreaker.New("slow-call-rate")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.WindowType = circuitbreaker.CountBasedWindow
+	p.WindowSize = 4
+	p.MinimumCalls = 4
+	p.SlowCallDuration = time.Second
+	p.SlowCallRateThreshold = 50
+	p.Clock = clock
+	p.OnOpenCircuit = func(p circuitbreaker.Policy, status *circuitbreaker.CircuitBreaker, err error) {
+		reason = status.TripReason
+		cause = err
+	}
+
+	run := func(d time.Duration) circuitbreaker.Metric {
+		p.Command = func() error {
+			clock.Advance(d)
+			return nil
+		}
+		metric := core.NewMetric()
+		_ = p.Run(metric)
+
+		m, _ := metric[reflect.TypeOf(circuitbreaker.Metric{}).String()].(circuitbreaker.Metric)
+		return m
+	}
+
+	m := run(time.Second)
+	assert.True(t, m.Slow)
+	m = run(time.Millisecond * 999)
+	assert.False(t, m.Slow)
+	m = run(0)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, 3, m.Calls)
+	assert.Equal(t, 1, m.SlowCalls)
+	assert.Equal(t, 0, m.FailedCalls)
+
+	m = run(time.Second * 20)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+	assert.Equal(t, 2, m.SlowCalls)
+	assert.Equal(t, 50.0, m.SlowCallRate)
+	assert.Equal(t, circuitbreaker.SlowCallRateTrip, m.TripReason)
+	assert.True(t, m.Success())
+	assert.Equal(t, circuitbreaker.SlowCallRateTrip, reason)
+	assert.ErrorIs(t, cause, circuitbreaker.ErrSlowCallRateExceeded)
+}
+
+func TestRunTripReason(t *testing.T) {
+	errTest := errors.New("err test")
+	errUnknown := errors.New("unknown")
+	clock := core.NewFakeClock(time.Now())
+
+	p := circuitbreaker.New("trip-reason")
+	p.Registry = circuitbreaker.NewRegistry(nil)
+	p.ThresholdErrors = 1
+	p.Errors = []error{errTest}
+	p.Clock = clock
+
+	runWindow(p, errTest)
+	m := runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+	assert.Equal(t, circuitbreaker.ErrorThresholdTrip, m.TripReason)
+
+	clock.Advance(p.ResetTimeout)
+	m = runWindow(p, errTest)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+	assert.Equal(t, circuitbreaker.FailedProbeTrip, m.TripReason)
+
+	clock.Advance(p.ResetTimeout)
+	m = runWindow(p, nil)
+	assert.Equal(t, circuitbreaker.ClosedState, m.State)
+	assert.Equal(t, circuitbreaker.NoTrip, m.TripReason)
+
+	m = runWindow(p, errUnknown)
+	assert.Equal(t, circuitbreaker.OpenState, m.State)
+	assert.Equal(t, circuitbreaker.UnhandledErrorTrip, m.TripReason)
+}
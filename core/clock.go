@@ -0,0 +1,277 @@
+package core
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock is the interface that policies use to tell and wait time. It allows tests to
+// replace real time with a FakeClock.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// After waits for the duration to elapse and then sends the current time on the returned channel.
+	After(d time.Duration) <-chan time.Time
+
+	// Sleep pauses the current goroutine for the duration.
+	Sleep(d time.Duration)
+
+	// NewTimer creates a new Timer that will send the current time on its channel after the duration.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the interface of a single event timer created by a Clock.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+
+	// Stop prevents the Timer from firing. It returns false if the timer has already expired or been stopped.
+	Stop() bool
+
+	// Reset changes the timer to expire after the duration. It returns true if the timer had been active.
+	Reset(d time.Duration) bool
+}
+
+// RealClock is the Clock backed by the time package.
+type RealClock struct{}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+// FakeClock is a Clock whose time only moves when told to. Timers fire as time is advanced.
+type FakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	c        chan time.Time
+	deadline time.Time
+}
+
+// ClockOrDefault returns the given clock or a RealClock if it is nil.
+func ClockOrDefault(clock Clock) Clock {
+	if clock == nil {
+		return RealClock{}
+	}
+
+	return clock
+}
+
+// SleepContext pauses the current goroutine for the duration according to the clock,
+// or until ctx is done.
+//
+// Return: ctx.Err() if the sleep was interrupted, nil otherwise.
+func SleepContext(ctx context.Context, clock Clock, d time.Duration) error {
+	timer := ClockOrDefault(clock).NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// WithTimeout derives a context from ctx which is canceled once the duration elapses according
+// to the clock. With a RealClock it is the same as context.WithTimeout, otherwise the context
+// carries no deadline, but its error and its cause are context.DeadlineExceeded all the same.
+//
+// Return: the derived context and its cancel function.
+func WithTimeout(ctx context.Context, clock Clock, d time.Duration) (context.Context, context.CancelFunc) {
+	clock = ClockOrDefault(clock)
+	if _, ok := clock.(RealClock); ok {
+		return context.WithTimeout(ctx, d)
+	}
+
+	cctx, cancel := context.WithCancelCause(ctx)
+	tctx := &timeoutContext{Context: cctx, done: make(chan struct{})}
+	timer := clock.NewTimer(d)
+
+	go func() {
+		select {
+		case <-timer.C():
+			tctx.err = context.DeadlineExceeded
+			cancel(context.DeadlineExceeded)
+		case <-cctx.Done():
+			timer.Stop()
+			tctx.err = cctx.Err()
+		}
+		close(tctx.done)
+	}()
+
+	return tctx, func() {
+		cancel(context.Canceled)
+		<-tctx.done
+	}
+}
+
+// timeoutContext is the context made by WithTimeout for a clock other than RealClock. It has a done
+// channel of its own, so that the contexts derived from it take their error from it too.
+type timeoutContext struct {
+	context.Context
+	done chan struct{}
+	err  error
+}
+
+func (c *timeoutContext) Done() <-chan struct{} {
+	return c.done
+}
+
+func (c *timeoutContext) Err() error {
+	select {
+	case <-c.done:
+		return c.err
+	default:
+		return nil
+	}
+}
+
+// Now returns time.Now().
+func (RealClock) Now() time.Time {
+	return time.Now()
+}
+
+// After returns time.After(d).
+func (RealClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+// Sleep calls time.Sleep(d).
+func (RealClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+// NewTimer returns a Timer backed by time.NewTimer(d).
+func (RealClock) NewTimer(d time.Duration) Timer {
+	return realTimer{timer: time.NewTimer(d)}
+}
+
+func (t realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t realTimer) Reset(d time.Duration) bool {
+	return t.timer.Reset(d)
+}
+
+// NewFakeClock creates a fake clock set to the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	return &FakeClock{now: now}
+}
+
+// Now returns the fake current time.
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// After returns a channel which receives the fake time once it is advanced by the duration.
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Sleep blocks until the fake time is advanced by the duration.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.After(d)
+}
+
+// NewTimer creates a timer which fires once the fake time is advanced by the duration.
+func (c *FakeClock) NewTimer(d time.Duration) Timer {
+	t := &fakeTimer{clock: c, c: make(chan time.Time, 1)}
+	t.Reset(d)
+
+	return t
+}
+
+// Advance moves the fake time forward, firing every timer that expires meanwhile.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	c.fire()
+}
+
+// Timers returns the number of timers waiting to fire.
+func (c *FakeClock) Timers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.timers)
+}
+
+// BlockUntil blocks until at least n timers are waiting to fire. It lets a test wait for
+// goroutines to sleep before advancing time.
+func (c *FakeClock) BlockUntil(n int) {
+	for c.Timers() < n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (c *FakeClock) fire() {
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].deadline.Before(c.timers[j].deadline)
+	})
+
+	pending := c.timers[:0]
+	for _, t := range c.timers {
+		if t.deadline.After(c.now) {
+			pending = append(pending, t)
+			continue
+		}
+
+		select {
+		case t.c <- c.now:
+		default:
+		}
+	}
+	c.timers = pending
+}
+
+func (c *FakeClock) remove(t *fakeTimer) bool {
+	for i, timer := range c.timers {
+		if timer == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	return t.clock.remove(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+
+	active := t.clock.remove(t)
+	t.deadline = t.clock.now.Add(d)
+	t.clock.timers = append(t.clock.timers, t)
+	t.clock.fire()
+
+	return active
+}
@@ -0,0 +1,182 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClockOrDefault(t *testing.T) {
+	assert.Equal(t, core.RealClock{}, core.ClockOrDefault(nil))
+
+	clock := core.NewFakeClock(time.Now())
+	assert.Same(t, clock, core.ClockOrDefault(clock))
+}
+
+func TestRealClock(t *testing.T) {
+	clock := core.RealClock{}
+	started := clock.Now()
+
+	clock.Sleep(time.Millisecond)
+	<-clock.After(time.Millisecond)
+	timer := clock.NewTimer(time.Millisecond)
+	<-timer.C()
+
+	assert.False(t, timer.Stop())
+	assert.False(t, timer.Reset(time.Hour))
+	assert.True(t, timer.Stop())
+	assert.GreaterOrEqual(t, time.Since(started), time.Millisecond*3)
+}
+
+func TestFakeClockNow(t *testing.T) {
+	now := time.Date(2023, 5, 10, 8, 0, 0, 0, time.UTC)
+	clock := core.NewFakeClock(now)
+	assert.Equal(t, now, clock.Now())
+
+	clock.Advance(time.Minute)
+	assert.Equal(t, now.Add(time.Minute), clock.Now())
+}
+
+func TestFakeClockTimer(t *testing.T) {
+	now := time.Date(2023, 5, 10, 8, 0, 0, 0, time.UTC)
+	clock := core.NewFakeClock(now)
+	timer := clock.NewTimer(time.Second)
+	assert.Equal(t, 1, clock.Timers())
+
+	clock.Advance(time.Millisecond * 999)
+	select {
+	case <-timer.C():
+		assert.Fail(t, "timer fired too soon")
+	default:
+	}
+
+	clock.Advance(time.Millisecond)
+	assert.Equal(t, now.Add(time.Second), <-timer.C())
+	assert.Equal(t, 0, clock.Timers())
+	assert.False(t, timer.Stop())
+}
+
+func TestFakeClockTimerStop(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	timer := clock.NewTimer(time.Second)
+
+	assert.True(t, timer.Stop())
+	assert.False(t, timer.Stop())
+
+	clock.Advance(time.Hour)
+	select {
+	case <-timer.C():
+		assert.Fail(t, "stopped timer fired")
+	default:
+	}
+}
+
+func TestFakeClockTimerReset(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	timer := clock.NewTimer(time.Second)
+
+	assert.True(t, timer.Reset(time.Minute))
+	clock.Advance(time.Second)
+	assert.Equal(t, 1, clock.Timers())
+
+	clock.Advance(time.Minute)
+	<-timer.C()
+	assert.False(t, timer.Reset(0))
+	<-timer.C()
+}
+
+func TestFakeClockSleep(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	done := make(chan struct{})
+
+	go func() {
+		clock.Sleep(time.Hour)
+		close(done)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	<-done
+}
+
+func TestSleepContext(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	c := make(chan error, 1)
+
+	go func() {
+		c <- core.SleepContext(context.Background(), clock, time.Hour)
+	}()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	assert.Nil(t, <-c)
+}
+
+func TestSleepContextCanceled(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	c := make(chan error, 1)
+
+	go func() {
+		c <- core.SleepContext(ctx, clock, time.Hour)
+	}()
+
+	clock.BlockUntil(1)
+	cancel()
+	assert.ErrorIs(t, <-c, context.Canceled)
+	assert.Equal(t, 0, clock.Timers())
+}
+
+func TestWithTimeoutRealClock(t *testing.T) {
+	ctx, cancel := core.WithTimeout(context.Background(), nil, time.Millisecond)
+	defer cancel()
+
+	_, ok := ctx.Deadline()
+	assert.True(t, ok)
+
+	<-ctx.Done()
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+}
+
+func TestWithTimeoutFakeClock(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	ctx, cancel := core.WithTimeout(context.Background(), clock, time.Second)
+	defer cancel()
+
+	assert.Nil(t, ctx.Err())
+	clock.Advance(time.Second)
+
+	<-ctx.Done()
+	assert.Equal(t, context.DeadlineExceeded, ctx.Err())
+	assert.ErrorIs(t, context.Cause(ctx), context.DeadlineExceeded)
+}
+
+func TestWithTimeoutFakeClockDerived(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	ctx, cancel := core.WithTimeout(context.Background(), clock, time.Second)
+	defer cancel()
+
+	child, cancelChild := context.WithCancel(ctx)
+	defer cancelChild()
+	clock.Advance(time.Second)
+
+	<-child.Done()
+	assert.Equal(t, context.DeadlineExceeded, child.Err())
+}
+
+func TestWithTimeoutFakeClockCanceled(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	ctx, cancel := core.WithTimeout(context.Background(), clock, time.Second)
+	clock.BlockUntil(1)
+
+	cancel()
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.ErrorIs(t, context.Cause(ctx), context.Canceled)
+
+	for clock.Timers() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -1,7 +1,10 @@
 package core
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"reflect"
 	"time"
 )
 
@@ -9,6 +12,10 @@ import (
 // pointer to an anonymous function.
 type Command func() error
 
+// CommandContext is the context-aware counterpart of Command. The context passed to it
+// is canceled when the caller gives up or when a policy (e.g. timeout) aborts the execution.
+type CommandContext func(ctx context.Context) error
+
 // PolicySupplier is the interface that all Policies must implement. Its contract
 // defines some obligations that every policy must follow.
 type PolicySupplier interface {
@@ -22,6 +29,19 @@ type PolicySupplier interface {
 	WithPolicy(policy PolicySupplier) PolicySupplier
 }
 
+// ContextPolicySupplier is the interface that context-aware Policies implement. It extends
+// PolicySupplier so that a caller's cancellation or deadline is propagated through the chain.
+type ContextPolicySupplier interface {
+	PolicySupplier
+
+	// RunContext is the method's definition of a running policy bound to a context.
+	RunContext(ctx context.Context, metric Metric) error
+
+	// WithCommandContext is the method's definition of a policy encapsulation with the
+	// context-aware command supplier.
+	WithCommandContext(command CommandContext) PolicySupplier
+}
+
 // MetricRecorder is the interface that all Metrics must implement. It defines some behaviors that
 // metrics are expected to be.
 type MetricRecorder interface {
@@ -93,7 +113,38 @@ func (m Metric) MetricError() error {
 	return nil
 }
 
+// ErrorMatcher is the interface that an expected error implements when it decides by itself
+// whether an error matches it.
+type ErrorMatcher interface {
+	error
+
+	// Match returns whether err matches this expected error.
+	Match(err error) bool
+}
+
+type typeMatchError[T error] struct{}
+
+// ErrorType makes an expected error which matches any error of type T found in the
+// error tree (see errors.As).
+//
+// Return: the expected error.
+func ErrorType[T error]() error {
+	return typeMatchError[T]{}
+}
+
+func (typeMatchError[T]) Error() string {
+	return fmt.Sprintf("error of type %s", reflect.TypeOf((*T)(nil)).Elem())
+}
+
+func (typeMatchError[T]) Match(err error) bool {
+	var target T
+	return errors.As(err, &target)
+}
+
 // ErrorInErrors Verifies that an error is a slice of expected errors.
+// The whole error tree is inspected, so wrapped errors (fmt.Errorf with %w) and
+// joined errors (errors.Join) match their expected errors too. Expected errors
+// which implement ErrorMatcher decide by themselves (see ErrorType).
 //
 // Return: whether err is in expectedErrors.
 func ErrorInErrors(expectedErrors []error, err error) bool {
@@ -104,10 +155,60 @@ func ErrorInErrors(expectedErrors []error, err error) bool {
 	}
 
 	for _, expectedError := range expectedErrors {
-		if errors.Is(expectedError, err) {
+		if matcher, ok := expectedError.(ErrorMatcher); ok {
+			if matcher.Match(err) {
+				return true
+			}
+		} else if errors.Is(err, expectedError) {
 			return true
 		}
 	}
 
 	return false
 }
+
+// ContextCommand adapts a command supplier to the context-aware signature.
+// The context is ignored by the adapted command.
+//
+// Return: the adapted command or nil if command is nil.
+func ContextCommand(command Command) CommandContext {
+	if command == nil {
+		return nil
+	}
+
+	return func(context.Context) error {
+		return command()
+	}
+}
+
+// RunPolicy runs a policy with the given context. Policies that don't implement
+// ContextPolicySupplier are run without it.
+//
+// Return: the error returned by the policy.
+func RunPolicy(ctx context.Context, policy PolicySupplier, metric Metric) error {
+	if p, ok := policy.(ContextPolicySupplier); ok {
+		return p.RunContext(ctx, metric)
+	}
+
+	return policy.Run(metric)
+}
+
+// BindCommand encapsulates a policy with a context-aware command supplier, clearing any
+// plain command it had. Policies that don't implement ContextPolicySupplier receive a plain
+// command which calls the supplier with ctx.
+//
+// Return: the new policy.
+func BindCommand(ctx context.Context, policy PolicySupplier, command CommandContext) PolicySupplier {
+	policy = policy.WithCommand(nil)
+	if p, ok := policy.(ContextPolicySupplier); ok {
+		return p.WithCommandContext(command)
+	}
+
+	if command == nil {
+		return policy
+	}
+
+	return policy.WithCommand(func() error {
+		return command(ctx)
+	})
+}
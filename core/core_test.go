@@ -1,6 +1,8 @@
 package core_test
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
@@ -110,3 +112,161 @@ func TestErrorInErrorsNotFound(t *testing.T) {
 	assert.False(t, core.ErrorInErrors(errs, fmt.Errorf("any")))
 	assert.False(t, core.ErrorInErrors(errs, fmt.Errorf("e1")))
 }
+
+type statusError struct {
+	code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d", e.code)
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string {
+	return "timeout"
+}
+
+func TestErrorInErrorsTree(t *testing.T) {
+	errUnavailable := errors.New("service unavailable")
+	errNotFound := errors.New("not found")
+
+	tests := []struct {
+		name     string
+		expected []error
+		err      error
+		want     bool
+	}{
+		{name: "nil", expected: []error{errUnavailable}, err: nil, want: true},
+		{name: "same", expected: []error{errUnavailable}, err: errUnavailable, want: true},
+		{name: "wrapped", expected: []error{errUnavailable}, err: fmt.Errorf("call: %w", errUnavailable), want: true},
+		{
+			name:     "wrapped twice",
+			expected: []error{errNotFound, errUnavailable},
+			err:      fmt.Errorf("retry: %w", fmt.Errorf("call: %w", errUnavailable)),
+			want:     true,
+		},
+		{name: "joined", expected: []error{errUnavailable}, err: errors.Join(errNotFound, errUnavailable), want: true},
+		{
+			name:     "multiple %w",
+			expected: []error{errNotFound},
+			err:      fmt.Errorf("%w: %w", errUnavailable, errNotFound),
+			want:     true,
+		},
+		{name: "wrapped not expected", expected: []error{errNotFound}, err: fmt.Errorf("call: %w", errUnavailable)},
+		{name: "not wrapped", expected: []error{errUnavailable}, err: fmt.Errorf("call: %v", errUnavailable)},
+		{name: "expected is wrapper", expected: []error{fmt.Errorf("call: %w", errUnavailable)}, err: errUnavailable},
+		{name: "pointer type", expected: []error{core.ErrorType[*statusError]()}, err: &statusError{code: 503}, want: true},
+		{
+			name:     "wrapped pointer type",
+			expected: []error{core.ErrorType[*statusError]()},
+			err:      fmt.Errorf("call: %w", &statusError{code: 503}),
+			want:     true,
+		},
+		{
+			name:     "joined value type",
+			expected: []error{errNotFound, core.ErrorType[timeoutError]()},
+			err:      errors.Join(errUnavailable, timeoutError{}),
+			want:     true,
+		},
+		{name: "other type", expected: []error{core.ErrorType[*statusError]()}, err: timeoutError{}},
+		{
+			name:     "type and value",
+			expected: []error{core.ErrorType[*statusError](), errNotFound},
+			err:      errNotFound,
+			want:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, core.ErrorInErrors(tt.expected, tt.err))
+		})
+	}
+}
+
+func TestErrorType(t *testing.T) {
+	err := core.ErrorType[*statusError]()
+
+	assert.Equal(t, "error of type *core_test.statusError", err.Error())
+	assert.Implements(t, (*core.ErrorMatcher)(nil), err)
+}
+
+type ctxKey struct{}
+
+type plainPolicy struct {
+	command core.Command
+}
+
+func (p plainPolicy) Run(_ core.Metric) error {
+	return p.command()
+}
+
+func (p plainPolicy) WithCommand(command core.Command) core.PolicySupplier {
+	p.command = command
+	return p
+}
+
+func (p plainPolicy) WithPolicy(_ core.PolicySupplier) core.PolicySupplier {
+	return p
+}
+
+type contextPolicy struct {
+	plainPolicy
+	commandContext core.CommandContext
+}
+
+func (p contextPolicy) RunContext(ctx context.Context, _ core.Metric) error {
+	return p.commandContext(ctx)
+}
+
+func (p contextPolicy) WithCommand(command core.Command) core.PolicySupplier {
+	p.command = command
+	return p
+}
+
+func (p contextPolicy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.commandContext = command
+	return p
+}
+
+func TestContextCommand(t *testing.T) {
+	assert.Nil(t, core.ContextCommand(nil))
+
+	errTest := fmt.Errorf("any error")
+	cmd := core.ContextCommand(func() error { return errTest })
+
+	assert.ErrorIs(t, cmd(context.Background()), errTest)
+}
+
+func TestBindCommandContextPolicy(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	var value interface{}
+
+	p := core.BindCommand(ctx, contextPolicy{}, func(ctx context.Context) error {
+		value = ctx.Value(ctxKey{})
+		return nil
+	})
+
+	cp, _ := p.(contextPolicy)
+	assert.Nil(t, cp.command)
+	assert.Nil(t, core.RunPolicy(ctx, p, core.NewMetric()))
+	assert.Equal(t, "value", value)
+}
+
+func TestBindCommandPlainPolicy(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "value")
+	var value interface{}
+
+	p := core.BindCommand(ctx, plainPolicy{}, func(ctx context.Context) error {
+		value = ctx.Value(ctxKey{})
+		return nil
+	})
+
+	assert.Nil(t, core.RunPolicy(context.Background(), p, core.NewMetric()))
+	assert.Equal(t, "value", value)
+
+	p = core.BindCommand(ctx, p, nil)
+	pp, _ := p.(plainPolicy)
+	assert.Nil(t, pp.command)
+}
@@ -7,11 +7,23 @@ must follow are in this package.
 
 Command is the type that Policies use as a supplier. Indeed, it is just a pointer to an anonymous function.
 
+# CommandContext
+
+CommandContext is the context-aware counterpart of Command. It receives the context which a policy chain
+is bound to, so the command can react to a caller's cancellation or deadline. ContextCommand adapts a Command
+to this signature.
+
 # PolicySupplier
 
 PolicySupplier is the interface that all Policies must implement. Its contract defines some obligations
 that every policy must follow.
 
+# ContextPolicySupplier
+
+ContextPolicySupplier extends PolicySupplier with RunContext and WithCommandContext. All policies of this
+library implement it. RunPolicy and BindCommand fall back to the plain PolicySupplier methods for policies
+which don't implement it.
+
 # MetricRecorder
 
 MetricRecorder is the interface that all Metrics must implement. It defines some behaviors that metrics
@@ -21,5 +33,52 @@ are expected to be.
 
 Metric is the base metric recorder type. This is the one which is passed through the life cycle of an
 execution chain.
+
+# Expected errors
+
+ErrorInErrors tells whether an error is one of the expected errors of a policy. The whole error tree is
+inspected, so wrapped and joined errors match as well. ErrorType makes an expected error which matches
+by type instead of by value (see errors.As).
+
+	p.Errors = []error{errServiceUnavailable, core.ErrorType[*net.OpError]()}
+
+# Policy errors
+
+Every policy follows the same error contract. Validation errors (e.g. a missing command) are returned as they
+are. Any other failure is returned as a *PolicyError, which tells the policy and the service that failed and
+wraps both the error raised by the policy (e.g. retry.ErrMaxTriesExceeded) and the error of the command supplier
+or of the wrapped policy. Nested policies nest their errors, so errors.Is and errors.As find any of them.
+
+	err := p.Run(core.NewMetric())
+
+	var perr *core.PolicyError
+	if errors.As(err, &perr) {
+		fmt.Println(perr.Policy, perr.ServiceID)
+	}
+
+	if errors.Is(err, errServiceUnavailable) {
+		// The command failed with errServiceUnavailable.
+		...
+	}
+
+Every policy has a SwallowErrors field which brings back the former behavior: the policy returns its bare
+errors, and the errors of the command supplier or of the wrapped policy are only recorded in the metric.
+
+# Clock
+
+Clock is the interface that policies use to tell and wait time. Every policy has a Clock field which
+defaults to RealClock. FakeClock is a clock whose time only moves when Advance is called, so that tests
+don't need to wait in real time.
+
+	clock := core.NewFakeClock(time.Now())
+	p := retry.New("service-id")
+	p.Delay = time.Hour
+	p.Clock = clock
+
+	go p.Run(core.NewMetric())
+
+	// Waits for the retry to sleep and wakes it up.
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
 */
 package core
@@ -0,0 +1,47 @@
+package core
+
+import "fmt"
+
+// PolicyError is the error returned by a policy whose execution failed. It tells which policy of which
+// service failed, why (Err) and the error of the command supplier or of the wrapped policy (Cause).
+// Both are in its error tree, so errors.Is and errors.As see through it.
+type PolicyError struct {
+	// Name of the policy (e.g. "retry").
+	Policy string
+
+	// The registered service id.
+	ServiceID string
+
+	// The error raised by the policy itself (e.g. retry.ErrMaxTriesExceeded), nil if the policy just
+	// propagates Cause.
+	Err error
+
+	// The error of the command supplier or of the wrapped policy, if any.
+	Cause error
+}
+
+// Error returns the policy and service, followed by Err and Cause.
+func (e *PolicyError) Error() string {
+	msg := fmt.Sprintf("%s (%s)", e.Policy, e.ServiceID)
+	if e.Err != nil {
+		msg = fmt.Sprintf("%s: %s", msg, e.Err)
+	}
+	if e.Cause != nil {
+		msg = fmt.Sprintf("%s: %s", msg, e.Cause)
+	}
+
+	return msg
+}
+
+// Unwrap returns Err and Cause (those which are set).
+func (e *PolicyError) Unwrap() []error {
+	errs := make([]error, 0, 2)
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	if e.Cause != nil {
+		errs = append(errs, e.Cause)
+	}
+
+	return errs
+}
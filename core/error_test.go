@@ -0,0 +1,49 @@
+package core_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyErrorError(t *testing.T) {
+	errPolicy := errors.New("max tries reached")
+	errCause := errors.New("service unavailable")
+
+	err := &core.PolicyError{Policy: "retry", ServiceID: "service-id", Err: errPolicy, Cause: errCause}
+	assert.Equal(t, "retry (service-id): max tries reached: service unavailable", err.Error())
+
+	err = &core.PolicyError{Policy: "retry", ServiceID: "service-id", Err: errPolicy}
+	assert.Equal(t, "retry (service-id): max tries reached", err.Error())
+
+	err = &core.PolicyError{Policy: "retry", ServiceID: "service-id", Cause: errCause}
+	assert.Equal(t, "retry (service-id): service unavailable", err.Error())
+}
+
+func TestPolicyErrorUnwrap(t *testing.T) {
+	errPolicy := errors.New("max tries reached")
+	errCause := errors.New("service unavailable")
+
+	err := &core.PolicyError{Policy: "retry", ServiceID: "service-id", Err: errPolicy, Cause: errCause}
+	assert.Equal(t, []error{errPolicy, errCause}, err.Unwrap())
+	assert.Equal(t, []error{errCause}, (&core.PolicyError{Cause: errCause}).Unwrap())
+	assert.Empty(t, (&core.PolicyError{}).Unwrap())
+}
+
+func TestPolicyErrorTree(t *testing.T) {
+	errPolicy := errors.New("circuit is open")
+	errCause := errors.New("service unavailable")
+
+	inner := &core.PolicyError{Policy: "circuitbreaker", ServiceID: "service-id", Err: errPolicy}
+	var err error = &core.PolicyError{Policy: "retry", ServiceID: "service-id", Err: errCause, Cause: inner}
+
+	assert.ErrorIs(t, err, errPolicy)
+	assert.ErrorIs(t, err, errCause)
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "retry", perr.Policy)
+	assert.True(t, core.ErrorInErrors([]error{errPolicy}, err))
+}
@@ -0,0 +1,73 @@
+package core
+
+import (
+	"context"
+	"sync"
+)
+
+// Result keeps the value produced by a result-returning command supplier while it travels
+// through a policy chain. It is carried by the context passed to policies and commands.
+type Result struct {
+	mu    sync.Mutex
+	value any
+	set   bool
+}
+
+type resultKey struct{}
+
+// NewResultContext creates an empty result and binds it to a copy of ctx.
+//
+// Return: the new context and its result.
+func NewResultContext(ctx context.Context) (context.Context, *Result) {
+	r := new(Result)
+	return context.WithValue(ctx, resultKey{}, r), r
+}
+
+// ResultFromContext queries for the result bound to ctx.
+//
+// Return: the result or nil if ctx carries none.
+func ResultFromContext(ctx context.Context) *Result {
+	r, _ := ctx.Value(resultKey{}).(*Result)
+	return r
+}
+
+// SetResult stores a value in the result bound to ctx.
+//
+// Return: whether ctx carries a result where value could be stored.
+func SetResult(ctx context.Context, value any) bool {
+	r := ResultFromContext(ctx)
+	if r == nil {
+		return false
+	}
+	r.Set(value)
+
+	return true
+}
+
+// Set stores a value, replacing any previous one.
+func (r *Result) Set(value any) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.value = value
+	r.set = true
+}
+
+// Clear removes the stored value, as if none was ever stored.
+func (r *Result) Clear() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.value = nil
+	r.set = false
+}
+
+// Get returns the stored value.
+//
+// Return: the value and whether any value was stored.
+func (r *Result) Get() (any, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.value, r.set
+}
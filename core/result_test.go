@@ -0,0 +1,51 @@
+package core_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultFromContextNone(t *testing.T) {
+	assert.Nil(t, core.ResultFromContext(context.Background()))
+	assert.False(t, core.SetResult(context.Background(), "value"))
+}
+
+func TestNewResultContext(t *testing.T) {
+	ctx, r := core.NewResultContext(context.Background())
+	assert.Same(t, r, core.ResultFromContext(ctx))
+
+	v, ok := r.Get()
+	assert.Nil(t, v)
+	assert.False(t, ok)
+
+	assert.True(t, core.SetResult(ctx, "value"))
+	v, ok = r.Get()
+	assert.Equal(t, "value", v)
+	assert.True(t, ok)
+
+	r.Set(nil)
+	v, ok = r.Get()
+	assert.Nil(t, v)
+	assert.True(t, ok)
+
+	r.Clear()
+	v, ok = r.Get()
+	assert.Nil(t, v)
+	assert.False(t, ok)
+}
+
+func TestNewResultContextNested(t *testing.T) {
+	ctx, parent := core.NewResultContext(context.Background())
+	child, r := core.NewResultContext(ctx)
+
+	core.SetResult(child, 10)
+
+	_, ok := parent.Get()
+	assert.False(t, ok)
+
+	v, _ := r.Get()
+	assert.Equal(t, 10, v)
+}
@@ -1,6 +1,9 @@
 package resiliencia
 
 import (
+	"context"
+
+	"github.com/aureliano/resiliencia/bulkhead"
 	"github.com/aureliano/resiliencia/circuitbreaker"
 	"github.com/aureliano/resiliencia/core"
 	"github.com/aureliano/resiliencia/fallback"
@@ -10,11 +13,13 @@ import (
 
 // Decoration is a policy decoration.
 type Decoration struct {
-	Supplier       core.Command
-	Retry          *retry.Policy
-	Timeout        *timeout.Policy
-	Fallback       *fallback.Policy
-	CircuitBreaker *circuitbreaker.Policy
+	Supplier        core.Command
+	SupplierContext core.CommandContext
+	Retry           *retry.Policy
+	Timeout         *timeout.Policy
+	Fallback        *fallback.Policy
+	CircuitBreaker  *circuitbreaker.Policy
+	Bulkhead        *bulkhead.Policy
 }
 
 // Decorator is the interface that teaches how to decorate a command supplier with policies.
@@ -23,9 +28,59 @@ type Decorator interface {
 	WithTimeout(policy timeout.Policy) Decorator
 	WithFallback(policy fallback.Policy) Decorator
 	WithCircuitBreaker(policy circuitbreaker.Policy) Decorator
+	WithBulkhead(policy bulkhead.Policy) Decorator
 	Execute() (core.Metric, error)
 }
 
+// ContextDecoration is a policy decoration of a context-aware command supplier. Its methods return the
+// decoration itself, so that it is still bound to a context once decorated.
+type ContextDecoration Decoration
+
+// WithRetry decorates with a retry policy.
+func (d ContextDecoration) WithRetry(policy retry.Policy) ContextDecoration {
+	d.Retry = &policy
+	return d
+}
+
+// WithTimeout decorates with a timeout policy.
+func (d ContextDecoration) WithTimeout(policy timeout.Policy) ContextDecoration {
+	d.Timeout = &policy
+	return d
+}
+
+// WithFallback decorates with a fallback policy.
+func (d ContextDecoration) WithFallback(policy fallback.Policy) ContextDecoration {
+	d.Fallback = &policy
+	return d
+}
+
+// WithCircuitBreaker decorates with a circuit breaker policy.
+func (d ContextDecoration) WithCircuitBreaker(policy circuitbreaker.Policy) ContextDecoration {
+	d.CircuitBreaker = &policy
+	return d
+}
+
+// WithBulkhead decorates with a bulkhead policy.
+func (d ContextDecoration) WithBulkhead(policy bulkhead.Policy) ContextDecoration {
+	d.Bulkhead = &policy
+	return d
+}
+
+// Execute starts a chain of responsibility with decorated policies (see Decoration.Execute).
+//
+// Returns chained metrics.
+func (d ContextDecoration) Execute() (core.Metric, error) {
+	return Decoration(d).Execute()
+}
+
+// ExecuteContext starts a chain of responsibility with decorated policies bound to a context
+// (see Decoration.ExecuteContext).
+//
+// Returns chained metrics.
+func (d ContextDecoration) ExecuteContext(ctx context.Context) (core.Metric, error) {
+	return Decoration(d).ExecuteContext(ctx)
+}
+
 // WithRetry decorates with a retry policy.
 func (d Decoration) WithRetry(policy retry.Policy) Decorator {
 	d.Retry = &policy
@@ -50,24 +105,44 @@ func (d Decoration) WithCircuitBreaker(policy circuitbreaker.Policy) Decorator {
 	return d
 }
 
+// WithBulkhead decorates with a bulkhead policy.
+func (d Decoration) WithBulkhead(policy bulkhead.Policy) Decorator {
+	d.Bulkhead = &policy
+	return d
+}
+
 // Execute starts a chain of responsibility with decorated policies.
-// Execution order: fallback -> circuit breaker -> retry -> timeout -> command
+// Execution order: fallback -> circuit breaker -> retry -> bulkhead -> timeout -> command
 // That means: fallback starts a circuit breaker and wait its result;
 // circuit breaker starts a retry policy and wait its result;
-// retry starts a timeout and wait its result; timeout calls command and wait
+// retry starts a bulkhead and wait its result (so each try takes its own slot);
+// bulkhead starts a timeout and wait its result; timeout calls command and wait
 // its result.
 //
 // Returns chained metrics.
 func (d Decoration) Execute() (core.Metric, error) {
+	return d.ExecuteContext(context.Background())
+}
+
+// ExecuteContext starts a chain of responsibility with decorated policies bound to a context.
+// Execution order is the same as Execute. The context-aware supplier takes precedence over Supplier.
+//
+// Returns chained metrics.
+func (d Decoration) ExecuteContext(ctx context.Context) (core.Metric, error) {
 	if err := validateDecorator(d); err != nil {
 		return nil, err
 	}
 
-	return Chain(buildPolicyChain(d)...).Execute(d.Supplier)
+	command := d.SupplierContext
+	if command == nil {
+		command = core.ContextCommand(d.Supplier)
+	}
+
+	return ChainContext(buildPolicyChain(d)...).ExecuteContext(ctx, command)
 }
 
 func buildPolicyChain(d Decoration) []core.PolicySupplier {
-	const totalPolicy = 4
+	const totalPolicy = 5
 	policies := make([]core.PolicySupplier, 0, totalPolicy)
 
 	if d.Fallback != nil {
@@ -79,6 +154,9 @@ func buildPolicyChain(d Decoration) []core.PolicySupplier {
 	if d.Retry != nil {
 		policies = append(policies, *d.Retry)
 	}
+	if d.Bulkhead != nil {
+		policies = append(policies, *d.Bulkhead)
+	}
 	if d.Timeout != nil {
 		policies = append(policies, *d.Timeout)
 	}
@@ -90,7 +168,7 @@ func validateDecorator(d Decoration) error {
 	switch {
 	case !anyPolicyProvided(d):
 		return ErrPolicyRequired
-	case d.Supplier == nil:
+	case d.Supplier == nil && d.SupplierContext == nil:
 		return ErrSupplierRequired
 	case anyWrappedPolicyWithCommand(d):
 		return ErrWrappedPolicyWithCommand
@@ -102,16 +180,17 @@ func validateDecorator(d Decoration) error {
 }
 
 func anyPolicyProvided(d Decoration) bool {
-	return d.CircuitBreaker != nil || d.Fallback != nil || d.Retry != nil || d.Timeout != nil
+	return d.CircuitBreaker != nil || d.Fallback != nil || d.Retry != nil || d.Timeout != nil || d.Bulkhead != nil
 }
 
 func anyWrappedPolicyWithCommand(d Decoration) bool {
-	cbCmd := d.CircuitBreaker != nil && d.CircuitBreaker.Command != nil
-	fbCmd := d.Fallback != nil && d.Fallback.Command != nil
-	rtCmd := d.Retry != nil && d.Retry.Command != nil
-	tmCmd := d.Timeout != nil && d.Timeout.Command != nil
+	cbCmd := d.CircuitBreaker != nil && (d.CircuitBreaker.Command != nil || d.CircuitBreaker.CommandContext != nil)
+	fbCmd := d.Fallback != nil && (d.Fallback.Command != nil || d.Fallback.CommandContext != nil)
+	rtCmd := d.Retry != nil && (d.Retry.Command != nil || d.Retry.CommandContext != nil)
+	tmCmd := d.Timeout != nil && (d.Timeout.Command != nil || d.Timeout.CommandContext != nil)
+	bhCmd := d.Bulkhead != nil && (d.Bulkhead.Command != nil || d.Bulkhead.CommandContext != nil)
 
-	return cbCmd || fbCmd || rtCmd || tmCmd
+	return cbCmd || fbCmd || rtCmd || tmCmd || bhCmd
 }
 
 func anyWrappedPolicyWithNestedPolicy(d Decoration) bool {
@@ -119,6 +198,7 @@ func anyWrappedPolicyWithNestedPolicy(d Decoration) bool {
 	fbCmd := d.Fallback != nil && d.Fallback.Policy != nil
 	rtCmd := d.Retry != nil && d.Retry.Policy != nil
 	tmCmd := d.Timeout != nil && d.Timeout.Policy != nil
+	bhCmd := d.Bulkhead != nil && d.Bulkhead.Policy != nil
 
-	return cbCmd || fbCmd || rtCmd || tmCmd
+	return cbCmd || fbCmd || rtCmd || tmCmd || bhCmd
 }
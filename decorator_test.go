@@ -2,11 +2,14 @@ package resiliencia_test
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
 
 	"github.com/aureliano/resiliencia"
+	"github.com/aureliano/resiliencia/bulkhead"
 	"github.com/aureliano/resiliencia/circuitbreaker"
 	"github.com/aureliano/resiliencia/fallback"
 	"github.com/aureliano/resiliencia/retry"
@@ -36,6 +39,25 @@ func TestDecoratorExecuteSupplierRequired(t *testing.T) {
 	assert.ErrorIs(t, err, resiliencia.ErrSupplierRequired)
 }
 
+func TestDecoratorExecuteContextSupplierRequired(t *testing.T) {
+	d := resiliencia.DecorateContext(nil)
+	d = d.WithRetry(retry.New("id"))
+
+	_, err := d.ExecuteContext(context.Background())
+	assert.ErrorIs(t, err, resiliencia.ErrSupplierRequired)
+}
+
+func TestDecoratorExecuteAnyWrappedPolicyWithCommandContext(t *testing.T) {
+	d := resiliencia.Decorate(func() error { return nil })
+	d = d.WithRetry(retry.New("id"))
+	tm := timeout.New("id")
+	tm.CommandContext = func(ctx context.Context) error { return nil }
+	d = d.WithTimeout(tm)
+
+	_, err := d.Execute()
+	assert.ErrorIs(t, err, resiliencia.ErrWrappedPolicyWithCommand)
+}
+
 func TestDecoratorExecuteAnyWrappedPolicyWithCommand(t *testing.T) {
 	d := resiliencia.Decorate(func() error { return nil })
 	d = d.WithRetry(retry.New("id"))
@@ -47,6 +69,16 @@ func TestDecoratorExecuteAnyWrappedPolicyWithCommand(t *testing.T) {
 	assert.ErrorIs(t, err, resiliencia.ErrWrappedPolicyWithCommand)
 }
 
+func TestDecoratorExecuteBulkheadWithCommand(t *testing.T) {
+	d := resiliencia.Decorate(func() error { return nil })
+	bh := bulkhead.New("id")
+	bh.Command = func() error { return nil }
+	d = d.WithBulkhead(bh)
+
+	_, err := d.Execute()
+	assert.ErrorIs(t, err, resiliencia.ErrWrappedPolicyWithCommand)
+}
+
 func TestDecoratorExecuteAnyWrappedPolicyWithNestedPolicy(t *testing.T) {
 	d := resiliencia.Decorate(func() error { return nil })
 	d = d.WithRetry(retry.New("id"))
@@ -406,3 +438,63 @@ func TestDecoratorExecuteTimeout(t *testing.T) {
 	assert.Nil(t, tm.Error)
 	assert.Greater(t, tm.PolicyDuration(), time.Duration(0))
 }
+
+func TestDecoratorExecuteBulkhead(t *testing.T) {
+	id := "service-id"
+	d := resiliencia.Decorate(func() error { return nil })
+	bhp := bulkhead.New(id)
+	bhp.MaxConcurrent = 2
+	d = d.WithBulkhead(bhp)
+
+	metric, err := d.Execute()
+	assert.Nil(t, err)
+
+	r := metric[reflect.TypeOf(bulkhead.Metric{}).String()]
+	bm, _ := r.(bulkhead.Metric)
+
+	assert.Equal(t, "service-id", bm.ID)
+	assert.Equal(t, 0, bm.Status)
+	assert.Equal(t, 1, bm.InFlight)
+	assert.Nil(t, bm.Error)
+}
+
+func TestDecoratorExecuteRetryWithBulkhead(t *testing.T) {
+	id := "service-bulkhead-retry"
+	errTest := errors.New("err test")
+	tries := 0
+	d := resiliencia.Decorate(func() error {
+		tries++
+		inFlight, _ := bulkhead.Stats(id)
+		assert.Equal(t, 1, inFlight)
+
+		return errTest
+	})
+	rtp := retry.New(id)
+	rtp.Tries = 3
+	rtp.Errors = []error{errTest}
+	d = d.WithRetry(rtp).WithBulkhead(bulkhead.New(id))
+
+	metric, err := d.Execute()
+	assert.ErrorIs(t, err, retry.ErrMaxTriesExceeded)
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, 3, tries)
+	assert.Len(t, metric, 2)
+}
+
+func TestDecoratorExecuteContext(t *testing.T) {
+	type key struct{}
+	id := "service-id"
+	ctx := context.WithValue(context.Background(), key{}, id)
+	d := resiliencia.DecorateContext(func(ctx context.Context) error {
+		assert.Equal(t, id, ctx.Value(key{}))
+		return nil
+	})
+	tmp := timeout.New(id)
+	tmp.Timeout = time.Second * 5
+	d = d.WithTimeout(tmp).WithRetry(retry.New(id)).WithCircuitBreaker(circuitbreaker.New(id))
+
+	metric, err := d.ExecuteContext(ctx)
+	assert.Nil(t, err)
+	assert.True(t, metric.Success())
+	assert.Len(t, metric, 3)
+}
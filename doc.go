@@ -6,9 +6,14 @@ Resiliência is a fault tolerance Go library, whose goal is to gather algorithms
 This library provides some fault tolerance policies, which can be used singly to wrap a function
 or chain other policies together.
 
+	> Adaptive Limit:  The right number of concurrent calls changes with the load - measure it, don't guess it.
+	> Bulkhead:        Too many concurrent calls can overload a resource and take down the caller with it.
+	> Cache:           The last good answer is often better than none - and cheaper than asking again.
 	> Circuit Breaker: When a system is seriously struggling, failing fast is better than making users/callers wait.
 	                   Protecting a faulting system from overload can help it recover.
 	> Fallback:        Things will still fail - plan what you will do when that happens.
+	> Hedge:           A slow call is often just unlucky - ask again before giving up on it.
+	> Rate Limit:      Calls beyond what a service can handle (or allows) are better not made at all.
 	> Retry:           Many faults are transient and may self-correct after a short delay.
 	> Timeout:         Beyond a certain wait, a success result is unlikely.
 
@@ -18,8 +23,8 @@ its documentation. Below we will see how to use a decorator or a policy chain of
 # Policy decorator
 
 A decorator allows you to decorate a command with one or more policies. These are chained so that the call to the
-service can be made within a circuit breaker, fallback, retry or timeout. In the example below, the command will be
-called within the policies in the following order: timeout, retry, circuit breaker and fallback.
+service can be made within a bulkhead, circuit breaker, fallback, retry or timeout. In the example below, the command
+will be called within the policies in the following order: timeout, retry, circuit breaker and fallback.
 
 	metric, err := resiliencia.
 		Decorate(func() error {
@@ -56,5 +61,42 @@ In this case: retry, circuit breaker, timeout and fallback.
 			fmt.Println("Do something.")
 			return nil
 		})
+
+# Context
+
+Both decorator and chain may be bound to a context.Context. The context is passed through every policy down
+to the command supplier, so that a caller's cancellation or deadline stops retries and waits.
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	metric, err := resiliencia.ChainContext(retry.New(id), timeout.New(id)).
+		ExecuteContext(ctx, func(ctx context.Context) error {
+			return doSomething(ctx)
+		})
+
+	metric, err = resiliencia.
+		DecorateContext(func(ctx context.Context) error {
+			return doSomething(ctx)
+		}).
+		WithRetry(retry.New(id)).
+		ExecuteContext(ctx)
+
+# Results
+
+ExecuteT runs a chain with a command supplier that returns a result besides the error. The result travels
+through every policy, so a fallback policy may supply a substitute value and a timed out execution never
+overrides it.
+
+	fb := fallback.New(id)
+	fb.Errors = []error{retry.ErrMaxTriesExceeded}
+	fb.FallBackResult = func(err error) (any, error) {
+		return "Unknown", nil
+	}
+
+	userName, metric, err := resiliencia.ExecuteT(ctx, resiliencia.Chain(fb, retry.New(id)),
+		func(ctx context.Context) (string, error) {
+			return fetchUserName(ctx, id)
+		})
 */
 package resiliencia
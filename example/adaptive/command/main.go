@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aureliano/resiliencia/adaptive"
+	"github.com/aureliano/resiliencia/core"
+)
+
+func main() {
+	policy := adaptive.New("service-name")
+	policy.InitialLimit = 4
+	policy.SlowCallDuration = time.Millisecond * 50
+	policy.OnLimitChange = func(p adaptive.Policy, from, to int) {
+		fmt.Println("Limit changed from", from, "to", to)
+	}
+
+	// The service slows down under load: the limit grows while it answers fast and shrinks once it doesn't.
+	for round := 1; round <= 3; round++ {
+		var wg sync.WaitGroup
+		for i := 0; i < 8; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				callService(policy)
+			}()
+		}
+		wg.Wait()
+
+		s, _ := adaptive.Stats("service-name")
+		fmt.Printf("Round %d: limit %d, latency %s\n", round, s.Limit, s.RTT.Round(time.Millisecond))
+	}
+}
+
+func callService(policy adaptive.Policy) {
+	policy.Command = func() error {
+		s, _ := adaptive.Stats("service-name")
+		time.Sleep(time.Millisecond * time.Duration(10*s.InFlight))
+
+		return nil
+	}
+
+	err := policy.Run(core.NewMetric())
+	if errors.Is(err, adaptive.ErrLimitExceeded) {
+		fmt.Println("Call rejected")
+	}
+}
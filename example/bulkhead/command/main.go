@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aureliano/resiliencia/bulkhead"
+	"github.com/aureliano/resiliencia/core"
+)
+
+func main() {
+	var wg sync.WaitGroup
+
+	// Two calls run, one waits for its turn and the others are rejected.
+	for id := 1; id <= 5; id++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			getUsername(id)
+		}(id)
+	}
+
+	wg.Wait()
+}
+
+func getUsername(id int) {
+	policy := bulkhead.New("service-name")
+	policy.MaxConcurrent = 2
+	policy.MaxQueue = 1
+	policy.MaxQueueWait = time.Second
+	policy.OnReject = func(p bulkhead.Policy, err error) {
+		fmt.Printf("Call %d rejected: %s\n", id, err)
+	}
+	policy.Command = func() error {
+		fmt.Printf("User name %d: %s\n", id, fetchUserName(id))
+		return nil
+	}
+
+	metric := core.NewMetric()
+	_ = policy.Run(metric)
+
+	fmt.Printf("Bulkhead metric %d: %v\n", id, metric["bulkhead.Metric"])
+}
+
+func fetchUserName(id int) string {
+	time.Sleep(time.Millisecond * 200)
+	return fmt.Sprintf("resiliencia-%d", id)
+}
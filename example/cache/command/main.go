@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aureliano/resiliencia"
+	"github.com/aureliano/resiliencia/cache"
+	"github.com/aureliano/resiliencia/core"
+)
+
+type userIDKey struct{}
+
+var calls int
+
+func main() {
+	// A fake clock stands for the time passing between calls.
+	clock := core.NewFakeClock(time.Now())
+
+	policy := cache.New("service-name")
+	policy.Clock = clock
+	policy.TTL = time.Minute
+	policy.StaleIfError = time.Hour
+	policy.Key = func(ctx context.Context) string {
+		id, _ := ctx.Value(userIDKey{}).(string)
+		return id
+	}
+	chain := resiliencia.ChainOfResponsibility{Policies: []core.PolicySupplier{policy}}
+
+	// The second call is served from the cache, the third one is served stale after the service failed.
+	for i := 0; i < 3; i++ {
+		if i == 2 {
+			clock.Advance(time.Minute * 2)
+		}
+
+		ctx := context.WithValue(context.Background(), userIDKey{}, "1")
+		name, metric, err := resiliencia.ExecuteT(ctx, chain, fetchUserName)
+
+		if err != nil {
+			fmt.Println("Service call failed:", err)
+			continue
+		}
+
+		m, _ := metric["cache.Metric"].(cache.Metric)
+		fmt.Printf("User name: %s (outcome %d, age %s)\n", name, m.Outcome, m.Age.Round(time.Second))
+	}
+}
+
+// fetchUserName fails from its second call on.
+func fetchUserName(ctx context.Context) (string, error) {
+	calls++
+	if calls > 1 {
+		return "", errors.New("service unavailable")
+	}
+
+	return "resiliencia", nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/hedge"
+)
+
+var calls int32
+
+func main() {
+	var userName atomic.Value
+
+	policy := hedge.New("service-name")
+	policy.Delay = time.Millisecond * 100
+	policy.MaxHedgedAttempts = 2
+	policy.OnHedge = func(p hedge.Policy, attempt int) {
+		fmt.Println("First attempt is slow, starting attempt", attempt)
+	}
+	policy.CommandContext = func(ctx context.Context) error {
+		name, err := fetchUserName(ctx)
+		if err != nil {
+			return err
+		}
+
+		userName.Store(name)
+
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := policy.Run(metric)
+
+	if err != nil {
+		fmt.Println("Service call failed:", err)
+	}
+
+	fmt.Println("User name: ", userName.Load())
+	fmt.Println("Hedge metric: ", metric["hedge.Metric"])
+}
+
+// fetchUserName is slow on its first call only.
+func fetchUserName(ctx context.Context) (string, error) {
+	latency := time.Millisecond * 20
+	if atomic.AddInt32(&calls, 1) == 1 {
+		latency = time.Second
+	}
+
+	select {
+	case <-time.After(latency):
+		return "resiliencia", nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/ratelimit"
+)
+
+func main() {
+	// Two calls per second: the third is rejected and the fourth waits for its permit.
+	for id := 1; id <= 4; id++ {
+		mode := ratelimit.FailFastMode
+		if id == 4 {
+			mode = ratelimit.BlockMode
+		}
+
+		getUsername(id, mode)
+		fmt.Printf("\n--------------------------------\n\n")
+	}
+}
+
+func getUsername(id int, mode ratelimit.Mode) {
+	var userName string
+
+	policy := ratelimit.New("service-name")
+	policy.Limit = 2
+	policy.Period = time.Second
+	policy.Mode = mode
+	policy.Command = func() error {
+		userName = fetchUserName(id)
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := policy.Run(metric)
+
+	var limited *ratelimit.RateLimitedError
+	if errors.As(err, &limited) {
+		fmt.Println("Service call rejected, try again in", limited.Wait)
+	}
+
+	fmt.Println("User name: ", userName)
+	fmt.Println("Rate limit metric: ", metric["ratelimit.Metric"])
+}
+
+func fetchUserName(id int) string {
+	return fmt.Sprintf("resiliencia-%d", id)
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/aureliano/resiliencia"
+	"github.com/aureliano/resiliencia/fallback"
+	"github.com/aureliano/resiliencia/retry"
+	"github.com/aureliano/resiliencia/timeout"
+)
+
+var errServiceUnavailable = fmt.Errorf("service unavailable")
+
+func main() {
+	getUsername(1)
+	fmt.Printf("\n--------------------------------\n\n")
+	getUsername(2)
+	fmt.Printf("\n--------------------------------\n\n")
+	getUsername(3)
+}
+
+func getUsername(id int) {
+	service := "service-name"
+
+	fb := fallback.New(service)
+	fb.Errors = []error{retry.ErrMaxTriesExceeded}
+	fb.FallBackResult = func(err error) (any, error) {
+		return "Unknown", nil
+	}
+
+	rt := retry.New(service)
+	rt.Tries = 3
+	rt.Delay = time.Millisecond * 100
+	rt.Errors = []error{errServiceUnavailable, timeout.ErrExecutionTimedOut}
+
+	tm := timeout.New(service)
+	tm.Timeout = time.Millisecond * 100
+
+	userName, metric, err := resiliencia.ExecuteT(context.Background(), resiliencia.Chain(fb, rt, tm),
+		func(ctx context.Context) (string, error) {
+			return fetchUserName(ctx, id)
+		})
+
+	if err != nil {
+		fmt.Println("Execution failed:", err)
+	}
+
+	fmt.Println("User name: ", userName)
+	fmt.Println("Fallback metric: ", metric["fallback.Metric"])
+	fmt.Println("Retry metric: ", metric["retry.Metric"])
+}
+
+func fetchUserName(ctx context.Context, id int) (string, error) {
+	switch id {
+	case 1:
+		return "resiliencia", nil
+	case 2:
+		select {
+		case <-time.After(time.Millisecond * 200):
+			return "New user", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	default:
+		return "", errServiceUnavailable
+	}
+}
@@ -71,6 +71,53 @@ There are two ways to run a command under a policy. Using a Command supplier
 	// Prints Fallback metric.
 	fmt.Println(fbMetric)
 
+# Result
+
+FallBackResult is an alternative handler which decides what to do about a handled error. It may recover
+with a substitute value, which is delivered to result-returning executions (see resiliencia.ExecuteT),
+translate the error into a domain error or rethrow it. FallBackHandler becomes optional in that case.
+The outcome, the error which triggered the fallback and the substitute value are kept in the metric.
+
+	p := fallback.New("service-id")
+	p.Errors = []error{err1, err2}
+	p.FallBackResult = func(err error) (any, error) {
+		if errors.Is(err, err1) {
+			// Substitute value.
+			return "default", nil
+		}
+
+		// Translated error.
+		return nil, ErrDomain
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+
+	fbMetric, _ := metric["fallback.Metric"].(fallback.Metric)
+	fmt.Println(fbMetric.Outcome, fbMetric.Cause, fbMetric.Value)
+
+# Errors
+
+An error which isn't in Errors makes the policy return a *core.PolicyError wrapping ErrUnhandledError and that
+error. An error returned by FallBackResult is wrapped in a *core.PolicyError as well. SwallowErrors makes the
+policy return ErrUnhandledError or the error of FallBackResult as they are (see core.PolicyError).
+
+# Context
+
+Every policy may also run bound to a context.Context. Use CommandContext instead of Command so that the
+command supplier receives the context, and RunContext instead of Run. A wrapped policy receives the same context.
+
+	p := fallback.New("service-id")
+	p.CommandContext = func(ctx context.Context) error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	err := p.RunContext(ctx, core.NewMetric())
+
 # Listener
 
 In order to keep tracking of what is happening on the execution, you may use listeners to generate some events.
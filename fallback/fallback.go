@@ -1,6 +1,7 @@
 package fallback
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"time"
@@ -30,6 +31,18 @@ type Policy struct {
 	// Function to be executed when execution fail.
 	FallBackHandler func(err error)
 
+	// Function which decides what to do when execution fail. It either recovers with a
+	// substitute result (nil error), which is delivered to result-returning executions
+	// (see resiliencia.ExecuteT), or returns an error (translated or the same) to be propagated.
+	FallBackResult func(err error) (any, error)
+
+	// Clock used to tell time (real time if not set).
+	Clock core.Clock
+
+	// Whether the policy returns its bare errors (e.g. ErrUnhandledError), leaving the error of the
+	// command supplier or of the wrapped policy only in the metric, as former versions did.
+	SwallowErrors bool
+
 	// Function called before execution.
 	BeforeFallBack func(p Policy)
 
@@ -39,6 +52,9 @@ type Policy struct {
 	// The command supplier.
 	Command core.Command
 
+	// The context-aware command supplier. It takes precedence over Command.
+	CommandContext core.CommandContext
+
 	// Any policy that will be wrapped by this one.
 	Policy core.PolicySupplier
 }
@@ -59,8 +75,34 @@ type Metric struct {
 
 	// The error (if execution wasn't succeeded)
 	Error error
+
+	// What the fallback did about the execution.
+	Outcome Outcome
+
+	// The error which triggered the fallback.
+	Cause error
+
+	// The substitute result supplied by FallBackResult.
+	Value any
 }
 
+// Outcome is what the fallback did about an execution.
+type Outcome int
+
+const (
+	// Indicates that execution succeeded and fallback wasn't needed.
+	NoFallBackOutcome = Outcome(0)
+
+	// Indicates that fallback recovered from the error.
+	RecoveredOutcome = Outcome(1)
+
+	// Indicates that fallback handler propagated an error (translated or the same).
+	PropagatedOutcome = Outcome(2)
+
+	// Indicates that error isn't in Errors policy field.
+	UnhandledOutcome = Outcome(3)
+)
+
 // New creates a fallback policy with default values set.
 func New(serviceID string) Policy {
 	return Policy{ServiceID: serviceID}
@@ -68,39 +110,59 @@ func New(serviceID string) Policy {
 
 // Run executes a command supplier or a wrapped policy in a fallback.
 //
-// Possible error(s): ErrCommandRequired, ErrNoFallBackHandler, ErrorErrUnhandledError.
+// Possible error(s): ErrCommandRequiredError, ErrNoFallBackHandler, ErrUnhandledError (see RunContext).
 func (p Policy) Run(metric core.Metric) error {
+	return p.RunContext(context.Background(), metric)
+}
+
+// RunContext executes a command supplier or a wrapped policy in a fallback bound to a context.
+// The context is passed on to the command supplier or to the wrapped policy.
+//
+// Validation errors are returned as they are. Any other error is a *core.PolicyError wrapping
+// ErrUnhandledError and the error which wasn't handled, or the error returned by FallBackResult,
+// unless SwallowErrors is set.
+//
+// Possible error(s): ErrCommandRequiredError, ErrNoFallBackHandler, ErrUnhandledError and
+// any error returned by FallBackResult.
+func (p Policy) RunContext(ctx context.Context, metric core.Metric) error {
 	if err := validate(p); err != nil {
 		return err
 	}
 
-	m := Metric{ID: p.ServiceID, StartedAt: time.Now()}
+	clock := core.ClockOrDefault(p.Clock)
+	m := Metric{ID: p.ServiceID, StartedAt: clock.Now()}
 	if p.BeforeFallBack != nil {
 		p.BeforeFallBack(p)
 	}
 
-	err := execute(p, metric)
+	err := execute(ctx, p, metric)
 	err = pickError(err, metric)
 
 	if p.AfterFallBack != nil {
 		p.AfterFallBack(p, err)
 	}
-	m.FinishedAt = time.Now()
+	m.FinishedAt = clock.Now()
 
 	if !handledError(p, err) {
 		m.Status = 1
 		m.Error = ErrUnhandledError
+		m.Outcome = UnhandledOutcome
+		m.Cause = err
 		metric[reflect.TypeOf(m).String()] = m
 
-		return ErrUnhandledError
+		return failure(p, ErrUnhandledError, err)
 	}
 
 	if err != nil {
-		p.FallBackHandler(err)
+		fallBack(ctx, p, &m, err)
 	}
 	metric[reflect.TypeOf(m).String()] = m
 
-	return nil
+	if m.Error == nil {
+		return nil
+	}
+
+	return failure(p, nil, m.Error)
 }
 
 // WithCommand encapsulates this policy in a new policy with given command supplier.
@@ -109,18 +171,66 @@ func (p Policy) WithCommand(command core.Command) core.PolicySupplier {
 	return p
 }
 
+// WithCommandContext encapsulates this policy in a new policy with given context-aware command supplier.
+func (p Policy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.CommandContext = command
+	return p
+}
+
 // WithPolicy encapsulates this policy in a new policy with wrapped policy.
 func (p Policy) WithPolicy(policy core.PolicySupplier) core.PolicySupplier {
 	p.Policy = policy
 	return p
 }
 
-func execute(p Policy, metric core.Metric) error {
-	if p.Command != nil && p.Policy == nil {
+func execute(ctx context.Context, p Policy, metric core.Metric) error {
+	if p.Policy == nil {
+		if p.CommandContext != nil {
+			return p.CommandContext(ctx)
+		}
+
 		return p.Command()
 	}
 
-	return p.Policy.Run(metric)
+	return core.RunPolicy(ctx, p.Policy, metric)
+}
+
+func fallBack(ctx context.Context, p Policy, m *Metric, err error) {
+	m.Cause = err
+	m.Outcome = RecoveredOutcome
+
+	if p.FallBackHandler != nil {
+		p.FallBackHandler(err)
+	}
+
+	if p.FallBackResult == nil {
+		return
+	}
+
+	value, ferr := p.FallBackResult(err)
+	if ferr != nil {
+		m.Status = 1
+		m.Error = ferr
+		m.Outcome = PropagatedOutcome
+
+		return
+	}
+
+	m.Value = value
+	core.SetResult(ctx, value)
+}
+
+// failure wraps the policy error and the error which caused it, unless errors are swallowed.
+// Swallowed errors of FallBackResult are still returned, being the outcome of the policy.
+func failure(p Policy, err, cause error) error {
+	switch {
+	case p.SwallowErrors && err != nil:
+		return err
+	case p.SwallowErrors:
+		return cause
+	default:
+		return &core.PolicyError{Policy: "fallback", ServiceID: p.ServiceID, Err: err, Cause: cause}
+	}
 }
 
 func handledError(p Policy, err error) bool {
@@ -129,9 +239,9 @@ func handledError(p Policy, err error) bool {
 
 func validate(p Policy) error {
 	switch {
-	case p.FallBackHandler == nil:
+	case p.FallBackHandler == nil && p.FallBackResult == nil:
 		return ErrNoFallBackHandler
-	case p.Command == nil && p.Policy == nil:
+	case p.Command == nil && p.CommandContext == nil && p.Policy == nil:
 		return ErrCommandRequiredError
 	default:
 		return nil
@@ -1,6 +1,7 @@
 package fallback_test
 
 import (
+	"context"
 	"errors"
 	"reflect"
 	"testing"
@@ -87,6 +88,111 @@ func TestRunValidatePolicyFallBackHandler(t *testing.T) {
 	assert.ErrorIs(t, err, fallback.ErrNoFallBackHandler)
 }
 
+func TestRunFallBackResult(t *testing.T) {
+	errTest := errors.New("error test")
+	ctx, result := core.NewResultContext(context.Background())
+
+	p := fallback.New("service-id")
+	p.Errors = []error{errTest}
+	p.FallBackResult = func(err error) (any, error) { return "default", nil }
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	value, ok := result.Get()
+
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "default", value)
+
+	i := metric[reflect.TypeOf(fallback.Metric{}).String()]
+	m, _ := i.(fallback.Metric)
+
+	assert.Equal(t, fallback.RecoveredOutcome, m.Outcome)
+	assert.ErrorIs(t, m.Cause, errTest)
+	assert.Equal(t, "default", m.Value)
+	assert.True(t, m.Success())
+}
+
+func TestRunFallBackResultTranslatedError(t *testing.T) {
+	errTest := errors.New("error test")
+	errDomain := errors.New("domain error")
+
+	p := fallback.New("service-id")
+	p.Errors = []error{errTest}
+	p.FallBackResult = func(err error) (any, error) { return nil, errDomain }
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	i := metric[reflect.TypeOf(fallback.Metric{}).String()]
+	m, _ := i.(fallback.Metric)
+
+	assert.ErrorIs(t, err, errDomain)
+	assert.Equal(t, fallback.PropagatedOutcome, m.Outcome)
+	assert.ErrorIs(t, m.Cause, errTest)
+	assert.ErrorIs(t, m.MetricError(), errDomain)
+	assert.Nil(t, m.Value)
+	assert.Equal(t, 1, m.Status)
+	assert.False(t, m.Success())
+}
+
+func TestRunFallBackResultRethrow(t *testing.T) {
+	errTest := errors.New("error test")
+	handlerCalled := false
+
+	p := fallback.New("service-id")
+	p.Errors = []error{errTest}
+	p.FallBackHandler = func(err error) { handlerCalled = true }
+	p.FallBackResult = func(err error) (any, error) { return nil, err }
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	i := metric[reflect.TypeOf(fallback.Metric{}).String()]
+	m, _ := i.(fallback.Metric)
+
+	assert.True(t, handlerCalled)
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, fallback.PropagatedOutcome, m.Outcome)
+	assert.ErrorIs(t, m.Cause, errTest)
+	assert.False(t, m.Success())
+}
+
+func TestRunOutcome(t *testing.T) {
+	errTest := errors.New("error test")
+
+	p := fallback.New("service-id")
+	p.FallBackHandler = func(err error) {}
+	p.Command = func() error { return nil }
+
+	metric := core.NewMetric()
+	_ = p.Run(metric)
+	m, _ := metric[reflect.TypeOf(fallback.Metric{}).String()].(fallback.Metric)
+
+	assert.Equal(t, fallback.NoFallBackOutcome, m.Outcome)
+	assert.Nil(t, m.Cause)
+
+	p.Errors = []error{errTest}
+	p.Command = func() error { return errTest }
+
+	metric = core.NewMetric()
+	_ = p.Run(metric)
+	m, _ = metric[reflect.TypeOf(fallback.Metric{}).String()].(fallback.Metric)
+
+	assert.Equal(t, fallback.RecoveredOutcome, m.Outcome)
+	assert.ErrorIs(t, m.Cause, errTest)
+
+	p.Errors = nil
+
+	metric = core.NewMetric()
+	_ = p.Run(metric)
+	m, _ = metric[reflect.TypeOf(fallback.Metric{}).String()].(fallback.Metric)
+
+	assert.Equal(t, fallback.UnhandledOutcome, m.Outcome)
+	assert.ErrorIs(t, m.Cause, errTest)
+}
+
 func TestRunValidatePolicyCommand(t *testing.T) {
 	p := fallback.New("service-id")
 	p.FallBackHandler = func(err error) {}
@@ -174,7 +280,7 @@ func TestRunCommandUnhandledError(t *testing.T) {
 	i := metric[reflect.TypeOf(fallback.Metric{}).String()]
 	m, _ := i.(fallback.Metric)
 
-	assert.ErrorIs(t, fallback.ErrUnhandledError, err)
+	assert.ErrorIs(t, err, fallback.ErrUnhandledError)
 	assert.False(t, fallbackCalled)
 
 	assert.Equal(t, "service-id", m.ID)
@@ -186,6 +292,28 @@ func TestRunCommandUnhandledError(t *testing.T) {
 	assert.False(t, m.Success())
 }
 
+func TestRunContextCommand(t *testing.T) {
+	type key struct{}
+	errTest := errors.New("err test")
+	ctx := context.WithValue(context.Background(), key{}, "remote-service")
+
+	p := fallback.New("remote-service")
+	p.Errors = []error{errTest}
+	p.FallBackHandler = func(err error) {}
+	p.CommandContext = func(ctx context.Context) error {
+		assert.Equal(t, "remote-service", ctx.Value(key{}))
+		return errTest
+	}
+
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	i := metric[reflect.TypeOf(fallback.Metric{}).String()]
+	m, _ := i.(fallback.Metric)
+
+	assert.Nil(t, err)
+	assert.True(t, m.Success())
+}
+
 func TestRunPolicyUnhandledError(t *testing.T) {
 	fallbackCalled := false
 	errTest1 := errors.New("error test 1")
@@ -218,7 +346,7 @@ func TestRunPolicyUnhandledError(t *testing.T) {
 	r := metric[reflect.TypeOf(Metric{}).String()]
 	childMetric, _ := r.(Metric)
 
-	assert.ErrorIs(t, fallback.ErrUnhandledError, err)
+	assert.ErrorIs(t, err, fallback.ErrUnhandledError)
 	assert.False(t, fallbackCalled)
 
 	assert.Equal(t, "dummy-service", childMetric.ID)
@@ -399,6 +527,47 @@ func TestRunPolicyNoFallback(t *testing.T) {
 	assert.True(t, fallbackMetric.Success())
 }
 
+func TestRunPolicyError(t *testing.T) {
+	errTest := errors.New("service unavailable")
+	errDomain := errors.New("domain error")
+	p := fallback.New("service-id")
+	p.FallBackResult = func(err error) (any, error) { return nil, errDomain }
+	p.Command = func() error { return errTest }
+
+	err := p.Run(core.NewMetric())
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "fallback", perr.Policy)
+	assert.Equal(t, "service-id", perr.ServiceID)
+	assert.ErrorIs(t, err, fallback.ErrUnhandledError)
+	assert.ErrorIs(t, err, errTest)
+
+	p.Errors = []error{errTest}
+	err = p.Run(core.NewMetric())
+	assert.ErrorAs(t, err, &perr)
+	assert.ErrorIs(t, err, errDomain)
+}
+
+func TestRunSwallowErrors(t *testing.T) {
+	errTest := errors.New("service unavailable")
+	errDomain := errors.New("domain error")
+	p := fallback.New("service-id")
+	p.FallBackResult = func(err error) (any, error) { return nil, errDomain }
+	p.SwallowErrors = true
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(fallback.Metric{}).String()].(fallback.Metric)
+
+	assert.Equal(t, fallback.ErrUnhandledError, err)
+	assert.Equal(t, errTest, m.Cause)
+
+	p.Errors = []error{errTest}
+	assert.Equal(t, errDomain, p.Run(core.NewMetric()))
+}
+
 func TestWithCommand(t *testing.T) {
 	p := fallback.New("id")
 	assert.Nil(t, p.Command)
@@ -408,6 +577,15 @@ func TestWithCommand(t *testing.T) {
 	assert.NotNil(t, p.Command)
 }
 
+func TestWithCommandContext(t *testing.T) {
+	p := fallback.New("id")
+	assert.Nil(t, p.CommandContext)
+
+	np := p.WithCommandContext(func(ctx context.Context) error { return nil })
+	p, _ = np.(fallback.Policy)
+	assert.NotNil(t, p.CommandContext)
+}
+
 func TestWithPolicy(t *testing.T) {
 	p := fallback.New("id")
 	assert.Nil(t, p.Policy)
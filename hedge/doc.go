@@ -0,0 +1,136 @@
+/*
+The hedge pattern reduces tail latency. When an attempt takes longer than usual, another one is started
+without abandoning the first, and whichever succeeds first wins. It suits idempotent calls, mostly reads,
+whose latency varies from call to call, at the cost of some extra load on the service.
+
+# Usage
+
+There are two ways to run a command under a policy. Using a Command supplier
+(anonymous function) or a wrapped policy.
+
+# Command supplier
+
+	p := hedge.New("service-id")
+	p.Delay = time.Millisecond * 50
+	p.MaxHedgedAttempts = 2
+	p.CommandContext = func(ctx context.Context) error {
+		// Your business logic, which gives up once ctx is done.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+
+	if err != nil {
+		// Error handling.
+		...
+	}
+
+	// Prints Hedge metric.
+	fmt.Println(metric)
+
+# Wrapped policy
+
+	policy := new(AnyPolicy)
+	policy.Command = func() error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	hd := hedge.New("service-id")
+	hd.Delay = time.Millisecond * 50
+
+	// Instead of a command supplier, it is passed a policy.
+	hd.Policy = policy
+
+	metric := core.NewMetric()
+	err := hd.Run(metric)
+
+	mr := metric["hedge.Metric"] // or metric[reflect.TypeOf(hedge.Metric{}).String()]
+	hdMetric, _ := mr.(hedge.Metric)
+
+	// Prints Hedge metric.
+	fmt.Println(hdMetric)
+
+# Attempts
+
+The first attempt starts right away. Each time Delay elapses another attempt is started, up to
+MaxHedgedAttempts besides the first one. Delay must be set whenever MaxHedgedAttempts is, otherwise
+every call would start all of its attempts right away. An attempt which fails doesn't win, and when
+every attempt in progress failed the next one starts without waiting for the delay. The metric records
+every attempt and which one won, and the losers are marked as canceled.
+
+# Percentile
+
+Instead of a fixed delay, the delay may follow the latency of the service: Percentile sets which
+percentile (e.g. 95) of the latencies of the latest SampleSize successful calls is used, each measured
+from the start of the first attempt to the answer of the winner. Delay is used until MinSamples latencies
+are observed. Latency tells the current percentile of a service, and Reset
+drops its latencies.
+
+	p := hedge.New("service-id")
+	p.Delay = time.Millisecond * 50
+	p.Percentile = 95
+
+# Registry
+
+Latencies are kept by a Registry. Policies sharing a registry and a ServiceID share the latencies of the
+service, so unrelated components (or tests) should use registries of their own. Policies without a Registry
+use the default one (see DefaultRegistry). Latencies keep the SampleSize of the first policy using
+Percentile: another such policy of the same service with another SampleSize fails with ErrSettingsConflict,
+until Reset drops them. Policies with a fixed delay never conflict.
+
+	registry := hedge.NewRegistry()
+
+	p := hedge.New("service-id")
+	p.Registry = registry
+	...
+
+	d, samples := registry.Latency("service-id", 95)
+	registry.Reset("service-id")
+
+# Errors
+
+When every attempt fails, the policy returns a *core.PolicyError wrapping the errors of all of them
+(see errors.Join). When the context is done first, it wraps the error of the context as well.
+SwallowErrors makes the policy return the error of the context as it is and nil when every attempt
+fails (see core.PolicyError).
+
+# Context
+
+Attempts run bound to a context.Context derived from the one given to RunContext. Once an attempt wins,
+the context of every other attempt is canceled, so the work in progress can be abandoned. Use
+CommandContext, since a plain Command can't be canceled and a losing attempt keeps running until it
+returns. A wrapped policy receives the context of its attempt.
+
+Every attempt has a result of its own (see core.Result), so that only the result of the winner is
+delivered to result-returning executions (see resiliencia.ExecuteT). Likewise, only the metrics of the
+wrapped policy of the winner are recorded. Delays are measured by the policy Clock (see core.Clock),
+which tests may replace by a core.FakeClock.
+
+# Listener
+
+In order to keep tracking of what is happening on the execution, you may use listeners to generate some events.
+hedge supports listeners to track events before and after policy execution, and when a hedged attempt starts.
+
+	p := hedge.New("service-id")
+	...
+	p.BeforeHedge = func(p hedge.Policy) {
+		fmt.Println("Before hedge.")
+	}
+	p.AfterHedge = func(p hedge.Policy, err error) {
+		fmt.Println("After hedge.")
+	}
+	p.OnHedge = func(p hedge.Policy, attempt int) {
+		fmt.Println("Hedged attempt", attempt)
+	}
+
+	_ = p.Run(core.Metric())
+*/
+package hedge
@@ -0,0 +1,452 @@
+package hedge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+)
+
+var (
+	// Policy delay is less than minimum required, or isn't set while attempts are hedged.
+	ErrDelayValidation = fmt.Errorf("delay must be >= %d, and > %d when attempts are hedged", MinDelay, MinDelay)
+
+	// Policy max hedged attempts is less than minimum required.
+	ErrMaxHedgedAttemptsValidation = fmt.Errorf("max hedged attempts must be >= %d", MinMaxHedgedAttempts)
+
+	// Policy percentile is out of range.
+	ErrPercentileValidation = errors.New("percentile must be 0 or > 0 and <= 100")
+
+	// Policy min samples is less than minimum required.
+	ErrMinSamplesValidation = fmt.Errorf("min samples must be >= %d", MinMinSamples)
+
+	// Policy sample size is less than min samples.
+	ErrSampleSizeValidation = errors.New("sample size must be >= min samples")
+
+	// No command nor wrapped policy is set.
+	ErrCommandRequired = errors.New("command nor wrapped policy provided")
+
+	// The latencies of the service are kept with another SampleSize.
+	ErrSettingsConflict = errors.New("hedge settings conflict with those of the service")
+)
+
+// Policy defines the hedge algorithm execution policy.
+type Policy struct {
+	// The registered service id.
+	ServiceID string
+
+	// How long to wait for an attempt before starting another one (required when MaxHedgedAttempts is set).
+	// It is used until enough latencies are observed when Percentile is set.
+	Delay time.Duration
+
+	// Percentile (e.g. 95) of the latencies observed for the service which is used as delay (zero means
+	// Delay is always used).
+	Percentile float64
+
+	// Number of latencies to be observed before Percentile is used.
+	MinSamples int
+
+	// Number of the latest latencies kept to compute Percentile.
+	SampleSize int
+
+	// Number of attempts started besides the first one (zero means no hedging).
+	MaxHedgedAttempts int
+
+	// Clock used to tell time and to time the hedged attempts (real time if not set).
+	Clock core.Clock
+
+	// Registry which keeps the latencies observed for the service (the default registry if not set).
+	Registry *Registry
+
+	// Whether the policy returns the error of the context as it is, and nil when every attempt fails,
+	// whose errors are then only recorded in the metric.
+	SwallowErrors bool
+
+	// Function called before execution.
+	BeforeHedge func(p Policy)
+
+	// Function called after execution.
+	AfterHedge func(p Policy, err error)
+
+	// Function called when a hedged attempt is started.
+	OnHedge func(p Policy, attempt int)
+
+	// The command supplier. It can't be canceled, so a losing attempt keeps running until it returns.
+	Command core.Command
+
+	// The context-aware command supplier. It takes precedence over Command.
+	CommandContext core.CommandContext
+
+	// Any policy that will be wrapped by this one.
+	Policy core.PolicySupplier
+}
+
+// Metric keeps the running state of the hedge.
+type Metric struct {
+	// The registered service id.
+	ID string
+
+	// The execution status (success is non zero).
+	Status int
+
+	// When execution started.
+	StartedAt time.Time
+
+	// When execution finished.
+	FinishedAt time.Time
+
+	// The error (if execution wasn't succeeded)
+	Error error
+
+	// The delay between attempts.
+	Delay time.Duration
+
+	// Number of attempts started.
+	Attempts int
+
+	// The attempt which succeeded first (zero if none did). Starts from one (1).
+	Winner int
+
+	// Attempt metrics, in the order they were started.
+	Executions []Execution
+}
+
+// Execution keeps the running state of a single attempt.
+type Execution struct {
+	// Attempt id. Starts from one (1).
+	Attempt int
+
+	// When attempt started.
+	StartedAt time.Time
+
+	// When attempt finished or was canceled.
+	FinishedAt time.Time
+
+	// Attempt duration.
+	Duration time.Duration
+
+	// The error (if attempt wasn't succeeded)
+	Error error
+
+	// Whether the attempt was canceled before it finished.
+	Canceled bool
+}
+
+const (
+	// Minimum expected to be set on Delay field of a hedge policy.
+	MinDelay = 0
+
+	// Minimum expected to be set on MaxHedgedAttempts field of a hedge policy.
+	MinMaxHedgedAttempts = 0
+
+	// Minimum expected to be set on MinSamples field of a hedge policy.
+	MinMinSamples = 1
+
+	// Number of latencies observed before Percentile is used, set by New.
+	DefaultMinSamples = 20
+
+	// Number of latencies kept to compute Percentile, set by New.
+	DefaultSampleSize = 100
+)
+
+// attempt is the outcome of an attempt.
+type attempt struct {
+	n      int
+	err    error
+	metric core.Metric
+	result *core.Result
+}
+
+// New creates a hedge policy with default values set.
+func New(serviceID string) Policy {
+	return Policy{
+		ServiceID:         serviceID,
+		MinSamples:        DefaultMinSamples,
+		SampleSize:        DefaultSampleSize,
+		MaxHedgedAttempts: 1,
+	}
+}
+
+// Run executes a command supplier or a wrapped policy in a hedge.
+//
+// Possible error(s): ErrDelayValidation, ErrMaxHedgedAttemptsValidation, ErrPercentileValidation,
+// ErrMinSamplesValidation, ErrSampleSizeValidation, ErrCommandRequired, ErrSettingsConflict (see RunContext).
+func (p Policy) Run(metric core.Metric) error {
+	return p.RunContext(context.Background(), metric)
+}
+
+// RunContext executes a command supplier or a wrapped policy in a hedge bound to a context.
+// Another attempt is started each time the delay elapses (or right away once every attempt in progress
+// failed), up to MaxHedgedAttempts. The first attempt to succeed wins and the others are canceled
+// through their context. Metrics of a wrapped policy and the result of a result-returning command are
+// only recorded for the winner.
+//
+// Validation errors and ErrSettingsConflict are returned as they are. Any other error is a
+// *core.PolicyError wrapping the error of ctx or the errors of every attempt, unless SwallowErrors is set.
+//
+// Possible error(s): ErrDelayValidation, ErrMaxHedgedAttemptsValidation, ErrPercentileValidation,
+// ErrMinSamplesValidation, ErrSampleSizeValidation, ErrCommandRequired, ErrSettingsConflict,
+// context.Canceled, context.DeadlineExceeded.
+func (p Policy) RunContext(ctx context.Context, metric core.Metric) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+
+	l, err := registryOf(p).get(p)
+	if err != nil {
+		return err
+	}
+
+	clock := core.ClockOrDefault(p.Clock)
+	m := Metric{ID: p.ServiceID, StartedAt: clock.Now(), Delay: delay(p, l)}
+	if p.BeforeHedge != nil {
+		p.BeforeHedge(p)
+	}
+
+	errs, err := hedge(ctx, p, clock, metric, &m)
+	var cause error
+
+	if m.Winner > 0 {
+		// The latency of the call, as the caller waited for it since the first attempt started.
+		l.observe(m.Executions[m.Winner-1].FinishedAt.Sub(m.Executions[0].StartedAt))
+	} else {
+		cause = errors.Join(errs...)
+		m.Status = 1
+		m.Error = err
+		if err == nil {
+			m.Error = cause
+		}
+	}
+
+	if p.AfterHedge != nil {
+		p.AfterHedge(p, m.Error)
+	}
+	m.FinishedAt = clock.Now()
+	metric[reflect.TypeOf(m).String()] = m
+
+	return failure(p, err, cause)
+}
+
+// WithCommand encapsulates this policy in a new policy with given command supplier.
+func (p Policy) WithCommand(command core.Command) core.PolicySupplier {
+	p.Command = command
+	return p
+}
+
+// WithCommandContext encapsulates this policy in a new policy with given context-aware command supplier.
+func (p Policy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.CommandContext = command
+	return p
+}
+
+// WithPolicy encapsulates this policy in a new policy with wrapped policy.
+func (p Policy) WithPolicy(policy core.PolicySupplier) core.PolicySupplier {
+	p.Policy = policy
+	return p
+}
+
+// hedge runs the attempts until one succeeds, all of them fail or ctx is done. It returns the errors
+// of the attempts which failed and the error of ctx.
+func hedge(ctx context.Context, p Policy, clock core.Clock, metric core.Metric, m *Metric) ([]error, error) {
+	// Buffered, so that a losing attempt never blocks on sending even if nobody is waiting anymore.
+	c := make(chan attempt, p.MaxHedgedAttempts+1)
+	cancels := make([]context.CancelFunc, 0, p.MaxHedgedAttempts+1)
+	defer func() {
+		for _, cancel := range cancels {
+			cancel()
+		}
+	}()
+
+	start := func() core.Timer {
+		actx, cancel := context.WithCancel(ctx)
+		cancels = append(cancels, cancel)
+		m.Attempts++
+		m.Executions = append(m.Executions, Execution{Attempt: m.Attempts, StartedAt: clock.Now()})
+		if m.Attempts > 1 && p.OnHedge != nil {
+			p.OnHedge(p, m.Attempts)
+		}
+		go execute(actx, p, m.Attempts, c)
+
+		if m.Attempts > p.MaxHedgedAttempts {
+			return nil
+		}
+
+		return clock.NewTimer(m.Delay)
+	}
+
+	var errs []error
+	inFlight := 1
+	timer := start()
+
+	for {
+		var next <-chan time.Time
+		if timer != nil {
+			next = timer.C()
+		}
+
+		select {
+		case a := <-c:
+			inFlight--
+			finish(clock, &m.Executions[a.n-1], a.err)
+
+			if a.err == nil {
+				m.Winner = a.n
+				record(ctx, metric, a)
+				stop(timer)
+				cancelLosers(clock, m)
+
+				return errs, nil
+			}
+
+			errs = append(errs, a.err)
+			if inFlight > 0 {
+				continue
+			}
+			if timer == nil {
+				return errs, nil
+			}
+
+			// Nothing is in progress, so the next attempt doesn't wait for the delay.
+			stop(timer)
+			timer = start()
+			inFlight++
+		case <-next:
+			timer = start()
+			inFlight++
+		case <-ctx.Done():
+			stop(timer)
+			cancelLosers(clock, m)
+
+			return errs, ctx.Err()
+		}
+	}
+}
+
+func execute(ctx context.Context, p Policy, n int, c chan<- attempt) {
+	a := attempt{n: n, metric: core.NewMetric()}
+
+	// Every attempt has a result of its own, so that only the winner's is delivered.
+	if core.ResultFromContext(ctx) != nil {
+		ctx, a.result = core.NewResultContext(ctx)
+	}
+
+	switch {
+	case p.Policy != nil:
+		a.err = core.RunPolicy(ctx, p.Policy, a.metric)
+		if a.err == nil {
+			a.err = a.metric.MetricError()
+		}
+	case p.CommandContext != nil:
+		a.err = p.CommandContext(ctx)
+	default:
+		a.err = p.Command()
+	}
+
+	c <- a
+}
+
+// record delivers the metrics and the result of the winner.
+func record(ctx context.Context, metric core.Metric, a attempt) {
+	for k, v := range a.metric {
+		metric[k] = v
+	}
+
+	if a.result != nil {
+		if value, ok := a.result.Get(); ok {
+			core.SetResult(ctx, value)
+		}
+	}
+}
+
+func finish(clock core.Clock, exec *Execution, err error) {
+	exec.FinishedAt = clock.Now()
+	exec.Duration = exec.FinishedAt.Sub(exec.StartedAt)
+	exec.Error = err
+}
+
+// cancelLosers marks the attempts still in progress as canceled. Their context is canceled on return.
+func cancelLosers(clock core.Clock, m *Metric) {
+	for i := range m.Executions {
+		exec := &m.Executions[i]
+		if exec.FinishedAt.IsZero() {
+			finish(clock, exec, nil)
+			exec.Canceled = true
+		}
+	}
+}
+
+func stop(timer core.Timer) {
+	if timer != nil {
+		timer.Stop()
+	}
+}
+
+// delay returns the percentile of the observed latencies, or Delay if not enough of them were observed.
+func delay(p Policy, l *latencies) time.Duration {
+	if p.Percentile == 0 {
+		return p.Delay
+	}
+
+	d, samples := l.percentile(p.Percentile)
+	if samples < p.MinSamples {
+		return p.Delay
+	}
+
+	return d
+}
+
+// failure wraps the error of the context and the errors of the attempts, unless errors are swallowed.
+func failure(p Policy, err, cause error) error {
+	switch {
+	case err == nil && cause == nil:
+		return nil
+	case p.SwallowErrors:
+		return err
+	default:
+		return &core.PolicyError{Policy: "hedge", ServiceID: p.ServiceID, Err: err, Cause: cause}
+	}
+}
+
+func validate(p Policy) error {
+	switch {
+	case p.Delay < MinDelay, p.MaxHedgedAttempts > 0 && p.Delay == MinDelay:
+		return ErrDelayValidation
+	case p.MaxHedgedAttempts < MinMaxHedgedAttempts:
+		return ErrMaxHedgedAttemptsValidation
+	case p.Percentile < 0 || p.Percentile > 100:
+		return ErrPercentileValidation
+	case p.Percentile > 0 && p.MinSamples < MinMinSamples:
+		return ErrMinSamplesValidation
+	case p.Percentile > 0 && p.SampleSize < p.MinSamples:
+		return ErrSampleSizeValidation
+	case p.Command == nil && p.CommandContext == nil && p.Policy == nil:
+		return ErrCommandRequired
+	default:
+		return nil
+	}
+}
+
+// ServiceID returns the service id registered to the policy binded to this metric.
+func (m Metric) ServiceID() string {
+	return m.ID
+}
+
+// PolicyDuration returns the policy execution duration.
+// In short, finished at less (-) started at.
+func (m Metric) PolicyDuration() time.Duration {
+	return m.FinishedAt.Sub(m.StartedAt)
+}
+
+// Success returns whether the policy execution succeeded or not.
+// In short, status is zero and error is nil.
+func (m Metric) Success() bool {
+	return (m.Status == 0) && (m.Error == nil)
+}
+
+// MetricError returns the error that a command supplier or a wrapped policy raised.
+func (m Metric) MetricError() error {
+	return m.Error
+}
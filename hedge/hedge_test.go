@@ -0,0 +1,370 @@
+package hedge_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/hedge"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPolicy struct{ mock.Mock }
+
+func (p *mockPolicy) Run(_ core.Metric) error {
+	args := p.Called()
+	return args.Error(0)
+}
+
+func (p *mockPolicy) WithCommand(_ core.Command) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+func (p *mockPolicy) WithPolicy(_ core.PolicySupplier) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+type Metric struct {
+	ID     string
+	Status int
+}
+
+func (m Metric) ServiceID() string             { return m.ID }
+func (m Metric) PolicyDuration() time.Duration { return 0 }
+func (m Metric) Success() bool                 { return m.Status == 0 }
+func (m Metric) MetricError() error            { return nil }
+
+func TestPolicyImplementsPolicySupplier(t *testing.T) {
+	p := hedge.New("remote-service")
+	i := reflect.TypeOf((*core.PolicySupplier)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(p).Implements(i))
+}
+
+func TestMetricImplementsMetricRecorder(t *testing.T) {
+	m := hedge.Metric{}
+	i := reflect.TypeOf((*core.MetricRecorder)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(m).Implements(i))
+}
+
+func TestNew(t *testing.T) {
+	p := hedge.New("remote-service")
+
+	assert.Equal(t, "remote-service", p.ServiceID)
+	assert.Equal(t, 1, p.MaxHedgedAttempts)
+	assert.Equal(t, hedge.DefaultMinSamples, p.MinSamples)
+	assert.Equal(t, hedge.DefaultSampleSize, p.SampleSize)
+	assert.Equal(t, time.Duration(0), p.Delay)
+	assert.Zero(t, p.Percentile)
+}
+
+func TestRunValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(p *hedge.Policy)
+		err    error
+	}{
+		{"delay", func(p *hedge.Policy) { p.Delay = -1 }, hedge.ErrDelayValidation},
+		{"no delay", func(p *hedge.Policy) { p.Delay = 0 }, hedge.ErrDelayValidation},
+		{"max hedged attempts", func(p *hedge.Policy) { p.MaxHedgedAttempts = -1 }, hedge.ErrMaxHedgedAttemptsValidation},
+		{"percentile", func(p *hedge.Policy) { p.Percentile = 101 }, hedge.ErrPercentileValidation},
+		{"min samples", func(p *hedge.Policy) { p.Percentile, p.MinSamples = 95, 0 }, hedge.ErrMinSamplesValidation},
+		{"sample size", func(p *hedge.Policy) { p.Percentile, p.SampleSize = 95, 1 }, hedge.ErrSampleSizeValidation},
+		{"command", func(p *hedge.Policy) { p.CommandContext = nil }, hedge.ErrCommandRequired},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := hedge.New("remote-service")
+			p.Delay = time.Hour
+			p.CommandContext = func(ctx context.Context) error { return nil }
+			c.change(&p)
+
+			assert.ErrorIs(t, p.Run(core.NewMetric()), c.err)
+		})
+	}
+}
+
+func TestRunWithoutHedging(t *testing.T) {
+	calls := 0
+	p := hedge.New("remote-service")
+	p.MaxHedgedAttempts = 0
+	p.Registry = hedge.NewRegistry()
+	p.Command = func() error {
+		calls++
+		return nil
+	}
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+	assert.Equal(t, 1, calls)
+}
+
+func TestRunFirstAttemptWins(t *testing.T) {
+	before, after, hedged := false, false, false
+	p := hedge.New("hedge-service-1")
+	p.Delay = time.Hour
+	p.Clock = core.NewFakeClock(time.Now())
+	p.BeforeHedge = func(p hedge.Policy) { before = true }
+	p.AfterHedge = func(p hedge.Policy, err error) { after = true }
+	p.OnHedge = func(p hedge.Policy, attempt int) { hedged = true }
+	p.CommandContext = func(ctx context.Context) error { return nil }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(hedge.Metric{}).String()].(hedge.Metric)
+
+	assert.Nil(t, err)
+	assert.True(t, before)
+	assert.True(t, after)
+	assert.False(t, hedged)
+	assert.True(t, m.Success())
+	assert.Equal(t, "hedge-service-1", m.ServiceID())
+	assert.Equal(t, 1, m.Attempts)
+	assert.Equal(t, 1, m.Winner)
+	assert.Equal(t, time.Hour, m.Delay)
+	assert.Len(t, m.Executions, 1)
+}
+
+func TestRunHedgedAttemptWins(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	called := make(chan struct{}, 2)
+	canceled := make(chan error, 1)
+	hedged := 0
+	var calls int32
+
+	p := hedge.New("hedge-service-2")
+	p.Delay = time.Millisecond * 100
+	p.Clock = clock
+	p.OnHedge = func(p hedge.Policy, attempt int) { hedged = attempt }
+	p.CommandContext = func(ctx context.Context) error {
+		called <- struct{}{}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			canceled <- ctx.Err()
+
+			return ctx.Err()
+		}
+
+		return nil
+	}
+
+	go func() {
+		<-called
+		clock.BlockUntil(1)
+		clock.Advance(time.Millisecond * 100)
+	}()
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(hedge.Metric{}).String()].(hedge.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, hedged)
+	assert.Equal(t, 2, m.Attempts)
+	assert.Equal(t, 2, m.Winner)
+	assert.True(t, m.Executions[0].Canceled)
+	assert.Equal(t, time.Millisecond*100, m.Executions[0].Duration)
+	assert.False(t, m.Executions[1].Canceled)
+	assert.Equal(t, time.Millisecond*100, m.Executions[1].StartedAt.Sub(m.StartedAt))
+	assert.ErrorIs(t, <-canceled, context.Canceled)
+	assert.Equal(t, 0, clock.Timers())
+}
+
+func TestRunMaxHedgedAttempts(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	called := make(chan struct{}, 3)
+	var calls int32
+
+	p := hedge.New("hedge-service-3")
+	p.Delay = time.Millisecond * 100
+	p.MaxHedgedAttempts = 2
+	p.Clock = clock
+	p.CommandContext = func(ctx context.Context) error {
+		called <- struct{}{}
+		if atomic.AddInt32(&calls, 1) < 3 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+
+		return nil
+	}
+
+	go func() {
+		for i := 0; i < 2; i++ {
+			<-called
+			clock.BlockUntil(1)
+			clock.Advance(time.Millisecond * 100)
+		}
+	}()
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(hedge.Metric{}).String()].(hedge.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, m.Attempts)
+	assert.Equal(t, 3, m.Winner)
+	assert.True(t, m.Executions[0].Canceled)
+	assert.True(t, m.Executions[1].Canceled)
+}
+
+func TestRunAllAttemptsFail(t *testing.T) {
+	errTest1 := errors.New("err test 1")
+	errTest2 := errors.New("err test 2")
+	var calls int32
+
+	p := hedge.New("hedge-service-4")
+	p.Delay = time.Hour
+	p.Clock = core.NewFakeClock(time.Now())
+	p.CommandContext = func(ctx context.Context) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return errTest1
+		}
+
+		return errTest2
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(hedge.Metric{}).String()].(hedge.Metric)
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "hedge", perr.Policy)
+	assert.ErrorIs(t, err, errTest1)
+	assert.ErrorIs(t, err, errTest2)
+
+	// The hedged attempt didn't wait for the delay, as nothing was in progress.
+	assert.Equal(t, 2, m.Attempts)
+	assert.Equal(t, 0, m.Winner)
+	assert.Equal(t, 1, m.Status)
+	assert.ErrorIs(t, m.MetricError(), errTest2)
+}
+
+func TestRunContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	clock := core.NewFakeClock(time.Now())
+	p := hedge.New("hedge-service-5")
+	p.Delay = time.Hour
+	p.Clock = clock
+	p.CommandContext = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	go func() {
+		clock.BlockUntil(1)
+		cancel()
+	}()
+
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	m, _ := metric[reflect.TypeOf(hedge.Metric{}).String()].(hedge.Metric)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.True(t, m.Executions[0].Canceled)
+	assert.ErrorIs(t, m.MetricError(), context.Canceled)
+}
+
+func TestRunResult(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	called := make(chan struct{}, 2)
+	release := make(chan struct{})
+	var calls int32
+
+	p := hedge.New("hedge-service-6")
+	p.Delay = time.Millisecond * 100
+	p.Clock = clock
+	p.CommandContext = func(ctx context.Context) error {
+		called <- struct{}{}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-release
+			core.SetResult(ctx, "late")
+
+			return nil
+		}
+		core.SetResult(ctx, "hedged")
+
+		return nil
+	}
+
+	go func() {
+		<-called
+		clock.BlockUntil(1)
+		clock.Advance(time.Millisecond * 100)
+	}()
+
+	ctx, result := core.NewResultContext(context.Background())
+	err := p.RunContext(ctx, core.NewMetric())
+	close(release)
+
+	value, ok := result.Get()
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "hedged", value)
+}
+
+func TestRunPolicy(t *testing.T) {
+	policy := new(mockPolicy)
+	policy.On("Run").Return(nil)
+
+	p := hedge.New("hedge-service-7")
+	p.Delay = time.Hour
+	p.Policy = policy
+
+	metric := core.NewMetric()
+	metric[reflect.TypeOf(Metric{}).String()] = Metric{ID: "stale", Status: 1}
+	err := p.Run(metric)
+
+	assert.Nil(t, err)
+	assert.Len(t, metric, 2)
+}
+
+func TestRunSwallowErrors(t *testing.T) {
+	errTest := errors.New("err test")
+	p := hedge.New("hedge-service-8")
+	p.Delay = time.Hour
+	p.SwallowErrors = true
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(hedge.Metric{}).String()].(hedge.Metric)
+
+	assert.Nil(t, err)
+	assert.ErrorIs(t, m.MetricError(), errTest)
+}
+
+func TestWithCommand(t *testing.T) {
+	p := hedge.New("remote-service")
+	c := func() error { return nil }
+	s := p.WithCommand(c)
+	p, _ = s.(hedge.Policy)
+
+	assert.NotNil(t, p.Command)
+}
+
+func TestWithCommandContext(t *testing.T) {
+	p := hedge.New("remote-service")
+	c := func(ctx context.Context) error { return nil }
+	s := p.WithCommandContext(c)
+	p, _ = s.(hedge.Policy)
+
+	assert.NotNil(t, p.CommandContext)
+}
+
+func TestWithPolicy(t *testing.T) {
+	p := hedge.New("remote-service")
+	s := p.WithPolicy(hedge.New("any"))
+	p, _ = s.(hedge.Policy)
+
+	assert.NotNil(t, p.Policy)
+}
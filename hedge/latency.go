@@ -0,0 +1,71 @@
+package hedge
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencies keeps the latencies of the last successful attempts of a service.
+type latencies struct {
+	size  int
+	sized bool // Whether size was set by a policy using Percentile.
+
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+// observe records a latency, replacing the oldest one once size samples are kept. Nothing is kept
+// without a size, as a policy with a fixed delay needs none.
+func (l *latencies) observe(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	switch {
+	case l.size <= 0:
+		return
+	case len(l.samples) < l.size:
+		l.samples = append(l.samples, d)
+	default:
+		l.samples[l.next%len(l.samples)] = d
+		l.next = (l.next + 1) % len(l.samples)
+	}
+}
+
+// resize keeps up to size of the latest samples, and at most size samples from then on.
+func (l *latencies) resize(size int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	latest := make([]time.Duration, 0, len(l.samples))
+	latest = append(latest, l.samples[l.next:]...)
+	latest = append(latest, l.samples[:l.next]...)
+	if len(latest) > size {
+		latest = latest[len(latest)-size:]
+	}
+
+	l.size = size
+	l.samples = latest
+	l.next = 0
+}
+
+// percentile returns the nearest-rank percentile of the samples.
+func (l *latencies) percentile(p float64) (time.Duration, int) {
+	l.mu.Lock()
+	sorted := append([]time.Duration(nil), l.samples...)
+	l.mu.Unlock()
+
+	if len(sorted) == 0 {
+		return 0, 0
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+
+	return sorted[rank-1], len(sorted)
+}
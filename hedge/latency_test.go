@@ -0,0 +1,142 @@
+package hedge_test
+
+import (
+	"context"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/hedge"
+	"github.com/stretchr/testify/assert"
+)
+
+// observe runs successful attempts which take the given latencies.
+func observe(p hedge.Policy, clock *core.FakeClock, latencies ...time.Duration) hedge.Metric {
+	var m hedge.Metric
+	for _, latency := range latencies {
+		latency := latency
+		p.CommandContext = func(ctx context.Context) error {
+			clock.Advance(latency)
+			return nil
+		}
+
+		metric := core.NewMetric()
+		_ = p.Run(metric)
+		m, _ = metric[reflect.TypeOf(hedge.Metric{}).String()].(hedge.Metric)
+	}
+
+	return m
+}
+
+func TestLatencyNone(t *testing.T) {
+	hedge.Reset("latency-service-1")
+
+	d, samples := hedge.Latency("latency-service-1", 95)
+	assert.Equal(t, time.Duration(0), d)
+	assert.Equal(t, 0, samples)
+}
+
+func TestLatencyPercentile(t *testing.T) {
+	hedge.Reset("latency-service-2")
+	clock := core.NewFakeClock(time.Now())
+	p := hedge.New("latency-service-2")
+	p.Delay = time.Hour
+	p.MaxHedgedAttempts = 0
+	p.Clock = clock
+
+	observe(p, clock, time.Millisecond*40, time.Millisecond*10, time.Millisecond*30, time.Millisecond*20)
+
+	d, samples := hedge.Latency("latency-service-2", 50)
+	assert.Equal(t, time.Millisecond*20, d)
+	assert.Equal(t, 4, samples)
+
+	d, _ = hedge.Latency("latency-service-2", 95)
+	assert.Equal(t, time.Millisecond*40, d)
+
+	d, _ = hedge.Latency("latency-service-2", 1)
+	assert.Equal(t, time.Millisecond*10, d)
+}
+
+func TestLatencySampleSize(t *testing.T) {
+	hedge.Reset("latency-service-3")
+	clock := core.NewFakeClock(time.Now())
+	p := hedge.New("latency-service-3")
+	p.Delay = time.Hour
+	p.MaxHedgedAttempts = 0
+	p.Percentile = 50
+	p.MinSamples = 1
+	p.SampleSize = 2
+	p.Clock = clock
+
+	observe(p, clock, time.Millisecond*10, time.Millisecond*20, time.Millisecond*30)
+
+	d, samples := hedge.Latency("latency-service-3", 1)
+	assert.Equal(t, time.Millisecond*20, d)
+	assert.Equal(t, 2, samples)
+}
+
+func TestRunPercentileDelay(t *testing.T) {
+	hedge.Reset("latency-service-4")
+	clock := core.NewFakeClock(time.Now())
+	p := hedge.New("latency-service-4")
+	p.Delay = time.Hour
+	p.MaxHedgedAttempts = 0
+	p.Percentile = 50
+	p.MinSamples = 3
+	p.Clock = clock
+
+	// The fixed delay is used until enough latencies are observed.
+	m := observe(p, clock, time.Millisecond*10, time.Millisecond*20, time.Millisecond*30)
+	assert.Equal(t, time.Hour, m.Delay)
+
+	m = observe(p, clock, time.Millisecond*10)
+	assert.Equal(t, time.Millisecond*20, m.Delay)
+}
+
+func TestLatencyFixedDelayWithoutSampleSize(t *testing.T) {
+	hedge.Reset("latency-service-5")
+	clock := core.NewFakeClock(time.Now())
+	p := hedge.Policy{ServiceID: "latency-service-5", Delay: time.Hour, Clock: clock}
+
+	m := observe(p, clock, time.Millisecond*10, time.Millisecond*20)
+
+	assert.True(t, m.Success())
+	_, samples := hedge.Latency("latency-service-5", 95)
+	assert.Equal(t, 0, samples)
+}
+
+func TestLatencyOfHedgedCall(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	called := make(chan struct{}, 2)
+	var calls int32
+
+	p := hedge.New("latency-service-6")
+	p.Delay = time.Millisecond * 100
+	p.Registry = hedge.NewRegistry()
+	p.Clock = clock
+	p.CommandContext = func(ctx context.Context) error {
+		called <- struct{}{}
+		if atomic.AddInt32(&calls, 1) == 1 {
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		clock.Advance(time.Millisecond * 10)
+
+		return nil
+	}
+
+	go func() {
+		<-called
+		clock.BlockUntil(1)
+		clock.Advance(time.Millisecond * 100)
+	}()
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	// The caller waited for the delay besides the winning attempt.
+	d, samples := p.Registry.Latency("latency-service-6", 95)
+	assert.Equal(t, time.Millisecond*110, d)
+	assert.Equal(t, 1, samples)
+}
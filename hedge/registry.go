@@ -0,0 +1,97 @@
+package hedge
+
+import (
+	"sync"
+	"time"
+)
+
+// Registry keeps the latencies observed for services. Policies sharing a registry and a ServiceID share
+// their latencies, while separate registries are isolated from each other. Policies without a Registry
+// use the default one.
+type Registry struct {
+	mu       sync.Mutex
+	services map[string]*latencies
+}
+
+var defaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{services: make(map[string]*latencies)}
+}
+
+// DefaultRegistry returns the registry used by policies without a Registry.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Latency queries for the given percentile of the latencies observed for the service in the default
+// registry (see Registry.Latency).
+func Latency(serviceID string, percentile float64) (time.Duration, int) {
+	return defaultRegistry.Latency(serviceID, percentile)
+}
+
+// Reset drops the latencies observed for the service in the default registry (see Registry.Reset).
+func Reset(serviceID string) {
+	defaultRegistry.Reset(serviceID)
+}
+
+// Latency queries for the given percentile (e.g. 95) of the latencies observed for the service.
+//
+// Returns the latency and the number of samples it was computed from.
+func (r *Registry) Latency(serviceID string, percentile float64) (time.Duration, int) {
+	r.mu.Lock()
+	l := r.services[serviceID]
+	r.mu.Unlock()
+
+	if l == nil {
+		return 0, 0
+	}
+
+	return l.percentile(percentile)
+}
+
+// Reset drops the latencies observed for the service, so that they are observed afresh, with the
+// SampleSize of the policy observing them.
+func (r *Registry) Reset(serviceID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.services, serviceID)
+}
+
+// get returns the latencies of the service, creating them with the sample size of the policy if there
+// are none. Only policies using Percentile depend on the sample size: the first of them sets it, and the
+// others must agree with it.
+//
+// Possible error(s): ErrSettingsConflict.
+func (r *Registry) get(p Policy) (*latencies, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	l := r.services[p.ServiceID]
+	if l == nil {
+		l = &latencies{size: p.SampleSize}
+		r.services[p.ServiceID] = l
+	}
+
+	switch {
+	case p.Percentile == 0:
+		return l, nil
+	case !l.sized:
+		l.resize(p.SampleSize)
+		l.sized = true
+	case l.size != p.SampleSize:
+		return nil, ErrSettingsConflict
+	}
+
+	return l, nil
+}
+
+func registryOf(p Policy) *Registry {
+	if p.Registry == nil {
+		return defaultRegistry
+	}
+
+	return p.Registry
+}
@@ -0,0 +1,97 @@
+package hedge_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/hedge"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	assert.NotNil(t, hedge.DefaultRegistry())
+	assert.Same(t, hedge.DefaultRegistry(), hedge.DefaultRegistry())
+}
+
+func TestRegistryIsolation(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	first, second := hedge.NewRegistry(), hedge.NewRegistry()
+	p := hedge.New("service")
+	p.Delay = time.Hour
+	p.MaxHedgedAttempts = 0
+	p.Clock = clock
+
+	p.Registry = first
+	observe(p, clock, time.Millisecond*10, time.Millisecond*20)
+	p.Registry = second
+	observe(p, clock, time.Millisecond*30)
+
+	d, samples := first.Latency("service", 95)
+	assert.Equal(t, time.Millisecond*20, d)
+	assert.Equal(t, 2, samples)
+
+	d, samples = second.Latency("service", 95)
+	assert.Equal(t, time.Millisecond*30, d)
+	assert.Equal(t, 1, samples)
+}
+
+func TestRegistrySettingsConflict(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	r := hedge.NewRegistry()
+	p := hedge.New("service")
+	p.Delay = time.Hour
+	p.MaxHedgedAttempts = 0
+	p.Percentile = 95
+	p.Registry = r
+	p.Clock = clock
+	observe(p, clock, time.Millisecond*10)
+
+	other := p
+	other.SampleSize = p.SampleSize * 2
+	other.Command = func() error { return nil }
+	metric := core.NewMetric()
+	assert.ErrorIs(t, other.Run(metric), hedge.ErrSettingsConflict)
+	assert.Empty(t, metric)
+
+	// Policies of the same sample size share the latencies, whatever their percentile.
+	other.SampleSize = p.SampleSize
+	other.Percentile = 50
+	assert.Nil(t, other.Run(core.NewMetric()))
+
+	// A policy with a fixed delay doesn't depend on the sample size.
+	other.SampleSize = 0
+	other.Percentile = 0
+	assert.Nil(t, other.Run(core.NewMetric()))
+
+	r.Reset("service")
+	other.SampleSize = p.SampleSize * 2
+	other.Percentile = 50
+	assert.Nil(t, other.Run(core.NewMetric()))
+	_, samples := r.Latency("service", 50)
+	assert.Equal(t, 1, samples)
+}
+
+func TestRegistryFixedDelayFirst(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	r := hedge.NewRegistry()
+	fixed := hedge.Policy{ServiceID: "service", Delay: time.Hour, Registry: r, Clock: clock}
+	observe(fixed, clock, time.Millisecond*10)
+
+	// The first policy using Percentile sets the sample size.
+	p := hedge.New("service")
+	p.Delay = time.Hour
+	p.MaxHedgedAttempts = 0
+	p.Percentile = 50
+	p.Registry = r
+	p.Clock = clock
+	observe(p, clock, time.Millisecond*10, time.Millisecond*20)
+	observe(fixed, clock, time.Millisecond*30)
+
+	_, samples := r.Latency("service", 50)
+	assert.Equal(t, 3, samples)
+
+	p.SampleSize = p.SampleSize * 2
+	p.Command = func() error { return nil }
+	assert.ErrorIs(t, p.Run(core.NewMetric()), hedge.ErrSettingsConflict)
+}
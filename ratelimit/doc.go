@@ -0,0 +1,153 @@
+/*
+The rate limit pattern controls how often a service is called. It keeps a caller within the quota of a
+partner API, and keeps a service from being called faster than it can handle. Calls over the rate are
+rejected straight away or wait for their turn.
+
+# Usage
+
+There are two ways to run a command under a policy. Using a Command supplier
+(anonymous function) or a wrapped policy.
+
+# Command supplier
+
+	p := ratelimit.New("service-id")
+	p.Limit = 100
+	p.Period = time.Minute
+	p.Command = func() error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+
+	var limited *ratelimit.RateLimitedError
+	if errors.As(err, &limited) {
+		fmt.Println("Try again in", limited.Wait)
+	}
+
+	// Prints RateLimit metric.
+	fmt.Println(metric)
+
+# Wrapped policy
+
+	policy := new(AnyPolicy)
+	policy.Command = func() error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	rl := ratelimit.New("service-id")
+	rl.Limit = 100
+	rl.Period = time.Minute
+
+	// Instead of a command supplier, it is passed a policy.
+	rl.Policy = policy
+
+	metric := core.NewMetric()
+	err := rl.Run(metric)
+
+	mr := metric["ratelimit.Metric"] // or metric[reflect.TypeOf(ratelimit.Metric{}).String()]
+	rlMetric, _ := mr.(ratelimit.Metric)
+
+	// Prints RateLimit metric.
+	fmt.Println(rlMetric)
+
+# Algorithms
+
+Calls of the same ServiceID share the rate limit, whatever policy value runs them. Limit calls are
+permitted per Period, and Algorithm tells how permits are handed out.
+
+	> TokenBucketAlgorithm:      Permits are added evenly up to Burst (Limit if not set), so that a service
+	                             idle for a while may be called Burst times at once.
+	> LeakyBucketAlgorithm:      Calls are evenly spaced, up to Burst (one if not set) of them coming closer.
+	> SlidingWindowLogAlgorithm: At most Limit calls within any Period, however close to each other.
+
+# Registry
+
+Rate limits are kept by a Registry. Policies sharing a registry and a ServiceID share a rate limit, so
+unrelated components (or tests) should use registries of their own. Policies without a Registry use the
+default one (see DefaultRegistry). A rate limit keeps the Algorithm, Limit, Period and Burst of the policy
+which created it: a policy of the same service with other settings fails with ErrSettingsConflict, until
+Reset drops the state of the service.
+
+	registry := ratelimit.NewRegistry()
+
+	p := ratelimit.New("service-id")
+	p.Registry = registry
+	...
+
+	registry.Reset("service-id")
+
+# Mode
+
+In FailFastMode, a call which isn't permitted is rejected with a *RateLimitedError, which is an
+ErrRateLimited and tells how long until the next permit. It implements retry.DelaySuggester, so a
+retry policy wrapping the rate limit waits just as long before trying again.
+
+	rl := ratelimit.New("service-id")
+	rl.Limit = 10
+	rl.Period = time.Second
+
+	rt := retry.New("service-id")
+	rt.Tries = 3
+	rt.Errors = []error{ratelimit.ErrRateLimited}
+
+	metric, err := resiliencia.Chain(rt, rl).Execute(command)
+
+In BlockMode, a call waits for its permit. MaxWait rejects calls whose permit is further than that,
+without waiting. The metric records how long each call waited.
+
+# Errors
+
+A rejected call makes the policy return a *core.PolicyError wrapping a *RateLimitedError or the error of
+the context. A call which fails makes it return a *core.PolicyError wrapping the error of the command
+supplier (or of the wrapped policy). SwallowErrors makes the policy return rejection errors as they are and
+nil when the call fails (see core.PolicyError).
+
+# Context
+
+Every policy may also run bound to a context.Context. Use CommandContext instead of Command so that the
+command supplier receives the context, and RunContext instead of Run. A wrapped policy receives the same context.
+
+	p := ratelimit.New("service-id")
+	p.Mode = ratelimit.BlockMode
+	p.CommandContext = func(ctx context.Context) error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	err := p.RunContext(ctx, core.NewMetric())
+
+A call stops waiting for its permit as soon as the context is done, and the permit is given back. Time is
+told by the policy Clock (see core.Clock), which tests may replace by a core.FakeClock.
+
+# Listener
+
+In order to keep tracking of what is happening on the execution, you may use listeners to generate some events.
+ratelimit supports listeners to track events before and after policy execution, and when a call is rejected.
+
+	p := ratelimit.New("service-id")
+	...
+	p.BeforeRateLimit = func(p ratelimit.Policy) {
+		fmt.Println("Before rate limit.")
+	}
+	p.AfterRateLimit = func(p ratelimit.Policy, err error) {
+		fmt.Println("After rate limit.")
+	}
+	p.OnRateLimited = func(p ratelimit.Policy, err *ratelimit.RateLimitedError) {
+		fmt.Println("Rate limited, next permit in", err.Wait)
+	}
+
+	_ = p.Run(core.Metric())
+*/
+package ratelimit
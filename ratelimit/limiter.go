@@ -0,0 +1,192 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Algorithm is the way a rate limiter hands out permits.
+type Algorithm int
+
+const (
+	// Indicates a token bucket: permits (tokens) are added at Limit per Period up to Burst, and each call
+	// takes one. Bursts up to Burst calls are allowed after an idle time.
+	TokenBucketAlgorithm = Algorithm(0)
+
+	// Indicates a leaky bucket: calls flow out evenly spaced at Limit per Period, and up to Burst calls
+	// may come closer than that.
+	LeakyBucketAlgorithm = Algorithm(1)
+
+	// Indicates a sliding window log: at most Limit calls are permitted within any Period.
+	SlidingWindowLogAlgorithm = Algorithm(2)
+)
+
+// limiter hands out permits. reserve returns when the next permit is available from now on, taking
+// it only if it's no later than deadline. cancel gives back a permit taken for the given time.
+type limiter interface {
+	reserve(now, deadline time.Time) (time.Time, bool)
+	cancel(at time.Time)
+}
+
+// service keeps the limiter of a service along with the settings it was made from.
+type service struct {
+	mu       sync.Mutex
+	settings settings
+	limiter  limiter
+}
+
+type settings struct {
+	algorithm Algorithm
+	limit     int
+	period    time.Duration
+	burst     int
+}
+
+func (s *service) reserve(now, deadline time.Time) (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.limiter.reserve(now, deadline)
+}
+
+func (s *service) cancel(at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.limiter.cancel(at)
+}
+
+func settingsOf(p Policy) settings {
+	s := settings{algorithm: p.Algorithm, limit: p.Limit, period: p.Period, burst: p.Burst}
+
+	switch {
+	case s.burst > 0:
+	case s.algorithm == TokenBucketAlgorithm:
+		s.burst = s.limit
+	default:
+		s.burst = 1
+	}
+
+	return s
+}
+
+func newLimiter(s settings) limiter {
+	interval := s.period / time.Duration(s.limit)
+
+	switch s.algorithm {
+	case LeakyBucketAlgorithm:
+		return &leakyBucket{interval: interval, tolerance: interval * time.Duration(s.burst-1)}
+	case SlidingWindowLogAlgorithm:
+		return &windowLog{limit: s.limit, period: s.period}
+	default:
+		return &tokenBucket{
+			limit:  float64(s.limit),
+			period: float64(s.period),
+			burst:  float64(s.burst),
+			tokens: float64(s.burst),
+		}
+	}
+}
+
+// tokenBucket keeps how many tokens were left at the last reservation. Tokens go below zero while
+// permits are reserved ahead of time.
+type tokenBucket struct {
+	limit  float64
+	period float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (b *tokenBucket) reserve(now, deadline time.Time) (time.Time, bool) {
+	tokens := b.tokens
+	if !b.last.IsZero() && now.After(b.last) {
+		tokens = math.Min(b.burst, tokens+float64(now.Sub(b.last))*b.limit/b.period)
+	}
+
+	at := now
+	if tokens < 1 {
+		at = now.Add(time.Duration(math.Ceil((1 - tokens) * b.period / b.limit)))
+	}
+	if at.After(deadline) {
+		return at, false
+	}
+
+	if now.After(b.last) {
+		b.last = now
+	}
+	b.tokens = tokens - 1
+
+	return at, true
+}
+
+func (b *tokenBucket) cancel(_ time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+1)
+}
+
+// leakyBucket implements the generic cell rate algorithm: tat is the theoretical arrival time of the
+// next call were calls evenly spaced, and calls may arrive up to tolerance earlier than that.
+type leakyBucket struct {
+	interval  time.Duration
+	tolerance time.Duration
+	tat       time.Time
+}
+
+func (b *leakyBucket) reserve(now, deadline time.Time) (time.Time, bool) {
+	tat := b.tat
+	if tat.Before(now) {
+		tat = now
+	}
+
+	at := now
+	if allowed := tat.Add(-b.tolerance); allowed.After(now) {
+		at = allowed
+	}
+	if at.After(deadline) {
+		return at, false
+	}
+	b.tat = tat.Add(b.interval)
+
+	return at, true
+}
+
+func (b *leakyBucket) cancel(_ time.Time) {
+	b.tat = b.tat.Add(-b.interval)
+}
+
+// windowLog keeps the time of every permit within the last period (and of those reserved ahead of time),
+// in ascending order.
+type windowLog struct {
+	limit  int
+	period time.Duration
+	log    []time.Time
+}
+
+func (w *windowLog) reserve(now, deadline time.Time) (time.Time, bool) {
+	expired := 0
+	for expired < len(w.log) && !w.log[expired].After(now.Add(-w.period)) {
+		expired++
+	}
+	w.log = w.log[expired:]
+
+	at := now
+	if len(w.log) >= w.limit {
+		at = w.log[len(w.log)-w.limit].Add(w.period)
+	}
+	if at.After(deadline) {
+		return at, false
+	}
+	w.log = append(w.log, at)
+
+	return at, true
+}
+
+func (w *windowLog) cancel(at time.Time) {
+	for i := len(w.log) - 1; i >= 0; i-- {
+		if w.log[i].Equal(at) {
+			w.log = append(w.log[:i], w.log[i+1:]...)
+			return
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+// call runs a successful command and returns the time until the next permit (zero if permitted).
+func call(t *testing.T, p ratelimit.Policy) time.Duration {
+	p.Command = func() error { return nil }
+
+	err := p.Run(core.NewMetric())
+	if err == nil {
+		return 0
+	}
+
+	var limited *ratelimit.RateLimitedError
+	assert.ErrorAs(t, err, &limited)
+
+	return limited.Wait
+}
+
+func TestTokenBucket(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	p := ratelimit.New("token-bucket-1")
+	ratelimit.Reset("token-bucket-1")
+	p.Limit = 2
+	p.Period = time.Second
+	p.Clock = clock
+
+	assert.Zero(t, call(t, p))
+	assert.Zero(t, call(t, p))
+	assert.Equal(t, time.Millisecond*500, call(t, p))
+
+	clock.Advance(time.Millisecond * 200)
+	assert.Equal(t, time.Millisecond*300, call(t, p))
+
+	clock.Advance(time.Millisecond * 300)
+	assert.Zero(t, call(t, p))
+	assert.Equal(t, time.Millisecond*500, call(t, p))
+}
+
+func TestTokenBucketBurst(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	p := ratelimit.New("token-bucket-2")
+	ratelimit.Reset("token-bucket-2")
+	p.Limit = 1
+	p.Period = time.Second
+	p.Burst = 3
+	p.Clock = clock
+
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, call(t, p))
+	}
+	assert.Equal(t, time.Second, call(t, p))
+
+	// Tokens don't pile up beyond the burst.
+	clock.Advance(time.Hour)
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, call(t, p))
+	}
+	assert.Equal(t, time.Second, call(t, p))
+}
+
+func TestLeakyBucket(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	p := ratelimit.New("leaky-bucket-1")
+	ratelimit.Reset("leaky-bucket-1")
+	p.Algorithm = ratelimit.LeakyBucketAlgorithm
+	p.Limit = 10
+	p.Period = time.Second
+	p.Clock = clock
+
+	assert.Zero(t, call(t, p))
+	assert.Equal(t, time.Millisecond*100, call(t, p))
+
+	clock.Advance(time.Millisecond * 60)
+	assert.Equal(t, time.Millisecond*40, call(t, p))
+
+	clock.Advance(time.Millisecond * 40)
+	assert.Zero(t, call(t, p))
+
+	// Calls are evenly spaced, however long the bucket was idle.
+	clock.Advance(time.Hour)
+	assert.Zero(t, call(t, p))
+	assert.Equal(t, time.Millisecond*100, call(t, p))
+}
+
+func TestLeakyBucketBurst(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	p := ratelimit.New("leaky-bucket-2")
+	ratelimit.Reset("leaky-bucket-2")
+	p.Algorithm = ratelimit.LeakyBucketAlgorithm
+	p.Limit = 10
+	p.Period = time.Second
+	p.Burst = 3
+	p.Clock = clock
+
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, call(t, p))
+	}
+	assert.Equal(t, time.Millisecond*100, call(t, p))
+
+	clock.Advance(time.Millisecond * 100)
+	assert.Zero(t, call(t, p))
+	assert.Equal(t, time.Millisecond*100, call(t, p))
+}
+
+func TestSlidingWindowLog(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	p := ratelimit.New("window-log-1")
+	ratelimit.Reset("window-log-1")
+	p.Algorithm = ratelimit.SlidingWindowLogAlgorithm
+	p.Limit = 3
+	p.Period = time.Second
+	p.Clock = clock
+
+	for i := 0; i < 3; i++ {
+		assert.Zero(t, call(t, p))
+		clock.Advance(time.Millisecond * 100)
+	}
+	assert.Equal(t, time.Millisecond*700, call(t, p))
+
+	clock.Advance(time.Millisecond * 700)
+	assert.Zero(t, call(t, p))
+	assert.Equal(t, time.Millisecond*100, call(t, p))
+
+	clock.Advance(time.Millisecond * 100)
+	assert.Zero(t, call(t, p))
+}
+
+func TestSettingsChange(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	r := ratelimit.NewRegistry()
+	p := ratelimit.New("settings-change")
+	p.Registry = r
+	p.Clock = clock
+
+	assert.Zero(t, call(t, p))
+	assert.Equal(t, time.Second, call(t, p))
+
+	// Policies of a service must agree on its settings.
+	other := p
+	other.Limit = 2
+	other.Command = func() error { return nil }
+	assert.ErrorIs(t, other.Run(core.NewMetric()), ratelimit.ErrSettingsConflict)
+	assert.Equal(t, time.Second, call(t, p))
+
+	// A reset service starts afresh, with new settings.
+	r.Reset(p.ServiceID)
+	assert.Zero(t, call(t, other))
+	assert.Zero(t, call(t, other))
+	assert.Equal(t, time.Millisecond*500, call(t, other))
+
+	// Every service has a limit of its own.
+	other.ServiceID = "settings-change-other"
+	assert.Zero(t, call(t, other))
+}
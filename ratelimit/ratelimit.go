@@ -0,0 +1,369 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+)
+
+var (
+	// Policy algorithm is unknown.
+	ErrAlgorithmValidation = errors.New("unknown rate limit algorithm")
+
+	// Policy limit is less than minimum required.
+	ErrLimitValidation = fmt.Errorf("limit must be >= %d", MinLimit)
+
+	// Policy period is less than minimum required.
+	ErrPeriodValidation = fmt.Errorf("period must be >= %s", MinPeriod)
+
+	// Policy burst is less than minimum required.
+	ErrBurstValidation = fmt.Errorf("burst must be >= %d", MinBurst)
+
+	// Policy max wait is less than minimum required.
+	ErrMaxWaitValidation = fmt.Errorf("max wait must be >= %d", MinMaxWait)
+
+	// No command nor wrapped policy is set.
+	ErrCommandRequired = errors.New("command nor wrapped policy provided")
+
+	// The rate limit doesn't permit the call (see RateLimitedError).
+	ErrRateLimited = errors.New("rate limited")
+
+	// The rate limit of the service was created with other Algorithm, Limit, Period or Burst settings.
+	ErrSettingsConflict = errors.New("rate limit settings conflict with those of the service")
+)
+
+// RateLimitedError is the error of a call which the rate limit doesn't permit. It is an ErrRateLimited
+// and suggests when to try again (see retry.DelaySuggester).
+type RateLimitedError struct {
+	// How long until the next permit is available.
+	Wait time.Duration
+}
+
+// Error returns the error message along with the time until the next permit.
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%s (next permit in %s)", ErrRateLimited, e.Wait)
+}
+
+// Is tells whether target is ErrRateLimited.
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RetryAfter returns how long until the next permit is available.
+func (e *RateLimitedError) RetryAfter() time.Duration {
+	return e.Wait
+}
+
+// Mode is what the rate limiter does about a call which isn't permitted yet.
+type Mode int
+
+const (
+	// Indicates that calls which aren't permitted are rejected straight away.
+	FailFastMode = Mode(0)
+
+	// Indicates that calls wait until they are permitted.
+	BlockMode = Mode(1)
+)
+
+// Policy defines the rate limit algorithm execution policy.
+type Policy struct {
+	// The registered service id. Calls of the same service share the rate limit.
+	ServiceID string
+
+	// How permits are handed out (TokenBucketAlgorithm if not set).
+	Algorithm Algorithm
+
+	// Number of calls permitted per Period.
+	Limit int
+
+	// Period which Limit refers to.
+	Period time.Duration
+
+	// Number of calls which may be made at once (zero means Limit for a token bucket and one for a leaky
+	// bucket). Ignored by a sliding window log.
+	Burst int
+
+	// What to do about a call which isn't permitted yet (FailFastMode if not set).
+	Mode Mode
+
+	// How long a call waits for its permit in BlockMode (zero means no limit). A call whose permit is
+	// further than that is rejected straight away.
+	MaxWait time.Duration
+
+	// Clock used to tell time and to wait for permits (real time if not set).
+	Clock core.Clock
+
+	// Registry which keeps the rate limit of the service (the default registry if not set).
+	Registry *Registry
+
+	// Whether the policy returns rejection errors (e.g. a *RateLimitedError) as they are, and nil when the
+	// command supplier or the wrapped policy fails, whose error is then only recorded in the metric.
+	SwallowErrors bool
+
+	// Function called before execution.
+	BeforeRateLimit func(p Policy)
+
+	// Function called after execution.
+	AfterRateLimit func(p Policy, err error)
+
+	// Function called when a call is rejected.
+	OnRateLimited func(p Policy, err *RateLimitedError)
+
+	// The command supplier.
+	Command core.Command
+
+	// The context-aware command supplier. It takes precedence over Command.
+	CommandContext core.CommandContext
+
+	// Any policy that will be wrapped by this one.
+	Policy core.PolicySupplier
+}
+
+// Metric keeps the running state of the rate limit.
+type Metric struct {
+	// The registered service id.
+	ID string
+
+	// The execution status (success is non zero).
+	Status int
+
+	// When execution started.
+	StartedAt time.Time
+
+	// When execution finished.
+	FinishedAt time.Time
+
+	// The error (if execution wasn't succeeded)
+	Error error
+
+	// Whether the call was rejected.
+	Limited bool
+
+	// How long the call waited for its permit.
+	Wait time.Duration
+
+	// How long until the next permit was available, if the call was rejected.
+	RetryAfter time.Duration
+}
+
+const (
+	// Minimum expected to be set on Limit field of a rate limit policy.
+	MinLimit = 1
+
+	// Minimum expected to be set on Period field of a rate limit policy.
+	MinPeriod = time.Nanosecond
+
+	// Minimum expected to be set on Burst field of a rate limit policy.
+	MinBurst = 0
+
+	// Minimum expected to be set on MaxWait field of a rate limit policy.
+	MinMaxWait = 0
+)
+
+// New creates a rate limit policy with default values set.
+func New(serviceID string) Policy {
+	return Policy{
+		ServiceID: serviceID,
+		Algorithm: TokenBucketAlgorithm,
+		Limit:     MinLimit,
+		Period:    time.Second,
+		Mode:      FailFastMode,
+	}
+}
+
+// Run executes a command supplier or a wrapped policy in a rate limit.
+//
+// Possible error(s): ErrAlgorithmValidation, ErrLimitValidation, ErrPeriodValidation, ErrBurstValidation,
+// ErrMaxWaitValidation, ErrCommandRequired, ErrSettingsConflict, ErrRateLimited (see RunContext).
+func (p Policy) Run(metric core.Metric) error {
+	return p.RunContext(context.Background(), metric)
+}
+
+// RunContext executes a command supplier or a wrapped policy in a rate limit bound to a context.
+// A call in BlockMode stops waiting for its permit, and gives it back, as soon as ctx is done.
+//
+// Validation errors and ErrSettingsConflict are returned as they are. Any other error is a
+// *core.PolicyError wrapping a *RateLimitedError, the error of ctx or the error of the command supplier
+// or of the wrapped policy, unless SwallowErrors is set.
+//
+// Possible error(s): ErrAlgorithmValidation, ErrLimitValidation, ErrPeriodValidation, ErrBurstValidation,
+// ErrMaxWaitValidation, ErrCommandRequired, ErrSettingsConflict, ErrRateLimited, context.Canceled,
+// context.DeadlineExceeded.
+func (p Policy) RunContext(ctx context.Context, metric core.Metric) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+
+	svc, err := registryOf(p).get(p)
+	if err != nil {
+		return err
+	}
+
+	clock := core.ClockOrDefault(p.Clock)
+	m := Metric{ID: p.ServiceID, StartedAt: clock.Now()}
+	if p.BeforeRateLimit != nil {
+		p.BeforeRateLimit(p)
+	}
+
+	if err = acquire(ctx, p, svc, clock, &m); err != nil {
+		m.Status = 1
+		m.Error = err
+
+		var limited *RateLimitedError
+		if errors.As(err, &limited) && p.OnRateLimited != nil {
+			p.OnRateLimited(p, limited)
+		}
+		if p.AfterRateLimit != nil {
+			p.AfterRateLimit(p, err)
+		}
+		m.FinishedAt = clock.Now()
+		metric[reflect.TypeOf(m).String()] = m
+
+		return failure(p, err, nil)
+	}
+
+	err = execute(ctx, p, metric)
+	err = pickError(err, metric)
+
+	if err != nil {
+		m.Status = 1
+		m.Error = err
+	}
+
+	if p.AfterRateLimit != nil {
+		p.AfterRateLimit(p, err)
+	}
+	m.FinishedAt = clock.Now()
+	metric[reflect.TypeOf(m).String()] = m
+
+	return failure(p, nil, err)
+}
+
+// WithCommand encapsulates this policy in a new policy with given command supplier.
+func (p Policy) WithCommand(command core.Command) core.PolicySupplier {
+	p.Command = command
+	return p
+}
+
+// WithCommandContext encapsulates this policy in a new policy with given context-aware command supplier.
+func (p Policy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.CommandContext = command
+	return p
+}
+
+// WithPolicy encapsulates this policy in a new policy with wrapped policy.
+func (p Policy) WithPolicy(policy core.PolicySupplier) core.PolicySupplier {
+	p.Policy = policy
+	return p
+}
+
+// acquire takes a permit, waiting for it in BlockMode, and records the wait on the metric.
+func acquire(ctx context.Context, p Policy, svc *service, clock core.Clock, m *Metric) error {
+	now := clock.Now()
+	deadline := now
+
+	if p.Mode == BlockMode {
+		deadline = now.Add(time.Duration(math.MaxInt64))
+		if p.MaxWait > 0 {
+			deadline = now.Add(p.MaxWait)
+		}
+	}
+
+	at, ok := svc.reserve(now, deadline)
+	if !ok {
+		m.Limited = true
+		m.RetryAfter = at.Sub(now)
+
+		return &RateLimitedError{Wait: m.RetryAfter}
+	}
+
+	if wait := at.Sub(now); wait > 0 {
+		if err := core.SleepContext(ctx, clock, wait); err != nil {
+			svc.cancel(at)
+			m.Wait = clock.Now().Sub(now)
+
+			return err
+		}
+		m.Wait = wait
+	}
+
+	return nil
+}
+
+func execute(ctx context.Context, p Policy, metric core.Metric) error {
+	if p.Policy == nil {
+		if p.CommandContext != nil {
+			return p.CommandContext(ctx)
+		}
+
+		return p.Command()
+	}
+
+	return core.RunPolicy(ctx, p.Policy, metric)
+}
+
+func pickError(err error, metric core.MetricRecorder) error {
+	if err != nil {
+		return err
+	}
+
+	return metric.MetricError()
+}
+
+// failure wraps the rejection error or the error of the execution, unless errors are swallowed.
+func failure(p Policy, err, cause error) error {
+	switch {
+	case err == nil && cause == nil:
+		return nil
+	case p.SwallowErrors:
+		return err
+	default:
+		return &core.PolicyError{Policy: "ratelimit", ServiceID: p.ServiceID, Err: err, Cause: cause}
+	}
+}
+
+func validate(p Policy) error {
+	switch {
+	case p.Algorithm < TokenBucketAlgorithm || p.Algorithm > SlidingWindowLogAlgorithm:
+		return ErrAlgorithmValidation
+	case p.Limit < MinLimit:
+		return ErrLimitValidation
+	case p.Period < MinPeriod:
+		return ErrPeriodValidation
+	case p.Burst < MinBurst:
+		return ErrBurstValidation
+	case p.MaxWait < MinMaxWait:
+		return ErrMaxWaitValidation
+	case p.Command == nil && p.CommandContext == nil && p.Policy == nil:
+		return ErrCommandRequired
+	default:
+		return nil
+	}
+}
+
+// ServiceID returns the service id registered to the policy binded to this metric.
+func (m Metric) ServiceID() string {
+	return m.ID
+}
+
+// PolicyDuration returns the policy execution duration.
+// In short, finished at less (-) started at.
+func (m Metric) PolicyDuration() time.Duration {
+	return m.FinishedAt.Sub(m.StartedAt)
+}
+
+// Success returns whether the policy execution succeeded or not.
+// In short, status is zero and error is nil.
+func (m Metric) Success() bool {
+	return (m.Status == 0) && (m.Error == nil)
+}
+
+// MetricError returns the error that a command supplier or a wrapped policy raised.
+func (m Metric) MetricError() error {
+	return m.Error
+}
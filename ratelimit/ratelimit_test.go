@@ -0,0 +1,301 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/ratelimit"
+	"github.com/aureliano/resiliencia/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockPolicy struct{ mock.Mock }
+
+func (p *mockPolicy) Run(_ core.Metric) error {
+	args := p.Called()
+	return args.Error(0)
+}
+
+func (p *mockPolicy) WithCommand(_ core.Command) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+func (p *mockPolicy) WithPolicy(_ core.PolicySupplier) core.PolicySupplier {
+	args := p.Called()
+	return args.Get(0).(core.PolicySupplier)
+}
+
+func TestPolicyImplementsPolicySupplier(t *testing.T) {
+	p := ratelimit.New("remote-service")
+	i := reflect.TypeOf((*core.PolicySupplier)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(p).Implements(i))
+}
+
+func TestMetricImplementsMetricRecorder(t *testing.T) {
+	m := ratelimit.Metric{}
+	i := reflect.TypeOf((*core.MetricRecorder)(nil)).Elem()
+
+	assert.True(t, reflect.TypeOf(m).Implements(i))
+}
+
+func TestNew(t *testing.T) {
+	p := ratelimit.New("remote-service")
+
+	assert.Equal(t, "remote-service", p.ServiceID)
+	assert.Equal(t, ratelimit.TokenBucketAlgorithm, p.Algorithm)
+	assert.Equal(t, ratelimit.MinLimit, p.Limit)
+	assert.Equal(t, time.Second, p.Period)
+	assert.Equal(t, 0, p.Burst)
+	assert.Equal(t, ratelimit.FailFastMode, p.Mode)
+}
+
+func TestRunValidation(t *testing.T) {
+	cases := []struct {
+		name   string
+		change func(p *ratelimit.Policy)
+		err    error
+	}{
+		{"algorithm", func(p *ratelimit.Policy) { p.Algorithm = 9 }, ratelimit.ErrAlgorithmValidation},
+		{"limit", func(p *ratelimit.Policy) { p.Limit = 0 }, ratelimit.ErrLimitValidation},
+		{"period", func(p *ratelimit.Policy) { p.Period = 0 }, ratelimit.ErrPeriodValidation},
+		{"burst", func(p *ratelimit.Policy) { p.Burst = -1 }, ratelimit.ErrBurstValidation},
+		{"max wait", func(p *ratelimit.Policy) { p.MaxWait = -1 }, ratelimit.ErrMaxWaitValidation},
+		{"command", func(p *ratelimit.Policy) { p.Command = nil }, ratelimit.ErrCommandRequired},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			p := ratelimit.New("remote-service")
+			p.Command = func() error { return nil }
+			c.change(&p)
+
+			assert.ErrorIs(t, p.Run(core.NewMetric()), c.err)
+		})
+	}
+}
+
+func TestRateLimitedError(t *testing.T) {
+	var err error = &ratelimit.RateLimitedError{Wait: time.Second}
+
+	assert.ErrorIs(t, err, ratelimit.ErrRateLimited)
+	assert.Equal(t, "rate limited (next permit in 1s)", err.Error())
+
+	var suggester retry.DelaySuggester
+	assert.ErrorAs(t, err, &suggester)
+	assert.Equal(t, time.Second, suggester.RetryAfter())
+}
+
+func TestRunCommand(t *testing.T) {
+	before, after := false, false
+	p := ratelimit.New("ratelimit-service-1")
+	ratelimit.Reset("ratelimit-service-1")
+	p.BeforeRateLimit = func(p ratelimit.Policy) { before = true }
+	p.AfterRateLimit = func(p ratelimit.Policy, err error) { after = true }
+	p.Command = func() error { return nil }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(ratelimit.Metric{}).String()].(ratelimit.Metric)
+
+	assert.Nil(t, err)
+	assert.True(t, before)
+	assert.True(t, after)
+	assert.True(t, m.Success())
+	assert.Equal(t, "ratelimit-service-1", m.ServiceID())
+	assert.False(t, m.Limited)
+	assert.Zero(t, m.Wait)
+}
+
+func TestRunCommandError(t *testing.T) {
+	errTest := errors.New("err test")
+	p := ratelimit.New("ratelimit-service-2")
+	ratelimit.Reset("ratelimit-service-2")
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(ratelimit.Metric{}).String()].(ratelimit.Metric)
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "ratelimit", perr.Policy)
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, errTest, m.MetricError())
+	assert.False(t, m.Limited)
+}
+
+func TestRunRateLimited(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	var limited *ratelimit.RateLimitedError
+	p := ratelimit.New("ratelimit-service-3")
+	ratelimit.Reset("ratelimit-service-3")
+	p.Clock = clock
+	p.OnRateLimited = func(p ratelimit.Policy, err *ratelimit.RateLimitedError) { limited = err }
+	p.Command = func() error { return nil }
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	clock.Advance(time.Millisecond * 400)
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(ratelimit.Metric{}).String()].(ratelimit.Metric)
+
+	assert.ErrorIs(t, err, ratelimit.ErrRateLimited)
+	assert.Equal(t, time.Millisecond*600, limited.Wait)
+	assert.True(t, m.Limited)
+	assert.Equal(t, time.Millisecond*600, m.RetryAfter)
+	assert.Equal(t, 1, m.Status)
+}
+
+func TestRunBlock(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	p := ratelimit.New("ratelimit-service-4")
+	ratelimit.Reset("ratelimit-service-4")
+	p.Mode = ratelimit.BlockMode
+	p.Clock = clock
+	p.Command = func() error { return nil }
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}()
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(ratelimit.Metric{}).String()].(ratelimit.Metric)
+
+	assert.Nil(t, err)
+	assert.False(t, m.Limited)
+	assert.Equal(t, time.Second, m.Wait)
+}
+
+func TestRunBlockMaxWait(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	p := ratelimit.New("ratelimit-service-5")
+	ratelimit.Reset("ratelimit-service-5")
+	p.Mode = ratelimit.BlockMode
+	p.MaxWait = time.Millisecond * 500
+	p.Clock = clock
+	p.Command = func() error { return nil }
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	var limited *ratelimit.RateLimitedError
+	assert.ErrorAs(t, p.Run(core.NewMetric()), &limited)
+	assert.Equal(t, time.Second, limited.Wait)
+	assert.Equal(t, 0, clock.Timers())
+}
+
+func TestRunBlockContextCanceled(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	p := ratelimit.New("ratelimit-service-6")
+	ratelimit.Reset("ratelimit-service-6")
+	p.Mode = ratelimit.BlockMode
+	p.Clock = clock
+	p.Command = func() error { return nil }
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		clock.BlockUntil(1)
+		cancel()
+	}()
+
+	err := p.RunContext(ctx, core.NewMetric())
+	assert.ErrorIs(t, err, context.Canceled)
+
+	// The permit of the canceled call was given back.
+	p.Mode = ratelimit.FailFastMode
+	clock.Advance(time.Second)
+	assert.Nil(t, p.Run(core.NewMetric()))
+}
+
+func TestRunPolicy(t *testing.T) {
+	errTest := errors.New("err test")
+	policy := new(mockPolicy)
+	policy.On("Run").Return(errTest)
+
+	p := ratelimit.New("ratelimit-service-7")
+	ratelimit.Reset("ratelimit-service-7")
+	p.Policy = policy
+
+	assert.ErrorIs(t, p.Run(core.NewMetric()), errTest)
+}
+
+func TestRunRetryAfterRateLimited(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	rl := ratelimit.New("ratelimit-service-8")
+	ratelimit.Reset("ratelimit-service-8")
+	rl.Clock = clock
+
+	rt := retry.New("ratelimit-service-8")
+	rt.Tries = 2
+	rt.Errors = []error{ratelimit.ErrRateLimited}
+	rt.Clock = clock
+	rt.Policy = rl.WithCommand(func() error { return nil })
+
+	assert.Nil(t, rt.Run(core.NewMetric()))
+
+	// The retry waits as long as the rate limit suggests.
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}()
+
+	metric := core.NewMetric()
+	err := rt.Run(metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 2, m.Tries)
+	assert.Equal(t, time.Second, m.Executions[0].RetryAfter)
+}
+
+func TestRunSwallowErrors(t *testing.T) {
+	errTest := errors.New("err test")
+	p := ratelimit.New("ratelimit-service-9")
+	ratelimit.Reset("ratelimit-service-9")
+	p.SwallowErrors = true
+	p.Command = func() error { return errTest }
+
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	err := p.Run(core.NewMetric())
+	assert.IsType(t, &ratelimit.RateLimitedError{}, err)
+}
+
+func TestWithCommand(t *testing.T) {
+	p := ratelimit.New("remote-service")
+	c := func() error { return nil }
+	s := p.WithCommand(c)
+	p, _ = s.(ratelimit.Policy)
+
+	assert.NotNil(t, p.Command)
+}
+
+func TestWithCommandContext(t *testing.T) {
+	p := ratelimit.New("remote-service")
+	c := func(ctx context.Context) error { return nil }
+	s := p.WithCommandContext(c)
+	p, _ = s.(ratelimit.Policy)
+
+	assert.NotNil(t, p.CommandContext)
+}
+
+func TestWithPolicy(t *testing.T) {
+	p := ratelimit.New("remote-service")
+	s := p.WithPolicy(ratelimit.New("any"))
+	p, _ = s.(ratelimit.Policy)
+
+	assert.NotNil(t, p.Policy)
+}
@@ -0,0 +1,67 @@
+package ratelimit
+
+import "sync"
+
+// Registry keeps the rate limits of services. Policies sharing a registry and a ServiceID share a rate
+// limit, while separate registries are isolated from each other. Policies without a Registry use the
+// default one.
+type Registry struct {
+	mu       sync.Mutex
+	services map[string]*service
+}
+
+var defaultRegistry = NewRegistry()
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{services: make(map[string]*service)}
+}
+
+// DefaultRegistry returns the registry used by policies without a Registry.
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Reset drops the rate limit state of the service in the default registry (see Registry.Reset).
+func Reset(serviceID string) {
+	defaultRegistry.Reset(serviceID)
+}
+
+// Reset drops the rate limit state of the service, so that its next call starts afresh, with the
+// settings of the policy making it.
+func (r *Registry) Reset(serviceID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.services, serviceID)
+}
+
+// get returns the limiter of the service, creating it from the settings of the policy if there is none.
+//
+// Possible error(s): ErrSettingsConflict.
+func (r *Registry) get(p Policy) (*service, error) {
+	s := settingsOf(p)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	svc := r.services[p.ServiceID]
+	if svc == nil {
+		svc = &service{settings: s, limiter: newLimiter(s)}
+		r.services[p.ServiceID] = svc
+	}
+
+	if svc.settings != s {
+		return nil, ErrSettingsConflict
+	}
+
+	return svc, nil
+}
+
+func registryOf(p Policy) *Registry {
+	if p.Registry == nil {
+		return defaultRegistry
+	}
+
+	return p.Registry
+}
@@ -0,0 +1,45 @@
+package ratelimit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultRegistry(t *testing.T) {
+	assert.NotNil(t, ratelimit.DefaultRegistry())
+	assert.Same(t, ratelimit.DefaultRegistry(), ratelimit.DefaultRegistry())
+}
+
+func TestRegistryIsolation(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	first, second := ratelimit.NewRegistry(), ratelimit.NewRegistry()
+	p := ratelimit.New("service")
+	p.Clock = clock
+
+	p.Registry = first
+	assert.Zero(t, call(t, p))
+	assert.Equal(t, time.Second, call(t, p))
+
+	// The rate limit of the second registry is untouched, and so are its settings.
+	p.Registry = second
+	p.Limit = 2
+	assert.Zero(t, call(t, p))
+	assert.Zero(t, call(t, p))
+	assert.Equal(t, time.Millisecond*500, call(t, p))
+}
+
+func TestRegistrySettingsConflictMetric(t *testing.T) {
+	p := ratelimit.New("service")
+	p.Registry = ratelimit.NewRegistry()
+	p.Command = func() error { return nil }
+	assert.Nil(t, p.Run(core.NewMetric()))
+
+	p.Algorithm = ratelimit.SlidingWindowLogAlgorithm
+	metric := core.NewMetric()
+	assert.ErrorIs(t, p.Run(metric), ratelimit.ErrSettingsConflict)
+	assert.Empty(t, metric)
+}
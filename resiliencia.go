@@ -27,6 +27,13 @@ func Decorate(command core.Command) Decorator {
 	return Decoration{Supplier: command}
 }
 
+// DecorateContext creates a policies decorator given a context-aware command supplier.
+//
+// Returns a decoration with command, which may run bound to a context.
+func DecorateContext(command core.CommandContext) ContextDecoration {
+	return ContextDecoration{SupplierContext: command}
+}
+
 // Chain creates a policies chain of responsibility.
 //
 // Returns a policies chain.
@@ -34,6 +41,13 @@ func Chain(policies ...core.PolicySupplier) Chainer {
 	return ChainOfResponsibility{Policies: removeNull(policies)}
 }
 
+// ChainContext creates a policies chain of responsibility which may run bound to a context.
+//
+// Returns a policies chain.
+func ChainContext(policies ...core.PolicySupplier) ContextChainer {
+	return ChainOfResponsibility{Policies: removeNull(policies)}
+}
+
 func removeNull(array []core.PolicySupplier) []core.PolicySupplier {
 	newArray := make([]core.PolicySupplier, 0, len(array))
 
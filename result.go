@@ -0,0 +1,58 @@
+package resiliencia
+
+import (
+	"context"
+	"errors"
+
+	"github.com/aureliano/resiliencia/core"
+)
+
+// Result produced by the chain can't be converted to the expected type.
+var ErrResultTypeMismatch = errors.New("result type mismatch")
+
+// CommandT is a command supplier which returns a result besides the error.
+type CommandT[T any] func(ctx context.Context) (T, error)
+
+// ExecuteT starts a chain of responsibility with a result-returning command supplier.
+// The result travels through every policy of the chain, so that a fallback policy may
+// supply a substitute value (see fallback.Policy.FallBackResult).
+//
+// Returns the result, chained metrics and the error raised by the chain. The result is
+// the zero value of T whenever an error is returned, or when the chain succeeded without
+// storing one (e.g. a fallback handled the error without supplying a substitute).
+//
+// Possible error(s): ErrResultTypeMismatch and any error returned by the chain.
+func ExecuteT[T any](ctx context.Context, chain Chainer, command CommandT[T]) (T, core.Metric, error) {
+	var zero T
+
+	var supplier core.CommandContext
+	if command != nil {
+		supplier = func(ctx context.Context) error {
+			value, err := command(ctx)
+			if err != nil {
+				return err
+			}
+			core.SetResult(ctx, value)
+
+			return nil
+		}
+	}
+
+	rctx, result := core.NewResultContext(ctx)
+	metric, err := executeChain(rctx, chain, supplier)
+	if err != nil {
+		return zero, metric, err
+	}
+
+	value, _ := result.Get()
+	if value == nil {
+		return zero, metric, nil
+	}
+
+	typed, ok := value.(T)
+	if !ok {
+		return zero, metric, ErrResultTypeMismatch
+	}
+
+	return typed, metric, nil
+}
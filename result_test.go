@@ -0,0 +1,200 @@
+package resiliencia_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia"
+	"github.com/aureliano/resiliencia/circuitbreaker"
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/fallback"
+	"github.com/aureliano/resiliencia/retry"
+	"github.com/aureliano/resiliencia/timeout"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecuteTSupplierRequired(t *testing.T) {
+	_, _, err := resiliencia.ExecuteT[string](context.Background(), resiliencia.Chain(retry.New("id")), nil)
+	assert.ErrorIs(t, err, resiliencia.ErrSupplierRequired)
+}
+
+func TestExecuteT(t *testing.T) {
+	id := "service-id"
+	errTest := errors.New("err test")
+	calls := 0
+
+	rtp := retry.New(id)
+	rtp.Tries = 3
+	rtp.Errors = []error{errTest}
+	tmp := timeout.New(id)
+	tmp.Timeout = time.Second * 5
+	c := resiliencia.Chain(circuitbreaker.New(id+"-result"), rtp, tmp)
+
+	value, metric, err := resiliencia.ExecuteT(context.Background(), c, func(ctx context.Context) (string, error) {
+		calls++
+		if calls < 3 {
+			return "", errTest
+		}
+
+		return "resiliencia", nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "resiliencia", value)
+	assert.True(t, metric.Success())
+	assert.Equal(t, 3, calls)
+}
+
+type plainChainer struct {
+	chain resiliencia.Chainer
+}
+
+func (c plainChainer) Execute(command core.Command) (core.Metric, error) {
+	return c.chain.Execute(command)
+}
+
+func TestExecuteTPlainChainer(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	c := plainChainer{chain: resiliencia.Chain(retry.New("service-id"))}
+
+	value, metric, err := resiliencia.ExecuteT(ctx, c, func(ctx context.Context) (any, error) {
+		return ctx.Value(key{}), nil
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "value", value)
+	assert.True(t, metric.Success())
+}
+
+func TestExecuteTError(t *testing.T) {
+	id := "service-id"
+	errTest := errors.New("err test")
+
+	rtp := retry.New(id)
+	rtp.Tries = 2
+	rtp.Errors = []error{errTest}
+
+	value, metric, err := resiliencia.ExecuteT(context.Background(), resiliencia.Chain(rtp),
+		func(ctx context.Context) (int, error) {
+			return 10, errTest
+		})
+
+	assert.ErrorIs(t, err, retry.ErrMaxTriesExceeded)
+	assert.Equal(t, 0, value)
+	assert.False(t, metric.Success())
+}
+
+func TestExecuteTTimedOutResultIsDiscarded(t *testing.T) {
+	id := "service-id"
+	tmp := timeout.New(id)
+	tmp.Timeout = time.Millisecond * 10
+	late := make(chan struct{})
+
+	value, _, err := resiliencia.ExecuteT(context.Background(), resiliencia.Chain(tmp),
+		func(ctx context.Context) (string, error) {
+			defer close(late)
+			time.Sleep(time.Millisecond * 30)
+			return "late", nil
+		})
+	<-late
+
+	assert.ErrorIs(t, err, timeout.ErrExecutionTimedOut)
+	assert.Equal(t, "", value)
+}
+
+func TestExecuteTFallbackResult(t *testing.T) {
+	id := "service-id"
+	errTest := errors.New("err test")
+
+	rtp := retry.New(id)
+	rtp.Tries = 2
+	rtp.Errors = []error{errTest}
+	fbp := fallback.New(id)
+	fbp.Errors = []error{retry.ErrMaxTriesExceeded}
+	fbp.FallBackResult = func(err error) (any, error) { return "cached", nil }
+
+	value, metric, err := resiliencia.ExecuteT(context.Background(), resiliencia.Chain(fbp, rtp),
+		func(ctx context.Context) (string, error) {
+			return "", errTest
+		})
+
+	assert.Nil(t, err)
+	assert.Equal(t, "cached", value)
+	assert.NotNil(t, metric)
+}
+
+func TestExecuteTRejectedResultIsDiscarded(t *testing.T) {
+	id := "service-id"
+	handled := false
+
+	rtp := retry.New(id)
+	rtp.Tries = 2
+	rtp.ShouldRetryResult = retry.OnResult(func(try int, result string) bool { return result == "pending" })
+	fbp := fallback.New(id)
+	fbp.Errors = []error{retry.ErrMaxTriesExceeded}
+	fbp.FallBackHandler = func(err error) { handled = true }
+
+	value, _, err := resiliencia.ExecuteT(context.Background(), resiliencia.Chain(fbp, rtp),
+		func(ctx context.Context) (string, error) {
+			return "pending", nil
+		})
+
+	assert.True(t, handled)
+	assert.Nil(t, err)
+	assert.Equal(t, "", value)
+}
+
+func TestExecuteTResultTypeMismatch(t *testing.T) {
+	id := "service-id"
+	errTest := errors.New("err test")
+
+	fbp := fallback.New(id)
+	fbp.Errors = []error{errTest}
+	fbp.FallBackResult = func(err error) (any, error) { return 10, nil }
+
+	value, _, err := resiliencia.ExecuteT(context.Background(), resiliencia.Chain(fbp),
+		func(ctx context.Context) (string, error) {
+			return "", errTest
+		})
+
+	assert.ErrorIs(t, err, resiliencia.ErrResultTypeMismatch)
+	assert.Equal(t, "", value)
+}
+
+func TestExecuteTNilFallbackResult(t *testing.T) {
+	id := "service-id"
+	errTest := errors.New("err test")
+
+	fbp := fallback.New(id)
+	fbp.Errors = []error{errTest}
+	fbp.FallBackResult = func(err error) (any, error) { return nil, nil }
+
+	value, _, err := resiliencia.ExecuteT(context.Background(), resiliencia.Chain(fbp),
+		func(ctx context.Context) (*string, error) {
+			return nil, errTest
+		})
+
+	assert.Nil(t, err)
+	assert.Nil(t, value)
+}
+
+func TestExecuteTFallbackResultError(t *testing.T) {
+	id := "service-id"
+	errTest := errors.New("err test")
+	errDomain := errors.New("domain error")
+
+	fbp := fallback.New(id)
+	fbp.Errors = []error{errTest}
+	fbp.FallBackResult = func(err error) (any, error) { return nil, errDomain }
+
+	value, _, err := resiliencia.ExecuteT(context.Background(), resiliencia.Chain(fbp),
+		func(ctx context.Context) (string, error) {
+			return "", errTest
+		})
+
+	assert.ErrorIs(t, err, errDomain)
+	assert.Equal(t, "", value)
+}
@@ -0,0 +1,75 @@
+package retry
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DelaySuggester is the interface that errors implement to suggest how long to wait before the next try
+// (e.g. HTTP Retry-After header, gRPC retry pushback or a rate limiter reservation).
+type DelaySuggester interface {
+	// RetryAfter returns the suggested delay.
+	RetryAfter() time.Duration
+}
+
+type retryAfterError struct {
+	err   error
+	delay time.Duration
+}
+
+// WithDelay wraps an error with a suggested delay before the next try.
+//
+// Returns an error which implements DelaySuggester and unwraps to err.
+func WithDelay(err error, delay time.Duration) error {
+	return retryAfterError{err: err, delay: delay}
+}
+
+func (e retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e retryAfterError) Unwrap() error {
+	return e.err
+}
+
+func (e retryAfterError) RetryAfter() time.Duration {
+	return e.delay
+}
+
+// ParseRetryAfter parses the value of an HTTP Retry-After header, which is either a number
+// of seconds or an HTTP date.
+//
+// Returns the delay and whether the value could be parsed.
+func ParseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	date, err := http.ParseTime(value)
+	if err != nil {
+		return 0, false
+	}
+
+	if delay := date.Sub(now); delay > 0 {
+		return delay, true
+	}
+
+	return 0, true
+}
+
+func suggestedDelay(err error) (time.Duration, bool) {
+	var suggester DelaySuggester
+	if !errors.As(err, &suggester) {
+		return 0, false
+	}
+
+	return suggester.RetryAfter(), true
+}
@@ -0,0 +1,98 @@
+package retry_test
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithDelay(t *testing.T) {
+	errTest := errors.New("too many requests")
+	err := retry.WithDelay(errTest, time.Second)
+
+	var suggester retry.DelaySuggester
+	assert.ErrorIs(t, err, errTest)
+	assert.Equal(t, "too many requests", err.Error())
+	assert.True(t, errors.As(fmt.Errorf("call: %w", err), &suggester))
+	assert.Equal(t, time.Second, suggester.RetryAfter())
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2015, time.October, 21, 7, 28, 0, 0, time.UTC)
+
+	tests := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{value: "120", want: time.Minute * 2, ok: true},
+		{value: " 0 ", want: 0, ok: true},
+		{value: "-1"},
+		{value: "Wed, 21 Oct 2015 07:28:30 GMT", want: time.Second * 30, ok: true},
+		{value: "Wed, 21 Oct 2015 07:27:00 GMT", want: 0, ok: true},
+		{value: "soon"},
+		{value: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			d, ok := retry.ParseRetryAfter(tt.value, now)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, d)
+		})
+	}
+}
+
+func TestRunValidatePolicyMaxRetryAfter(t *testing.T) {
+	p := retry.New("postForm")
+	p.MaxRetryAfter = -1
+	p.Command = func() error { return nil }
+
+	err := p.Run(core.NewMetric())
+
+	assert.ErrorIs(t, err, retry.ErrMaxRetryAfterValidation)
+}
+
+func TestRunRetryAfter(t *testing.T) {
+	errTest := errors.New("too many requests")
+	calls := 0
+
+	p := retry.New("postForm")
+	p.Tries = 4
+	p.Delay = time.Hour
+	p.MaxRetryAfter = time.Millisecond * 5
+	p.Errors = []error{errTest}
+	p.Command = func() error {
+		calls++
+		switch calls {
+		case 1:
+			return retry.WithDelay(errTest, time.Millisecond*2)
+		case 2:
+			return fmt.Errorf("call: %w", retry.WithDelay(errTest, time.Hour))
+		case 3:
+			return retry.WithDelay(errTest, 0)
+		default:
+			return nil
+		}
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+
+	assert.Nil(t, err)
+	assert.Len(t, m.Executions, 4)
+	assert.Equal(t, time.Millisecond*2, m.Executions[0].RetryAfter)
+	assert.Equal(t, time.Millisecond*2, m.Executions[0].Delay)
+	assert.Equal(t, time.Hour, m.Executions[1].RetryAfter)
+	assert.Equal(t, time.Millisecond*5, m.Executions[1].Delay)
+	assert.Equal(t, time.Duration(0), m.Executions[2].RetryAfter)
+	assert.Equal(t, time.Duration(0), m.Executions[2].Delay)
+	assert.Equal(t, time.Duration(0), m.Executions[3].Delay)
+}
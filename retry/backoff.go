@@ -0,0 +1,137 @@
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff is the interface that computes how long a retry policy waits between tries.
+type Backoff interface {
+	// Next returns how long to wait after the given try failed. Try starts from one (1),
+	// previous is the last delay waited (zero before the first wait) and limit is the maximum
+	// delay allowed (zero means no limit).
+	Next(try int, previous, limit time.Duration) time.Duration
+}
+
+// ConstantBackoff waits the same delay between every try.
+type ConstantBackoff struct {
+	// Delay between each execution.
+	Delay time.Duration
+}
+
+// LinearBackoff increases the delay by a fixed increment after every try.
+type LinearBackoff struct {
+	// Delay after the first try.
+	Initial time.Duration
+
+	// Amount added to the delay after each try.
+	Increment time.Duration
+}
+
+// ExponentialBackoff multiplies the delay by a factor after every try.
+type ExponentialBackoff struct {
+	// Delay after the first try.
+	Initial time.Duration
+
+	// Factor applied to the delay after each try. Defaults to DefaultMultiplier if less than or equal to one (1).
+	Multiplier float64
+}
+
+// FullJitterBackoff waits a random delay between zero and the exponential delay.
+type FullJitterBackoff struct {
+	// Delay after the first try (before randomization).
+	Initial time.Duration
+
+	// Factor applied to the delay after each try. Defaults to DefaultMultiplier if less than or equal to one (1).
+	Multiplier float64
+}
+
+// EqualJitterBackoff waits half of the exponential delay plus a random delay up to the other half.
+type EqualJitterBackoff struct {
+	// Delay after the first try (before randomization).
+	Initial time.Duration
+
+	// Factor applied to the delay after each try. Defaults to DefaultMultiplier if less than or equal to one (1).
+	Multiplier float64
+}
+
+// DecorrelatedJitterBackoff waits a random delay between Initial and three times the previous delay.
+type DecorrelatedJitterBackoff struct {
+	// Minimum delay.
+	Initial time.Duration
+}
+
+// Default factor of exponential backoffs.
+const DefaultMultiplier = 2.0
+
+// Next returns the constant delay.
+func (b ConstantBackoff) Next(_ int, _, limit time.Duration) time.Duration {
+	return capped(b.Delay, limit)
+}
+
+// Next returns Initial plus Increment times the number of previous tries.
+func (b LinearBackoff) Next(try int, _, limit time.Duration) time.Duration {
+	return capped(b.Initial+b.Increment*time.Duration(try-1), limit)
+}
+
+// Next returns Initial times Multiplier to the power of the number of previous tries.
+func (b ExponentialBackoff) Next(try int, _, limit time.Duration) time.Duration {
+	return exponential(b.Initial, b.Multiplier, try, limit)
+}
+
+// Next returns a random delay between zero and the exponential delay.
+func (b FullJitterBackoff) Next(try int, _, limit time.Duration) time.Duration {
+	return random(0, exponential(b.Initial, b.Multiplier, try, limit))
+}
+
+// Next returns half of the exponential delay plus a random delay up to the other half.
+func (b EqualJitterBackoff) Next(try int, _, limit time.Duration) time.Duration {
+	half := exponential(b.Initial, b.Multiplier, try, limit) / 2
+	return half + random(0, half)
+}
+
+// Next returns a random delay between Initial and three times the previous delay.
+func (b DecorrelatedJitterBackoff) Next(_ int, previous, limit time.Duration) time.Duration {
+	const factor = 3
+	if previous < b.Initial {
+		previous = b.Initial
+	}
+
+	upper := previous * factor
+	if upper < previous {
+		upper = time.Duration(math.MaxInt64)
+	}
+
+	return capped(random(b.Initial, upper), limit)
+}
+
+func exponential(initial time.Duration, multiplier float64, try int, limit time.Duration) time.Duration {
+	if multiplier <= 1 {
+		multiplier = DefaultMultiplier
+	}
+
+	d := float64(initial) * math.Pow(multiplier, float64(try-1))
+	if d >= math.MaxInt64 {
+		return capped(time.Duration(math.MaxInt64), limit)
+	}
+
+	return capped(time.Duration(d), limit)
+}
+
+func capped(d, limit time.Duration) time.Duration {
+	if limit > 0 && d > limit {
+		return limit
+	}
+
+	return d
+}
+
+func random(lower, upper time.Duration) time.Duration {
+	if upper <= lower {
+		return lower
+	}
+
+	//nolint:gosec // jitter doesn't need a cryptographically secure random number generator.
+	return lower + time.Duration(rand.Int63n(int64(upper-lower)))
+}
@@ -0,0 +1,180 @@
+package retry_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoffImplementations(t *testing.T) {
+	i := reflect.TypeOf((*retry.Backoff)(nil)).Elem()
+
+	for _, b := range []retry.Backoff{
+		retry.ConstantBackoff{}, retry.LinearBackoff{}, retry.ExponentialBackoff{},
+		retry.FullJitterBackoff{}, retry.EqualJitterBackoff{}, retry.DecorrelatedJitterBackoff{},
+	} {
+		assert.True(t, reflect.TypeOf(b).Implements(i))
+	}
+}
+
+func TestDeterministicBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		backoff retry.Backoff
+		limit   time.Duration
+		want    []time.Duration
+	}{
+		{
+			name:    "constant",
+			backoff: retry.ConstantBackoff{Delay: time.Millisecond * 10},
+			want:    []time.Duration{time.Millisecond * 10, time.Millisecond * 10, time.Millisecond * 10},
+		},
+		{
+			name:    "constant capped",
+			backoff: retry.ConstantBackoff{Delay: time.Millisecond * 10},
+			limit:   time.Millisecond * 5,
+			want:    []time.Duration{time.Millisecond * 5, time.Millisecond * 5},
+		},
+		{
+			name:    "linear",
+			backoff: retry.LinearBackoff{Initial: time.Millisecond * 10, Increment: time.Millisecond * 5},
+			want:    []time.Duration{time.Millisecond * 10, time.Millisecond * 15, time.Millisecond * 20},
+		},
+		{
+			name:    "linear capped",
+			backoff: retry.LinearBackoff{Initial: time.Millisecond * 10, Increment: time.Millisecond * 5},
+			limit:   time.Millisecond * 12,
+			want:    []time.Duration{time.Millisecond * 10, time.Millisecond * 12, time.Millisecond * 12},
+		},
+		{
+			name:    "exponential default multiplier",
+			backoff: retry.ExponentialBackoff{Initial: time.Millisecond * 10},
+			want:    []time.Duration{time.Millisecond * 10, time.Millisecond * 20, time.Millisecond * 40},
+		},
+		{
+			name:    "exponential",
+			backoff: retry.ExponentialBackoff{Initial: time.Millisecond * 10, Multiplier: 3},
+			want:    []time.Duration{time.Millisecond * 10, time.Millisecond * 30, time.Millisecond * 90},
+		},
+		{
+			name:    "exponential capped",
+			backoff: retry.ExponentialBackoff{Initial: time.Millisecond * 10},
+			limit:   time.Millisecond * 25,
+			want:    []time.Duration{time.Millisecond * 10, time.Millisecond * 20, time.Millisecond * 25},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			previous := time.Duration(0)
+			for i, want := range tt.want {
+				previous = tt.backoff.Next(i+1, previous, tt.limit)
+				assert.Equal(t, want, previous)
+			}
+		})
+	}
+}
+
+func TestExponentialBackoffOverflow(t *testing.T) {
+	b := retry.ExponentialBackoff{Initial: time.Hour}
+
+	assert.Greater(t, b.Next(200, 0, 0), time.Duration(0))
+	assert.Equal(t, time.Minute, b.Next(200, 0, time.Minute))
+}
+
+func TestJitterBackoff(t *testing.T) {
+	const samples = 100
+
+	for i := 0; i < samples; i++ {
+		d := retry.FullJitterBackoff{Initial: time.Millisecond * 10}.Next(3, 0, 0)
+		assert.GreaterOrEqual(t, d, time.Duration(0))
+		assert.LessOrEqual(t, d, time.Millisecond*40)
+
+		d = retry.FullJitterBackoff{Initial: time.Millisecond * 10}.Next(10, 0, time.Millisecond*15)
+		assert.LessOrEqual(t, d, time.Millisecond*15)
+
+		d = retry.EqualJitterBackoff{Initial: time.Millisecond * 10}.Next(3, 0, 0)
+		assert.GreaterOrEqual(t, d, time.Millisecond*20)
+		assert.LessOrEqual(t, d, time.Millisecond*40)
+
+		d = retry.EqualJitterBackoff{Initial: time.Millisecond * 10}.Next(10, 0, time.Millisecond*16)
+		assert.GreaterOrEqual(t, d, time.Millisecond*8)
+		assert.LessOrEqual(t, d, time.Millisecond*16)
+
+		d = retry.DecorrelatedJitterBackoff{Initial: time.Millisecond * 10}.Next(1, 0, 0)
+		assert.GreaterOrEqual(t, d, time.Millisecond*10)
+		assert.LessOrEqual(t, d, time.Millisecond*30)
+
+		d = retry.DecorrelatedJitterBackoff{Initial: time.Millisecond * 10}.Next(5, time.Millisecond*100, 0)
+		assert.GreaterOrEqual(t, d, time.Millisecond*10)
+		assert.LessOrEqual(t, d, time.Millisecond*300)
+
+		d = retry.DecorrelatedJitterBackoff{Initial: time.Millisecond * 10}.Next(5, time.Millisecond*100, time.Millisecond*20)
+		assert.LessOrEqual(t, d, time.Millisecond*20)
+	}
+}
+
+func TestRunValidatePolicyMaxDelay(t *testing.T) {
+	p := retry.New("postForm")
+	p.MaxDelay = -1
+	p.Command = func() error { return nil }
+
+	err := p.Run(core.NewMetric())
+
+	assert.ErrorIs(t, err, retry.ErrMaxDelayValidation)
+}
+
+func TestRunBackoffDelays(t *testing.T) {
+	errTest := errors.New("any")
+
+	p := retry.New("postForm")
+	p.Tries = 4
+	p.Errors = []error{errTest}
+	p.Backoff = retry.ExponentialBackoff{Initial: time.Millisecond}
+	p.MaxDelay = time.Millisecond * 3
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+
+	assert.ErrorIs(t, err, retry.ErrMaxTriesExceeded)
+	assert.Len(t, m.Executions, 4)
+	assert.Equal(t, time.Millisecond, m.Executions[0].Delay)
+	assert.Equal(t, time.Millisecond*2, m.Executions[1].Delay)
+	assert.Equal(t, time.Millisecond*3, m.Executions[2].Delay)
+	assert.Equal(t, time.Duration(0), m.Executions[3].Delay)
+	assert.GreaterOrEqual(t, m.FinishedAt.Sub(m.StartedAt), time.Millisecond*6)
+}
+
+func TestRunConstantDelay(t *testing.T) {
+	errTest := errors.New("any")
+	calls := 0
+
+	p := retry.New("postForm")
+	p.Tries = 3
+	p.Delay = time.Millisecond * 2
+	p.Errors = []error{errTest}
+	p.Command = func() error {
+		calls++
+		if calls == 2 {
+			return nil
+		}
+
+		return errTest
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+
+	assert.Nil(t, err)
+	assert.Len(t, m.Executions, 2)
+	assert.Equal(t, time.Millisecond*2, m.Executions[0].Delay)
+	assert.Equal(t, time.Duration(0), m.Executions[1].Delay)
+}
@@ -0,0 +1,142 @@
+package retry
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+)
+
+// Budget limits the retries of every service to a ratio of its requests over a sliding window, so that
+// retries can't multiply the load on a failing service during an outage. Services are identified by
+// ServiceID and a single budget may be shared by any number of policies.
+type Budget struct {
+	// Clock used to tell time (real time if not set). It must be set before the budget is used.
+	Clock core.Clock
+
+	ratio      float64
+	window     time.Duration
+	minRetries int
+
+	mu       sync.Mutex
+	services map[string]*budgetWindow
+}
+
+type budgetBucket struct {
+	start    time.Time
+	requests int
+	retries  int
+}
+
+type budgetWindow struct {
+	buckets [budgetBuckets]budgetBucket
+}
+
+const (
+	// Window used by a budget when none is provided.
+	DefaultBudgetWindow = time.Second * 10
+
+	budgetBuckets = 10
+)
+
+// NewBudget creates a retry budget. Within any window, a service may retry at most minRetries
+// plus ratio times its requests (e.g. 0.2 allows retries to add 20% of load).
+//
+// Returns the budget.
+func NewBudget(ratio float64, window time.Duration, minRetries int) *Budget {
+	if ratio < 0 {
+		ratio = 0
+	}
+	if window <= 0 {
+		window = DefaultBudgetWindow
+	}
+	if minRetries < 0 {
+		minRetries = 0
+	}
+
+	return &Budget{
+		ratio:      ratio,
+		window:     window,
+		minRetries: minRetries,
+		services:   make(map[string]*budgetWindow),
+	}
+}
+
+// Request records a request (first try) to the service.
+func (b *Budget) Request(serviceID string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.bucket(serviceID, b.now()).requests++
+}
+
+// Withdraw records a retry to the service if the budget allows it.
+//
+// Returns whether the retry is allowed.
+func (b *Budget) Withdraw(serviceID string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	requests, retries := b.totals(serviceID, now)
+	if float64(retries+1) > float64(b.minRetries)+b.ratio*float64(requests) {
+		return false
+	}
+	b.bucket(serviceID, now).retries++
+
+	return true
+}
+
+// Stats queries for the requests and retries of the service within the current window.
+//
+// Returns the number of requests and retries.
+func (b *Budget) Stats(serviceID string) (int, int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.totals(serviceID, b.now())
+}
+
+func (b *Budget) now() time.Time {
+	return core.ClockOrDefault(b.Clock).Now()
+}
+
+func (b *Budget) bucket(serviceID string, now time.Time) *budgetBucket {
+	w := b.services[serviceID]
+	if w == nil {
+		w = new(budgetWindow)
+		b.services[serviceID] = w
+	}
+
+	width := int64(b.window / budgetBuckets)
+	if width == 0 {
+		width = 1
+	}
+	slot := now.UnixNano() / width
+	start := time.Unix(0, slot*width)
+
+	bucket := &w.buckets[slot%budgetBuckets]
+	if !bucket.start.Equal(start) {
+		*bucket = budgetBucket{start: start}
+	}
+
+	return bucket
+}
+
+func (b *Budget) totals(serviceID string, now time.Time) (int, int) {
+	requests, retries := 0, 0
+
+	w := b.services[serviceID]
+	if w == nil {
+		return requests, retries
+	}
+
+	for _, bucket := range w.buckets {
+		if !bucket.start.IsZero() && now.Sub(bucket.start) < b.window {
+			requests += bucket.requests
+			retries += bucket.retries
+		}
+	}
+
+	return requests, retries
+}
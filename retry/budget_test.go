@@ -0,0 +1,137 @@
+package retry_test
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aureliano/resiliencia/core"
+	"github.com/aureliano/resiliencia/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudgetWithdraw(t *testing.T) {
+	b := retry.NewBudget(0.2, time.Minute, 0)
+
+	assert.False(t, b.Withdraw("service-a"))
+
+	for i := 0; i < 10; i++ {
+		b.Request("service-a")
+	}
+
+	assert.True(t, b.Withdraw("service-a"))
+	assert.True(t, b.Withdraw("service-a"))
+	assert.False(t, b.Withdraw("service-a"))
+
+	requests, retries := b.Stats("service-a")
+	assert.Equal(t, 10, requests)
+	assert.Equal(t, 2, retries)
+
+	requests, retries = b.Stats("service-b")
+	assert.Equal(t, 0, requests)
+	assert.Equal(t, 0, retries)
+}
+
+func TestBudgetMinRetries(t *testing.T) {
+	b := retry.NewBudget(0, time.Minute, 2)
+
+	assert.True(t, b.Withdraw("service-a"))
+	assert.True(t, b.Withdraw("service-a"))
+	assert.False(t, b.Withdraw("service-a"))
+}
+
+func TestBudgetWindowSlides(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	b := retry.NewBudget(0, time.Millisecond*50, 1)
+	b.Clock = clock
+
+	assert.True(t, b.Withdraw("service-a"))
+	assert.False(t, b.Withdraw("service-a"))
+
+	clock.Advance(time.Millisecond * 60)
+
+	assert.True(t, b.Withdraw("service-a"))
+}
+
+func TestNewBudgetDefaults(t *testing.T) {
+	b := retry.NewBudget(-1, 0, -1)
+
+	b.Request("service-a")
+	assert.False(t, b.Withdraw("service-a"))
+}
+
+func TestBudgetConcurrent(t *testing.T) {
+	const total = 100
+	b := retry.NewBudget(0.5, time.Minute, 0)
+	allowed := 0
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for i := 0; i < total; i++ {
+		b.Request("service-a")
+	}
+
+	wg.Add(total)
+	for i := 0; i < total; i++ {
+		go func() {
+			defer wg.Done()
+			if b.Withdraw("service-a") {
+				mu.Lock()
+				allowed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, total/2, allowed)
+}
+
+func TestRunRetryBudgetExhausted(t *testing.T) {
+	errTest := errors.New("any")
+	calls := 0
+
+	p := retry.New("postForm")
+	p.Tries = 5
+	p.Errors = []error{errTest}
+	p.Budget = retry.NewBudget(0, time.Minute, 2)
+	p.Command = func() error {
+		calls++
+		return errTest
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+
+	assert.ErrorIs(t, err, retry.ErrRetryBudgetExhausted)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 3, m.Tries)
+	assert.True(t, m.BudgetExhausted)
+	assert.ErrorIs(t, m.MetricError(), retry.ErrRetryBudgetExhausted)
+	assert.Equal(t, time.Duration(0), m.Executions[2].Delay)
+
+	calls = 0
+	metric = core.NewMetric()
+	err = p.Run(metric)
+
+	assert.ErrorIs(t, err, retry.ErrRetryBudgetExhausted)
+	assert.Equal(t, 1, calls)
+}
+
+func TestRunRetryBudgetNotConsumedOnSuccess(t *testing.T) {
+	p := retry.New("postForm")
+	p.Tries = 5
+	p.Budget = retry.NewBudget(0.1, time.Minute, 0)
+	p.Command = func() error { return nil }
+
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, p.Run(core.NewMetric()))
+	}
+
+	requests, retries := p.Budget.Stats("postForm")
+	assert.Equal(t, 10, requests)
+	assert.Equal(t, 0, retries)
+}
@@ -69,6 +69,105 @@ There are two ways to run a command under a policy. Using a Command supplier
 	// Prints Retry metric.
 	fmt.Println(rtMetric)
 
+Each try runs the wrapped policy with metrics of its own, so a policy never mistakes the metric of a former
+try for its own. Only the metrics of the last try are kept.
+
+# Predicates
+
+Instead of enumerating expected errors, ShouldRetry decides whether a failed execution should be tried again.
+Likewise, ShouldRetryResult decides whether a result should be tried again (see resiliencia.ExecuteT).
+
+	p := retry.New("service-id")
+	p.Tries = 3
+	p.ShouldRetry = func(try int, err error) bool {
+		var netErr net.Error
+		return errors.As(err, &netErr) && netErr.Timeout()
+	}
+	p.ShouldRetryResult = retry.OnResult(func(try int, res *http.Response) bool {
+		return res.StatusCode == http.StatusServiceUnavailable
+	})
+
+# Backoff
+
+By default, the policy waits the constant Delay between tries. A Backoff strategy may be set instead, in
+order to spread tries out in time and avoid thundering herds when a service recovers. MaxDelay caps the
+delay computed by any strategy. The delay waited after each try is kept in the execution metric.
+
+	p := retry.New("service-id")
+	p.Tries = 5
+	p.Backoff = retry.FullJitterBackoff{Initial: time.Millisecond * 100}
+	p.MaxDelay = time.Second * 2
+
+Built-in strategies: ConstantBackoff, LinearBackoff, ExponentialBackoff, FullJitterBackoff,
+EqualJitterBackoff and DecorrelatedJitterBackoff.
+
+# Retry-After
+
+When a service tells how long to wait (HTTP Retry-After header, gRPC retry pushback, a rate limiter
+reservation), the command may return an error which implements DelaySuggester. The suggested delay
+takes precedence over Backoff and is bounded by MaxRetryAfter. Both are kept in the execution metric.
+
+	p := retry.New("service-id")
+	p.MaxRetryAfter = time.Second * 30
+	p.ShouldRetry = func(try int, err error) bool {
+		return errors.Is(err, errTooManyRequests)
+	}
+	p.Command = func() error {
+		res, err := client.Do(req)
+		...
+
+		if res.StatusCode == http.StatusTooManyRequests {
+			delay, _ := retry.ParseRetryAfter(res.Header.Get("Retry-After"), time.Now())
+			return retry.WithDelay(errTooManyRequests, delay)
+		}
+
+		return nil
+	}
+
+# Budget
+
+Each policy run retries independently, so during an outage every caller multiplies the load on the failing
+service. A Budget shared across calls caps retries of a service (by ServiceID) to a ratio of its requests
+within a sliding window. Once exhausted, the policy fails fast with ErrRetryBudgetExhausted and the metric
+flags BudgetExhausted.
+
+	// Retries may add at most 20% of load over 10 seconds (plus 3 retries).
+	budget := retry.NewBudget(0.2, time.Second*10, 3)
+
+	p := retry.New("service-id")
+	p.Tries = 3
+	p.Budget = budget
+
+# Context
+
+Every policy may also run bound to a context.Context. Use CommandContext instead of Command so that the
+command supplier receives the context, and RunContext instead of Run. A wrapped policy receives the same context.
+
+	p := retry.New("service-id")
+	p.CommandContext = func(ctx context.Context) error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	err := p.RunContext(ctx, core.NewMetric())
+
+The delay between tries is interrupted as soon as the context is done. It is measured by the policy Clock
+(see core.Clock), which tests may replace by a core.FakeClock. A Budget has a Clock field as well.
+
+# Errors
+
+Once tries are over, the policy returns a *core.PolicyError wrapping ErrMaxTriesExceeded, ErrUnhandledError,
+ErrRetryBudgetExhausted or the error of the context, along with the error of the last try. SwallowErrors makes
+the policy return the bare error instead (see core.PolicyError).
+
+	if errors.Is(err, retry.ErrMaxTriesExceeded) && errors.Is(err, errServiceUnavailable) {
+		// Every try failed and the last one with errServiceUnavailable.
+		...
+	}
+
 # Listener
 
 In order to keep tracking of what is happening on the execution, you may use listeners to generate some events.
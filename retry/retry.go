@@ -1,6 +1,7 @@
 package retry
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -13,15 +14,27 @@ var (
 	// Policy delay is less than minimum required.
 	ErrDelayValidation = fmt.Errorf("delay must be >= %d", MinDelay)
 
+	// Policy max delay is less than minimum required.
+	ErrMaxDelayValidation = fmt.Errorf("max delay must be >= %d", MinDelay)
+
+	// Policy max retry after is less than minimum required.
+	ErrMaxRetryAfterValidation = fmt.Errorf("max retry after must be >= %d", MinDelay)
+
 	// Policy tries is less than minimum required.
 	ErrTriesValidation = fmt.Errorf("tries must be >= %d", MinTries)
 
 	// Number of executions has reached the limit.
 	ErrMaxTriesExceeded = errors.New("max tries reached")
 
+	// Retry budget doesn't allow another try.
+	ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
 	// Unhandled error. It's not in Errors policy field.
 	ErrUnhandledError = errors.New("unhandled error")
 
+	// Result rejected by ShouldRetryResult policy field.
+	ErrResultRejected = errors.New("result rejected")
+
 	// No command nor wrapped policy is set.
 	ErrCommandRequired = errors.New("command nor wrapped policy provided")
 )
@@ -34,12 +47,40 @@ type Policy struct {
 	// Number of executions to be tried until fail.
 	Tries int
 
-	// Delay between each execution.
+	// Delay between each execution. Ignored if Backoff is set.
 	Delay time.Duration
 
+	// Strategy which computes the delay between each execution (constant Delay if not set).
+	Backoff Backoff
+
+	// Maximum delay between executions (zero means no limit).
+	MaxDelay time.Duration
+
+	// Maximum delay honoured when an error suggests how long to wait (see DelaySuggester).
+	// Zero means no limit.
+	MaxRetryAfter time.Duration
+
 	// Expected erros (not expected errors will abort execution).
 	Errors []error
 
+	// Function which decides whether a failed execution should be tried again
+	// (returning false aborts execution). Errors is ignored when it is set.
+	ShouldRetry func(try int, err error) bool
+
+	// Function which decides whether a successful execution should be tried again given its result.
+	// It only applies to result-returning executions (see resiliencia.ExecuteT and OnResult).
+	ShouldRetryResult func(try int, result any) bool
+
+	// Budget shared across calls which limits the number of retries per service (no limit if not set).
+	Budget *Budget
+
+	// Clock used to tell time and to wait between executions (real time if not set).
+	Clock core.Clock
+
+	// Whether the policy returns its bare errors (e.g. ErrMaxTriesExceeded), leaving the error of the
+	// command supplier or of the wrapped policy only in the metric, as former versions did.
+	SwallowErrors bool
+
 	// Function called before each execution.
 	BeforeTry func(p Policy, try int)
 
@@ -49,6 +90,9 @@ type Policy struct {
 	// The command supplier.
 	Command core.Command
 
+	// The context-aware command supplier. It takes precedence over Command.
+	CommandContext core.CommandContext
+
 	// Any policy that will be wrapped by this one.
 	Policy core.PolicySupplier
 }
@@ -73,23 +117,35 @@ type Metric struct {
 	// The error (if execution wasn't succeeded)
 	Error error
 
+	// Whether retries were stopped because the retry budget was exhausted.
+	BudgetExhausted bool
+
 	// Execution metrics.
-	Executions []struct {
-		// Iteration id. Starts from one (1).
-		Iteration int
+	Executions []Execution
+}
+
+// Execution keeps the running state of a single try.
+type Execution struct {
+	// Iteration id. Starts from one (1).
+	Iteration int
 
-		// When execution started.
-		StartedAt time.Time
+	// When execution started.
+	StartedAt time.Time
 
-		// When execution finished.
-		FinishedAt time.Time
+	// When execution finished.
+	FinishedAt time.Time
 
-		// Execution duration.
-		Duration time.Duration
+	// Execution duration.
+	Duration time.Duration
 
-		// The error (if execution wasn't succeeded)
-		Error error
-	}
+	// The error (if execution wasn't succeeded)
+	Error error
+
+	// How long the policy waited after this execution (zero if no other try was made).
+	Delay time.Duration
+
+	// Delay suggested by the error (see DelaySuggester).
+	RetryAfter time.Duration
 }
 
 const (
@@ -112,58 +168,84 @@ func New(serviceID string) Policy {
 // Run executes a command supplier or a wrapped policy in a retry.
 //
 // Possible error(s): ErrDelayValidation, ErrTriesValidation, ErrCommandRequired,
-// ErrUnhandledError, ErrMaxTriesExceeded.
+// ErrUnhandledError, ErrMaxTriesExceeded (see RunContext).
 func (p Policy) Run(metric core.Metric) error {
+	return p.RunContext(context.Background(), metric)
+}
+
+// RunContext executes a command supplier or a wrapped policy in a retry bound to a context.
+// No other try is made once the context is done, and the delay between tries is interrupted.
+//
+// Validation errors are returned as they are. Any other error is a *core.PolicyError wrapping one of
+// ErrUnhandledError, ErrMaxTriesExceeded, ErrRetryBudgetExhausted, context.Canceled or
+// context.DeadlineExceeded along with the error of the last try, unless SwallowErrors is set.
+//
+// Possible error(s): ErrDelayValidation, ErrTriesValidation, ErrCommandRequired,
+// ErrUnhandledError, ErrMaxTriesExceeded, ErrRetryBudgetExhausted, context.Canceled,
+// context.DeadlineExceeded.
+func (p Policy) RunContext(ctx context.Context, metric core.Metric) error {
 	if err := validate(p); err != nil {
 		return err
 	}
 
-	m := Metric{ID: p.ServiceID, StartedAt: time.Now(), Executions: make([]struct {
-		Iteration  int
-		StartedAt  time.Time
-		FinishedAt time.Time
-		Duration   time.Duration
-		Error      error
-	}, 0)}
+	clock := core.ClockOrDefault(p.Clock)
+	m := Metric{ID: p.ServiceID, StartedAt: clock.Now(), Executions: make([]Execution, 0)}
+	backoff := p.Backoff
+	if backoff == nil {
+		backoff = ConstantBackoff{Delay: p.Delay}
+	}
+
 	done := false
+	delay := time.Duration(0)
+	var tried core.Metric
+
+	if p.Budget != nil {
+		p.Budget.Request(p.ServiceID)
+	}
 
 	for i := 0; i < p.Tries; i++ {
 		turn := i + 1
 		m.Tries = turn
-		exec := struct {
-			Iteration  int
-			StartedAt  time.Time
-			FinishedAt time.Time
-			Duration   time.Duration
-			Error      error
-		}{}
-
-		exec.Iteration = turn
+		exec := Execution{Iteration: turn}
 
 		if p.BeforeTry != nil {
 			p.BeforeTry(p, turn)
 		}
 
-		exec.StartedAt = time.Now()
-		err := execute(p, metric)
-		err = pickError(err, metric)
+		exec.StartedAt = clock.Now()
+		var err error
+		tried, err = try(ctx, p, metric, tried)
+		if err == nil && rejectedResult(ctx, p, turn) {
+			err = ErrResultRejected
+		}
+		shouldRetry := err != nil && retryable(p, turn, err)
 
 		exec.Error = err
-		exec.FinishedAt = time.Now()
-		m.FinishedAt = time.Now()
+		exec.FinishedAt = clock.Now()
+		m.FinishedAt = exec.FinishedAt
 		exec.Duration = exec.FinishedAt.Sub(exec.StartedAt)
+
+		willRetry := shouldRetry && turn < p.Tries
+		exhausted := willRetry && p.Budget != nil && !p.Budget.Withdraw(p.ServiceID)
+		if willRetry && !exhausted {
+			delay = nextDelay(p, backoff, &exec, delay)
+		}
 		m.Executions = append(m.Executions, exec)
 
 		if p.AfterTry != nil {
 			p.AfterTry(p, turn, err)
 		}
 
-		if !handledError(p, err) {
+		if err != nil && ctx.Err() != nil {
+			return abort(p, m, metric, clock, ctx.Err(), err)
+		}
+
+		if err != nil && !shouldRetry {
 			m.Status = 1
 			m.Error = ErrUnhandledError
 			metric[reflect.TypeOf(m).String()] = m
 
-			return ErrUnhandledError
+			return failure(p, ErrUnhandledError, err)
 		}
 
 		if err == nil {
@@ -171,17 +253,26 @@ func (p Policy) Run(metric core.Metric) error {
 			break
 		}
 
-		time.Sleep(p.Delay)
+		if exhausted {
+			m.BudgetExhausted = true
+			return abort(p, m, metric, clock, ErrRetryBudgetExhausted, err)
+		}
+
+		if turn < p.Tries {
+			if serr := core.SleepContext(ctx, clock, delay); serr != nil {
+				return abort(p, m, metric, clock, serr, err)
+			}
+		}
 	}
 
-	m.FinishedAt = time.Now()
+	m.FinishedAt = clock.Now()
 
 	if !done {
 		m.Status = 1
 		m.Error = ErrMaxTriesExceeded
 		metric[reflect.TypeOf(m).String()] = m
 
-		return ErrMaxTriesExceeded
+		return failure(p, ErrMaxTriesExceeded, m.Executions[len(m.Executions)-1].Error)
 	}
 	metric[reflect.TypeOf(m).String()] = m
 
@@ -194,18 +285,73 @@ func (p Policy) WithCommand(command core.Command) core.PolicySupplier {
 	return p
 }
 
+// WithCommandContext encapsulates this policy in a new policy with given context-aware command supplier.
+func (p Policy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.CommandContext = command
+	return p
+}
+
 // WithPolicy encapsulates this policy in a new policy with wrapped policy.
 func (p Policy) WithPolicy(policy core.PolicySupplier) core.PolicySupplier {
 	p.Policy = policy
 	return p
 }
 
-func execute(p Policy, metric core.Metric) error {
-	if p.Command != nil && p.Policy == nil {
+func execute(ctx context.Context, p Policy, metric core.Metric) error {
+	if p.Policy == nil {
+		if p.CommandContext != nil {
+			return p.CommandContext(ctx)
+		}
+
 		return p.Command()
 	}
 
-	return p.Policy.Run(metric)
+	return core.RunPolicy(ctx, p.Policy, metric)
+}
+
+// try executes the command supplier or the wrapped policy with metrics of its own, since a metric of a
+// former try tells nothing about this one. They then replace the metrics of the former try in metric.
+func try(ctx context.Context, p Policy, metric, former core.Metric) (core.Metric, error) {
+	tried := core.NewMetric()
+	err := pickError(execute(ctx, p, tried), tried)
+
+	for k := range former {
+		delete(metric, k)
+	}
+	for k, v := range tried {
+		metric[k] = v
+	}
+
+	return tried, err
+}
+
+func nextDelay(p Policy, backoff Backoff, exec *Execution, previous time.Duration) time.Duration {
+	delay := backoff.Next(exec.Iteration, previous, p.MaxDelay)
+	if suggested, ok := suggestedDelay(exec.Error); ok {
+		exec.RetryAfter = suggested
+		delay = capped(suggested, p.MaxRetryAfter)
+	}
+	exec.Delay = delay
+
+	return delay
+}
+
+func abort(p Policy, m Metric, metric core.Metric, clock core.Clock, err, cause error) error {
+	m.FinishedAt = clock.Now()
+	m.Status = 1
+	m.Error = err
+	metric[reflect.TypeOf(m).String()] = m
+
+	return failure(p, err, cause)
+}
+
+// failure wraps the policy error and the error of the last try, unless errors are swallowed.
+func failure(p Policy, err, cause error) error {
+	if p.SwallowErrors {
+		return err
+	}
+
+	return &core.PolicyError{Policy: "retry", ServiceID: p.ServiceID, Err: err, Cause: cause}
 }
 
 // ServiceID returns the service id registered to the policy binded to this metric.
@@ -238,17 +384,58 @@ func (m Metric) MetricError() error {
 	return m.Error
 }
 
-func handledError(p Policy, err error) bool {
-	return core.ErrorInErrors(p.Errors, err)
+func retryable(p Policy, try int, err error) bool {
+	switch {
+	case errors.Is(err, ErrResultRejected):
+		return true
+	case p.ShouldRetry != nil:
+		return p.ShouldRetry(try, err)
+	default:
+		return core.ErrorInErrors(p.Errors, err)
+	}
+}
+
+// rejectedResult tells whether ShouldRetryResult rejects the result of the try. A rejected result is
+// cleared, so that it is never delivered, not even once tries run out.
+func rejectedResult(ctx context.Context, p Policy, try int) bool {
+	if p.ShouldRetryResult == nil {
+		return false
+	}
+
+	result := core.ResultFromContext(ctx)
+	if result == nil {
+		return false
+	}
+
+	value, ok := result.Get()
+	if !ok || !p.ShouldRetryResult(try, value) {
+		return false
+	}
+	result.Clear()
+
+	return true
+}
+
+// OnResult adapts a typed predicate to the ShouldRetryResult policy field.
+// Results which aren't of type T are never tried again.
+func OnResult[T any](predicate func(try int, result T) bool) func(try int, result any) bool {
+	return func(try int, result any) bool {
+		typed, ok := result.(T)
+		return ok && predicate(try, typed)
+	}
 }
 
 func validate(p Policy) error {
 	switch {
 	case p.Delay < MinDelay:
 		return ErrDelayValidation
+	case p.MaxDelay < MinDelay:
+		return ErrMaxDelayValidation
+	case p.MaxRetryAfter < MinDelay:
+		return ErrMaxRetryAfterValidation
 	case p.Tries < MinTries:
 		return ErrTriesValidation
-	case p.Command == nil && p.Policy == nil:
+	case p.Command == nil && p.CommandContext == nil && p.Policy == nil:
 		return ErrCommandRequired
 	default:
 		return nil
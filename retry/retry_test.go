@@ -1,6 +1,7 @@
 package retry_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -54,6 +55,25 @@ func (m Metric) MetricError() error {
 	return m.Error
 }
 
+var errFlaky = errors.New("flaky")
+
+// flakyPolicy fails once, recording its failure, and then succeeds without recording anything.
+type flakyPolicy struct{ runs int }
+
+func (p *flakyPolicy) Run(metric core.Metric) error {
+	p.runs++
+	if p.runs > 1 {
+		return nil
+	}
+
+	metric[reflect.TypeOf(Metric{}).String()] = Metric{ID: "flaky", Status: 1, Error: errFlaky}
+
+	return errFlaky
+}
+
+func (p *flakyPolicy) WithCommand(_ core.Command) core.PolicySupplier       { return p }
+func (p *flakyPolicy) WithPolicy(_ core.PolicySupplier) core.PolicySupplier { return p }
+
 func TestPolicyImplementsPolicySupplier(t *testing.T) {
 	p := retry.New("postForm")
 	i := reflect.TypeOf((*core.PolicySupplier)(nil)).Elem()
@@ -280,6 +300,296 @@ func TestRunCommand(t *testing.T) {
 	assert.Nil(t, m.MetricError())
 }
 
+func TestRunContextCommand(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "postForm")
+
+	p := retry.New("postForm")
+	p.Tries = 3
+	p.CommandContext = func(ctx context.Context) error {
+		assert.Equal(t, "postForm", ctx.Value(key{}))
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	i := metric[reflect.TypeOf(retry.Metric{}).String()]
+	m, _ := i.(retry.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 1, m.Tries)
+	assert.True(t, m.Success())
+}
+
+func TestRunContextCanceled(t *testing.T) {
+	errTest := errors.New("any")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := retry.New("postForm")
+	p.Tries = 3
+	p.Errors = []error{errTest}
+	p.CommandContext = func(ctx context.Context) error {
+		cancel()
+		return errTest
+	}
+
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	i := metric[reflect.TypeOf(retry.Metric{}).String()]
+	m, _ := i.(retry.Metric)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, m.Tries)
+	assert.Equal(t, 1, m.Status)
+	assert.ErrorIs(t, m.MetricError(), context.Canceled)
+	assert.ErrorIs(t, m.Executions[0].Error, errTest)
+}
+
+func TestRunContextDelayInterrupted(t *testing.T) {
+	errTest := errors.New("any")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+
+	p := retry.New("postForm")
+	p.Tries = 3
+	p.Delay = time.Hour
+	p.Errors = []error{errTest}
+	p.Command = func() error { return errTest }
+
+	started := time.Now()
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	i := metric[reflect.TypeOf(retry.Metric{}).String()]
+	m, _ := i.(retry.Metric)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(started), time.Second)
+	assert.Equal(t, 1, m.Tries)
+	assert.False(t, m.Success())
+}
+
+func TestRunClock(t *testing.T) {
+	errTest := errors.New("any")
+	now := time.Date(2023, 5, 10, 8, 0, 0, 0, time.UTC)
+	clock := core.NewFakeClock(now)
+
+	p := retry.New("postForm")
+	p.Tries = 3
+	p.Delay = time.Hour
+	p.Errors = []error{errTest}
+	p.Clock = clock
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	c := make(chan error, 1)
+	go func() { c <- p.Run(metric) }()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+
+	err := <-c
+	i := metric[reflect.TypeOf(retry.Metric{}).String()]
+	m, _ := i.(retry.Metric)
+
+	assert.ErrorIs(t, err, retry.ErrMaxTriesExceeded)
+	assert.Equal(t, 3, m.Tries)
+	assert.Equal(t, now, m.StartedAt)
+	assert.Equal(t, now.Add(time.Hour*2), m.FinishedAt)
+	assert.Equal(t, now.Add(time.Hour), m.Executions[1].StartedAt)
+	assert.Equal(t, time.Hour, m.Executions[0].Delay)
+	assert.Equal(t, time.Duration(0), m.Executions[2].Delay)
+}
+
+func TestRunClockDelayInterrupted(t *testing.T) {
+	errTest := errors.New("any")
+	clock := core.NewFakeClock(time.Now())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	p := retry.New("postForm")
+	p.Tries = 3
+	p.Delay = time.Hour
+	p.Errors = []error{errTest}
+	p.Clock = clock
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	c := make(chan error, 1)
+	go func() { c <- p.RunContext(ctx, metric) }()
+
+	clock.BlockUntil(1)
+	cancel()
+
+	assert.ErrorIs(t, <-c, context.Canceled)
+	assert.Equal(t, 0, clock.Timers())
+}
+
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string {
+	return fmt.Sprintf("status %d", e.code)
+}
+
+func TestRunCommandWrappedHandledErrors(t *testing.T) {
+	errTest := errors.New("service unavailable")
+	calls := 0
+
+	p := retry.New("postForm")
+	p.Tries = 3
+	p.Errors = []error{errTest, core.ErrorType[statusError]()}
+	p.Command = func() error {
+		calls++
+		switch calls {
+		case 1:
+			return fmt.Errorf("call: %w", errTest)
+		case 2:
+			return errors.Join(errors.New("any"), statusError{code: 503})
+		default:
+			return nil
+		}
+	}
+
+	err := p.Run(core.NewMetric())
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRunShouldRetry(t *testing.T) {
+	calls := 0
+	tries := make([]int, 0)
+
+	p := retry.New("postForm")
+	p.Tries = 5
+	p.ShouldRetry = func(try int, err error) bool {
+		tries = append(tries, try)
+
+		var se statusError
+		return errors.As(err, &se) && se.code == 503
+	}
+	p.Command = func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("call: %w", statusError{code: 503})
+		}
+
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, []int{1, 2}, tries)
+	assert.Equal(t, 3, m.Tries)
+	assert.True(t, m.Success())
+}
+
+func TestRunShouldRetryAbort(t *testing.T) {
+	errTest := errors.New("any")
+	calls := 0
+
+	p := retry.New("postForm")
+	p.Tries = 5
+	p.Errors = []error{errTest}
+	p.ShouldRetry = func(try int, err error) bool {
+		return false
+	}
+	p.Command = func() error {
+		calls++
+		return statusError{code: 400}
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+
+	assert.ErrorIs(t, err, retry.ErrUnhandledError)
+	assert.Equal(t, 1, calls)
+	assert.Equal(t, 1, m.Tries)
+	assert.Equal(t, time.Duration(0), m.Executions[0].Delay)
+}
+
+func TestRunShouldRetryResult(t *testing.T) {
+	ctx, result := core.NewResultContext(context.Background())
+	calls := 0
+
+	p := retry.New("postForm")
+	p.Tries = 5
+	p.ShouldRetryResult = retry.OnResult(func(try int, code int) bool {
+		return code == 503
+	})
+	p.CommandContext = func(ctx context.Context) error {
+		calls++
+		if calls < 3 {
+			core.SetResult(ctx, 503)
+		} else {
+			core.SetResult(ctx, 200)
+		}
+
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+	value, _ := result.Get()
+
+	assert.Nil(t, err)
+	assert.Equal(t, 200, value)
+	assert.Equal(t, 3, m.Tries)
+	assert.ErrorIs(t, m.Executions[0].Error, retry.ErrResultRejected)
+	assert.ErrorIs(t, m.Executions[1].Error, retry.ErrResultRejected)
+	assert.Nil(t, m.Executions[2].Error)
+}
+
+func TestRunShouldRetryResultMaxTriesExceeded(t *testing.T) {
+	ctx, _ := core.NewResultContext(context.Background())
+
+	p := retry.New("postForm")
+	p.Tries = 2
+	p.ShouldRetryResult = retry.OnResult(func(try int, code int) bool {
+		return code == 503
+	})
+	p.CommandContext = func(ctx context.Context) error {
+		core.SetResult(ctx, 503)
+		return nil
+	}
+
+	err := p.RunContext(ctx, core.NewMetric())
+
+	assert.ErrorIs(t, err, retry.ErrMaxTriesExceeded)
+}
+
+func TestRunShouldRetryResultNoResult(t *testing.T) {
+	p := retry.New("postForm")
+	p.Tries = 2
+	p.ShouldRetryResult = func(try int, result any) bool { return true }
+	p.Command = func() error { return nil }
+
+	err := p.Run(core.NewMetric())
+
+	assert.Nil(t, err)
+}
+
+func TestOnResult(t *testing.T) {
+	predicate := retry.OnResult(func(try int, result string) bool {
+		return result == "retry"
+	})
+
+	assert.True(t, predicate(1, "retry"))
+	assert.False(t, predicate(1, "done"))
+	assert.False(t, predicate(1, 10))
+	assert.False(t, predicate(1, nil))
+}
+
 func TestRunPolicy(t *testing.T) {
 	timesAfter, timesBefore := 0, 0
 
@@ -455,6 +765,62 @@ func TestRunPolicyMaxTriesExceeded(t *testing.T) {
 	assert.NotNil(t, retryMetric.MetricError())
 }
 
+func TestRunPolicyError(t *testing.T) {
+	errTest := errors.New("service unavailable")
+	p := retry.New("service-id")
+	p.Tries = 2
+	p.Errors = []error{errTest}
+	p.Command = func() error { return errTest }
+
+	err := p.Run(core.NewMetric())
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "retry", perr.Policy)
+	assert.Equal(t, "service-id", perr.ServiceID)
+	assert.ErrorIs(t, err, retry.ErrMaxTriesExceeded)
+	assert.ErrorIs(t, err, errTest)
+
+	p.Errors = nil
+	err = p.Run(core.NewMetric())
+	assert.ErrorIs(t, err, retry.ErrUnhandledError)
+	assert.ErrorIs(t, err, errTest)
+}
+
+func TestRunPolicyFormerTryMetric(t *testing.T) {
+	flaky := &flakyPolicy{}
+	p := retry.New("service-id")
+	p.Tries = 3
+	p.Errors = []error{errFlaky}
+	p.Policy = flaky
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+
+	// The failure recorded by the first try doesn't fail the second one, nor is it kept.
+	assert.Nil(t, err)
+	assert.Equal(t, 2, flaky.runs)
+	assert.Equal(t, 2, m.Tries)
+	assert.NotContains(t, metric, reflect.TypeOf(Metric{}).String())
+}
+
+func TestRunSwallowErrors(t *testing.T) {
+	errTest := errors.New("service unavailable")
+	p := retry.New("service-id")
+	p.Tries = 2
+	p.Errors = []error{errTest}
+	p.SwallowErrors = true
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(retry.Metric{}).String()].(retry.Metric)
+
+	assert.Equal(t, retry.ErrMaxTriesExceeded, err)
+	assert.Equal(t, errTest, m.Executions[1].Error)
+}
+
 func TestWithCommand(t *testing.T) {
 	p := retry.New("id")
 	assert.Nil(t, p.Command)
@@ -464,6 +830,15 @@ func TestWithCommand(t *testing.T) {
 	assert.NotNil(t, p.Command)
 }
 
+func TestWithCommandContext(t *testing.T) {
+	p := retry.New("id")
+	assert.Nil(t, p.CommandContext)
+
+	np := p.WithCommandContext(func(ctx context.Context) error { return nil })
+	p, _ = np.(retry.Policy)
+	assert.NotNil(t, p.CommandContext)
+}
+
 func TestWithPolicy(t *testing.T) {
 	p := retry.New("id")
 	assert.Nil(t, p.Policy)
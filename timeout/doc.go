@@ -63,6 +63,33 @@ There are two ways to run a command under a policy. Using a Command supplier
 	// Prints Timeout metric.
 	fmt.Println(tmMetric)
 
+# Context
+
+Every policy may also run bound to a context.Context. Use CommandContext instead of Command so that the
+command supplier receives the context, and RunContext instead of Run. A wrapped policy receives the same context.
+
+	p := timeout.New("service-id")
+	p.CommandContext = func(ctx context.Context) error {
+		// Your business logic.
+		...
+
+		// Returns error or nil otherwise.
+		return nil
+	}
+
+	err := p.RunContext(ctx, core.NewMetric())
+
+The context received by the command supplier (or by the wrapped policy) is canceled as soon as the timeout
+expires, so the work in progress can be abandoned. A command supplier that ignores the context keeps running
+until it returns, but its result is discarded and no goroutine is left behind.
+
+# Errors
+
+The policy returns a *core.PolicyError wrapping ErrExecutionTimedOut, the error of the context, or the error of
+the command supplier (or of the wrapped policy) when it fails in time. SwallowErrors makes the policy return
+ErrExecutionTimedOut and the error of the context as they are, and nil when the execution fails in time
+(see core.PolicyError).
+
 # Listener
 
 In order to keep tracking of what is happening on the execution, you may use listeners to generate some events.
@@ -1,6 +1,7 @@
 package timeout
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
@@ -28,6 +29,13 @@ type Policy struct {
 	// Time to wait until the run times out.
 	Timeout time.Duration
 
+	// Clock used to tell time and to time the execution out (real time if not set).
+	Clock core.Clock
+
+	// Whether the policy returns its bare errors (e.g. ErrExecutionTimedOut), leaving the error of the
+	// command supplier or of the wrapped policy only in the metric, as former versions did.
+	SwallowErrors bool
+
 	// Function called before execution.
 	BeforeTimeout func(p Policy)
 
@@ -37,6 +45,9 @@ type Policy struct {
 	// The command supplier.
 	Command core.Command
 
+	// The context-aware command supplier. It takes precedence over Command.
+	CommandContext core.CommandContext
+
 	// Any policy that will be wrapped by this one.
 	Policy core.PolicySupplier
 }
@@ -59,6 +70,12 @@ type Metric struct {
 	Error error
 }
 
+type execution struct {
+	err    error
+	metric core.Metric
+	result *core.Result
+}
+
 // Minimum expected to be set on Timeout field of a timeout policy.
 const MinTimeout = 0
 
@@ -72,51 +89,87 @@ func New(serviceID string) Policy {
 
 // Run executes a command supplier or a wrapped policy in a timeout.
 //
-// Possible error(s): ErrTimeoutValidation, ErrCommandRequired, ErrExecutionTimedOut.
+// Possible error(s): ErrTimeoutValidation, ErrCommandRequired, ErrExecutionTimedOut (see RunContext).
 func (p Policy) Run(metric core.Metric) error {
+	return p.RunContext(context.Background(), metric)
+}
+
+// RunContext executes a command supplier or a wrapped policy in a timeout bound to a context.
+// The command supplier or the wrapped policy receives a context derived from ctx, which is canceled
+// as soon as the timeout expires or ctx is done. Metrics of a wrapped policy and the result of a
+// result-returning command are only recorded when it finishes in time.
+//
+// Validation errors are returned as they are. Any other error is a *core.PolicyError wrapping
+// ErrExecutionTimedOut, the error of ctx or the error of the command supplier or of the wrapped
+// policy, unless SwallowErrors is set.
+//
+// Possible error(s): ErrTimeoutValidation, ErrCommandRequired, ErrExecutionTimedOut,
+// context.Canceled, context.DeadlineExceeded.
+func (p Policy) RunContext(ctx context.Context, metric core.Metric) error {
 	if err := validate(p); err != nil {
 		return err
 	}
 
-	m := Metric{ID: p.ServiceID, StartedAt: time.Now()}
+	clock := core.ClockOrDefault(p.Clock)
+	m := Metric{ID: p.ServiceID, StartedAt: clock.Now()}
 	if p.BeforeTimeout != nil {
 		p.BeforeTimeout(p)
 	}
 
-	cerr := make(chan error)
-	c := make(chan string)
-	go executeCommand(cerr, c, metric, p)
+	tctx, cancel := core.WithTimeout(ctx, clock, p.Timeout)
+	defer cancel()
 
-	var merror error
+	// Buffered, so that the goroutine never blocks on sending even if nobody is waiting anymore.
+	c := make(chan execution, 1)
+	go executeCommand(tctx, c, p)
 
-waiting:
-	for {
-		select {
-		case e := <-cerr:
-			if e != nil {
-				m.Error = e
-				m.Status = 1
-			}
-		case str := <-c:
-			if str == "done" {
-				break waiting
+	var (
+		merror error
+		cause  error
+		exec   execution
+	)
+
+	select {
+	case exec = <-c:
+	case <-tctx.Done():
+	}
+
+	// A result delivered after the deadline is as late as no result at all.
+	if tctx.Err() != nil {
+		cancel()
+
+		merror = ErrExecutionTimedOut
+		if ctx.Err() != nil {
+			merror = ctx.Err()
+		}
+
+		m.Error = merror
+		m.Status = 1
+	} else {
+		for k, v := range exec.metric {
+			metric[k] = v
+		}
+
+		if exec.result != nil {
+			if value, ok := exec.result.Get(); ok {
+				core.SetResult(ctx, value)
 			}
-		case <-time.After(p.Timeout):
-			merror = ErrExecutionTimedOut
-			m.Error = ErrExecutionTimedOut
-			m.Status = 1
+		}
 
-			break waiting
+		if exec.err != nil {
+			cause = exec.err
+			m.Error = exec.err
+			m.Status = 1
 		}
 	}
 
 	if p.AfterTimeout != nil {
 		p.AfterTimeout(p, m.Error)
 	}
-	m.FinishedAt = time.Now()
+	m.FinishedAt = clock.Now()
 	metric[reflect.TypeOf(m).String()] = m
 
-	return merror
+	return failure(p, merror, cause)
 }
 
 // WithCommand encapsulates this policy in a new policy with given command supplier.
@@ -125,29 +178,48 @@ func (p Policy) WithCommand(command core.Command) core.PolicySupplier {
 	return p
 }
 
+// WithCommandContext encapsulates this policy in a new policy with given context-aware command supplier.
+func (p Policy) WithCommandContext(command core.CommandContext) core.PolicySupplier {
+	p.CommandContext = command
+	return p
+}
+
 // WithPolicy encapsulates this policy in a new policy with wrapped policy.
 func (p Policy) WithPolicy(policy core.PolicySupplier) core.PolicySupplier {
 	p.Policy = policy
 	return p
 }
 
-func executeCommand(cerr chan error, c chan string, metric core.Metric, p Policy) {
-	c <- "start"
+// failure wraps the policy error and the error of the execution, unless errors are swallowed.
+func failure(p Policy, err, cause error) error {
+	switch {
+	case err == nil && cause == nil:
+		return nil
+	case p.SwallowErrors:
+		return err
+	default:
+		return &core.PolicyError{Policy: "timeout", ServiceID: p.ServiceID, Err: err, Cause: cause}
+	}
+}
 
-	var lerr error
-	if p.Command != nil && p.Policy == nil {
-		lerr = p.Command()
-	} else {
-		lerr = p.Policy.Run(metric)
+func executeCommand(ctx context.Context, c chan<- execution, p Policy) {
+	exec := execution{metric: core.NewMetric()}
+
+	// The result is isolated as well, so that a late execution can't override it.
+	if core.ResultFromContext(ctx) != nil {
+		ctx, exec.result = core.NewResultContext(ctx)
 	}
 
-	if lerr != nil {
-		cerr <- lerr
+	switch {
+	case p.Policy != nil:
+		exec.err = core.RunPolicy(ctx, p.Policy, exec.metric)
+	case p.CommandContext != nil:
+		exec.err = p.CommandContext(ctx)
+	default:
+		exec.err = p.Command()
 	}
-	close(cerr)
 
-	c <- "done"
-	close(c)
+	c <- exec
 }
 
 // ServiceID returns the service id registered to the policy binded to this metric.
@@ -176,7 +248,7 @@ func validate(p Policy) error {
 	switch {
 	case p.Timeout < MinTimeout:
 		return ErrTimeoutValidation
-	case p.Command == nil && p.Policy == nil:
+	case p.Command == nil && p.CommandContext == nil && p.Policy == nil:
 		return ErrCommandRequired
 	default:
 		return nil
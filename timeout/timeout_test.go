@@ -1,9 +1,11 @@
 package timeout_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
+	"runtime"
 	"testing"
 	"time"
 
@@ -139,7 +141,7 @@ func TestRunCommandWithUnknownError(t *testing.T) {
 	i := metric[reflect.TypeOf(timeout.Metric{}).String()]
 	m, _ := i.(timeout.Metric)
 
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest)
 
 	assert.Equal(t, "remote-service", m.ID)
 	assert.Equal(t, 1, m.Status)
@@ -165,7 +167,7 @@ func TestRunCommandTimeout(t *testing.T) {
 	i := metric[reflect.TypeOf(timeout.Metric{}).String()]
 	m, _ := i.(timeout.Metric)
 
-	assert.ErrorIs(t, timeout.ErrExecutionTimedOut, err)
+	assert.ErrorIs(t, err, timeout.ErrExecutionTimedOut)
 
 	assert.Equal(t, "remote-service", m.ID)
 	assert.Equal(t, 1, m.Status)
@@ -176,6 +178,143 @@ func TestRunCommandTimeout(t *testing.T) {
 	assert.False(t, m.Success())
 }
 
+func TestRunContextCommand(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "remote-service")
+
+	p := timeout.New("remote-service")
+	p.Timeout = time.Second * 4
+	p.CommandContext = func(ctx context.Context) error {
+		assert.Equal(t, "remote-service", ctx.Value(key{}))
+		return nil
+	}
+
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	i := metric[reflect.TypeOf(timeout.Metric{}).String()]
+	m, _ := i.(timeout.Metric)
+
+	assert.Nil(t, err)
+	assert.True(t, m.Success())
+}
+
+func TestRunContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	p := timeout.New("remote-service")
+	p.Timeout = time.Second * 4
+	p.CommandContext = func(ctx context.Context) error {
+		cancel()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	metric := core.NewMetric()
+	err := p.RunContext(ctx, metric)
+	i := metric[reflect.TypeOf(timeout.Metric{}).String()]
+	m, _ := i.(timeout.Metric)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 1, m.Status)
+	assert.ErrorIs(t, m.MetricError(), context.Canceled)
+	assert.False(t, m.Success())
+}
+
+func TestRunCommandContextCanceledOnTimeout(t *testing.T) {
+	cerr := make(chan error, 1)
+	p := timeout.New("remote-service")
+	p.Timeout = time.Millisecond * 20
+	p.CommandContext = func(ctx context.Context) error {
+		<-ctx.Done()
+		cerr <- ctx.Err()
+		return ctx.Err()
+	}
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+
+	assert.ErrorIs(t, err, timeout.ErrExecutionTimedOut)
+	assert.ErrorIs(t, <-cerr, context.DeadlineExceeded)
+}
+
+func TestRunCommandClock(t *testing.T) {
+	clock := core.NewFakeClock(time.Now())
+	cerr := make(chan error, 1)
+	p := timeout.New("remote-service")
+	p.Timeout = time.Hour
+	p.Clock = clock
+	p.CommandContext = func(ctx context.Context) error {
+		<-ctx.Done()
+		cerr <- context.Cause(ctx)
+		return ctx.Err()
+	}
+
+	metric := core.NewMetric()
+	c := make(chan error, 1)
+	go func() { c <- p.Run(metric) }()
+
+	clock.BlockUntil(1)
+	clock.Advance(time.Hour)
+
+	assert.ErrorIs(t, <-c, timeout.ErrExecutionTimedOut)
+	assert.ErrorIs(t, <-cerr, context.DeadlineExceeded)
+}
+
+func TestRunPolicyContextCanceledOnTimeout(t *testing.T) {
+	cerr := make(chan error, 1)
+	inner := timeout.New("inner-service")
+	inner.Timeout = time.Hour
+	inner.CommandContext = func(ctx context.Context) error {
+		<-ctx.Done()
+		cerr <- ctx.Err()
+		return ctx.Err()
+	}
+
+	p := timeout.New("remote-service")
+	p.Timeout = time.Millisecond * 20
+	p.Policy = inner
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+
+	assert.ErrorIs(t, err, timeout.ErrExecutionTimedOut)
+	assert.ErrorIs(t, <-cerr, context.DeadlineExceeded)
+}
+
+func TestRunCommandNoGoroutineLeak(t *testing.T) {
+	const total = 50
+	baseline := runtime.NumGoroutine()
+
+	p := timeout.New("remote-service")
+	p.Timeout = time.Millisecond * 5
+
+	for i := 0; i < total; i++ {
+		if i%2 == 0 {
+			p.CommandContext = func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+		} else {
+			// Ignores the context, but finishes later anyway.
+			p.CommandContext = func(ctx context.Context) error {
+				time.Sleep(time.Millisecond * 20)
+				return nil
+			}
+		}
+
+		err := p.Run(core.NewMetric())
+		assert.ErrorIs(t, err, timeout.ErrExecutionTimedOut)
+	}
+
+	// assert.Eventually can't be used here, since it starts goroutines by itself.
+	deadline := time.Now().Add(time.Second * 2)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond * 10)
+	}
+
+	assert.LessOrEqual(t, runtime.NumGoroutine(), baseline)
+}
+
 func TestRunPolicySuccess(t *testing.T) {
 	policy := new(mockPolicy)
 	policy.On("Run").Return(nil)
@@ -237,7 +376,7 @@ func TestRunPolicyChildFail(t *testing.T) {
 	metric[reflect.TypeOf(mockMetric).String()] = mockMetric
 
 	err := timeoutPolicy.Run(metric)
-	assert.Nil(t, err)
+	assert.ErrorIs(t, err, errTest)
 
 	r := metric[reflect.TypeOf(Metric{}).String()]
 	childMetric, _ := r.(Metric)
@@ -288,6 +427,50 @@ func TestRunPolicyTimeout(t *testing.T) {
 	assert.False(t, timeoutMetric.Success())
 }
 
+func TestRunPolicyError(t *testing.T) {
+	errTest := errors.New("service unavailable")
+	p := timeout.New("service-id")
+	p.Timeout = time.Second
+	p.Command = func() error { return errTest }
+
+	err := p.Run(core.NewMetric())
+
+	var perr *core.PolicyError
+	assert.ErrorAs(t, err, &perr)
+	assert.Equal(t, "timeout", perr.Policy)
+	assert.Equal(t, "service-id", perr.ServiceID)
+	assert.ErrorIs(t, err, errTest)
+}
+
+func TestRunSwallowErrors(t *testing.T) {
+	errTest := errors.New("service unavailable")
+	p := timeout.New("service-id")
+	p.Timeout = time.Second
+	p.SwallowErrors = true
+	p.Command = func() error { return errTest }
+
+	metric := core.NewMetric()
+	err := p.Run(metric)
+	m, _ := metric[reflect.TypeOf(timeout.Metric{}).String()].(timeout.Metric)
+
+	assert.Nil(t, err)
+	assert.Equal(t, errTest, m.Error)
+
+	clock := core.NewFakeClock(time.Now())
+	p.Clock = clock
+	p.CommandContext = func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	go func() {
+		clock.BlockUntil(1)
+		clock.Advance(time.Second)
+	}()
+
+	assert.Equal(t, timeout.ErrExecutionTimedOut, p.Run(core.NewMetric()))
+}
+
 func TestWithCommand(t *testing.T) {
 	p := timeout.New("id")
 	assert.Nil(t, p.Command)
@@ -297,6 +480,15 @@ func TestWithCommand(t *testing.T) {
 	assert.NotNil(t, p.Command)
 }
 
+func TestWithCommandContext(t *testing.T) {
+	p := timeout.New("id")
+	assert.Nil(t, p.CommandContext)
+
+	np := p.WithCommandContext(func(ctx context.Context) error { return nil })
+	p, _ = np.(timeout.Policy)
+	assert.NotNil(t, p.CommandContext)
+}
+
 func TestWithPolicy(t *testing.T) {
 	p := timeout.New("id")
 	assert.Nil(t, p.Policy)